@@ -0,0 +1,383 @@
+package main
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/gin-gonic/gin"
+	handlers "github.com/tanush-128/openzo_backend/product/internal/api"
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"gorm.io/gorm"
+)
+
+// app is the product service wired over one database: its HTTP routes, and
+// what the gRPC server and the background jobs run on.
+type app struct {
+	router            *gin.Engine
+	productRepository repository.ProductRepository
+	priceResolver     service.PriceResolver
+	taxCalculator     service.TaxCalculator
+	imageService      service.ImageService
+	storeRepository   repository.StoreRepository
+	geoRepository     repository.GeoRepository
+}
+
+// newApp wires the repositories, services and handlers of the product
+// service over db and registers its HTTP routes.
+func newApp(db *gorm.DB, imageClient pb.ImageServiceClient, producer *kafka.Producer) *app {
+	productRepository := repository.NewProductRepository(db)
+	pricingRepository := repository.NewPricingRepository(db)
+	categoryRepository := repository.NewCategoryRepository(db)
+	attributeRepository := repository.NewAttributeRepository(db)
+	catalogRepository := repository.NewCatalogRepository(db)
+	imageRepository := repository.NewImageRepository(db)
+	imageClient = service.NewTrackingImageClient(imageClient, imageRepository)
+	availabilityRepository := repository.NewAvailabilityRepository(db)
+	priceResolver := service.NewPriceResolver(pricingRepository)
+	availabilityResolver := service.NewAvailabilityResolver(availabilityRepository, productRepository, categoryRepository)
+	productService := service.NewProductService(productRepository, pricingRepository, categoryRepository, attributeRepository, catalogRepository, imageRepository, priceResolver, availabilityResolver, imageClient, producer)
+
+	taxRepository := repository.NewTaxRepository(db)
+	taxCalculator := service.NewTaxCalculator(taxRepository, productRepository, priceResolver)
+
+	// Initialize Inventory Repository and Service
+	inventoryTransactionRepository := repository.NewInventoryTransactionRepository(db)
+	inventoryService := service.NewInventoryService(inventoryTransactionRepository, productRepository)
+	inventoryHandler := handlers.NewInventoryHandler(&inventoryService)
+
+	replenishmentRepository := repository.NewReplenishmentRepository(db)
+	replenishmentService := service.NewReplenishmentService(replenishmentRepository, productRepository)
+	replenishmentHandler := handlers.NewReplenishmentHandler(&replenishmentService)
+
+	supplierRepository := repository.NewSupplierRepository(db)
+	supplierService := service.NewSupplierService(supplierRepository)
+	supplierHandler := handlers.NewSupplierHandler(&supplierService)
+
+	purchaseOrderRepository := repository.NewPurchaseOrderRepository(db)
+	purchaseOrderService := service.NewPurchaseOrderService(purchaseOrderRepository, supplierRepository, productRepository, replenishmentService)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(&purchaseOrderService)
+
+	priceRuleService := service.NewPriceRuleService(pricingRepository, productRepository, priceResolver)
+	priceRuleHandler := handlers.NewPriceRuleHandler(&priceRuleService)
+
+	taxService := service.NewTaxService(taxRepository, taxCalculator)
+	taxHandler := handlers.NewTaxHandler(&taxService)
+
+	categoryService := service.NewCategoryService(categoryRepository, productRepository, priceResolver, availabilityResolver)
+	categoryHandler := handlers.NewCategoryHandler(&categoryService)
+
+	attributeService := service.NewAttributeService(attributeRepository, categoryRepository)
+	attributeHandler := handlers.NewAttributeHandler(&attributeService)
+
+	modifierRepository := repository.NewModifierRepository(db)
+	modifierService := service.NewModifierService(modifierRepository, productRepository)
+	modifierHandler := handlers.NewModifierHandler(&modifierService)
+
+	menuService := service.NewMenuService(productRepository, priceResolver, availabilityResolver)
+	menuHandler := handlers.NewMenuHandler(&menuService)
+
+	availabilityService := service.NewAvailabilityService(availabilityRepository, productRepository, categoryRepository)
+	availabilityHandler := handlers.NewAvailabilityHandler(&availabilityService)
+
+	catalogService := service.NewCatalogService(catalogRepository, categoryRepository, attributeRepository)
+	catalogHandler := handlers.NewCatalogHandler(&catalogService)
+
+	offerService := service.NewOfferService(productRepository, catalogRepository, priceResolver, availabilityResolver)
+	offerHandler := handlers.NewOfferHandler(&offerService)
+
+	imageService := service.NewImageService(imageRepository, productRepository, imageClient, service.NewImageFetcher(nil), producer)
+	imageHandler := handlers.NewImageHandler(&imageService)
+
+	geoRepository := repository.NewGeoRepository(db)
+	geoService := service.NewGeoService(geoRepository, productRepository, priceResolver, availabilityResolver)
+	geoHandler := handlers.NewGeoHandler(&geoService)
+
+	// product reads carry the catalog version of their store as ETag
+	catalogVersionRepository := repository.NewCatalogVersionRepository(db)
+	catalogVersionService := service.NewCatalogVersionService(catalogVersionRepository, pricingRepository, availabilityRepository)
+
+	// listings read store details from a local replica fed by the store service
+	storeRepository := repository.NewStoreRepository(db)
+
+	// Initialize HTTP server with Gin
+	router := gin.Default()
+	// unversioned routes keep answering prices as numbers
+	router.Use(handlers.LegacyMoney())
+	handler := handlers.NewHandler(&productService)
+
+	router.GET("ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "pong",
+		})
+	})
+
+	// router.Use(middlewares.JwtMiddleware(c))
+	router.POST("/", handler.CreateProduct)
+	router.GET("/store/:id", handlers.StoreCatalogConditional(catalogVersionService, "id"), handler.GetProductsByStoreID)
+	router.GET("post/pincode/:pincode", handler.GetPostByPincode)
+	router.GET("feed/pincode/:pincode", handler.GetFeed)
+	router.GET("/units", handler.GetUnits)
+	router.GET("/:id", handlers.ProductCatalogConditional(catalogVersionService, "id"), handler.GetProductByID)
+	// router.Use(middlewares.NewMiddleware(c).JwtMiddleware)
+	router.PUT("/:id", handler.ChangeProductQuantity)
+	router.PUT("/", handler.UpdateProduct)
+	router.PUT("/display_order/:id", handler.UpdateDisplayOrder)
+	router.PUT("/display_order/batch", handler.BatchUpdateDisplayOrder)
+	router.DELETE("/:id", handler.DeleteProduct)
+
+	// Inventory routes
+	router.POST("/inventory", inventoryHandler.CreateInventoryTransaction)
+	router.GET("/inventory/:id", inventoryHandler.GetInventoryTransactionByID)
+	router.PUT("/inventory/:id", inventoryHandler.UpdateInventoryTransaction)
+	router.DELETE("/inventory/:id", inventoryHandler.DeleteInventoryTransaction)
+	router.GET("/inventory/product/:product_id", inventoryHandler.GetAllTransactionsByProductID)
+
+	// Replenishment routes
+	router.PUT("/replenishment/settings", replenishmentHandler.UpsertReplenishmentSetting)
+	router.GET("/replenishment/settings/store/:store_id", replenishmentHandler.GetReplenishmentSettingsByStoreID)
+	router.GET("/replenishment/store/:store_id", replenishmentHandler.GetReorderSuggestions)
+	router.GET("/replenishment/store/:store_id/draft_po", replenishmentHandler.GetDraftPurchaseOrder)
+
+	// Supplier routes
+	router.POST("/suppliers", supplierHandler.CreateSupplier)
+	router.GET("/suppliers/:id", supplierHandler.GetSupplierByID)
+	router.GET("/suppliers/store/:store_id", supplierHandler.GetSuppliersByStoreID)
+	router.PUT("/suppliers/:id", supplierHandler.UpdateSupplier)
+	router.DELETE("/suppliers/:id", supplierHandler.DeleteSupplier)
+
+	// Purchase order routes
+	router.POST("/purchase_orders", purchaseOrderHandler.CreatePurchaseOrder)
+	router.POST("/purchase_orders/replenishment", purchaseOrderHandler.CreatePurchaseOrderFromReplenishment)
+	router.GET("/purchase_orders/:id", purchaseOrderHandler.GetPurchaseOrderByID)
+	router.GET("/purchase_orders/store/:store_id", purchaseOrderHandler.GetPurchaseOrdersByStoreID)
+	router.PUT("/purchase_orders/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+	router.PUT("/purchase_orders/:id/status", purchaseOrderHandler.UpdatePurchaseOrderStatus)
+	router.POST("/purchase_orders/:id/receive", purchaseOrderHandler.ReceiveGoods)
+	router.DELETE("/purchase_orders/:id", purchaseOrderHandler.DeletePurchaseOrder)
+
+	// Pricing routes
+	router.POST("/price_rules", priceRuleHandler.CreatePriceRule)
+	router.GET("/price_rules/:id", priceRuleHandler.GetPriceRuleByID)
+	router.GET("/price_rules/store/:store_id", priceRuleHandler.GetPriceRulesByStoreID)
+	router.PUT("/price_rules/:id", priceRuleHandler.UpdatePriceRule)
+	router.DELETE("/price_rules/:id", priceRuleHandler.DeletePriceRule)
+	router.GET("/price/:product_id", priceRuleHandler.GetProductPrice)
+	router.GET("/price_history/:product_id", priceRuleHandler.GetPriceHistoryByProductID)
+
+	// Tax routes
+	router.GET("/tax/:product_id", taxHandler.GetProductTax)
+	router.PUT("/tax/category_defaults", taxHandler.UpsertCategoryTaxDefault)
+	router.GET("/tax/category_defaults/store/:store_id", taxHandler.GetCategoryTaxDefaults)
+	router.DELETE("/tax/category_defaults/:id", taxHandler.DeleteCategoryTaxDefault)
+	router.PUT("/tax/store/:store_id", taxHandler.UpsertStoreTaxProfile)
+	router.GET("/tax/store/:store_id", taxHandler.GetStoreTaxProfile)
+
+	// Category routes
+	router.POST("/categories", categoryHandler.CreateCategory)
+	router.GET("/categories/:id", categoryHandler.GetCategoryByID)
+	router.GET("/categories/store/:store_id", categoryHandler.GetCategoryTree)
+	router.PUT("/categories/:id", categoryHandler.UpdateCategory)
+	router.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+	router.GET("/store/:id/category/:category_id", handlers.StoreCatalogConditional(catalogVersionService, "id"), categoryHandler.GetProductsByCategory)
+
+	// Attribute routes
+	router.POST("/categories/:id/attributes", attributeHandler.CreateAttributeDefinition)
+	router.GET("/categories/:id/attributes", attributeHandler.GetAttributeDefinitions)
+	router.PUT("/attributes/:id", attributeHandler.UpdateAttributeDefinition)
+	router.DELETE("/attributes/:id", attributeHandler.DeleteAttributeDefinition)
+
+	// Modifier routes
+	router.POST("/modifier_groups", modifierHandler.CreateModifierGroup)
+	router.GET("/modifier_groups/:id", modifierHandler.GetModifierGroupByID)
+	router.GET("/modifier_groups/store/:store_id", modifierHandler.GetModifierGroupsByStoreID)
+	router.PUT("/modifier_groups/:id", modifierHandler.UpdateModifierGroup)
+	router.DELETE("/modifier_groups/:id", modifierHandler.DeleteModifierGroup)
+	router.PUT("/modifier_groups/product/:product_id", modifierHandler.SetProductModifierGroups)
+
+	// Menu routes
+	router.GET("/menu/store/:store_id", handlers.StoreCatalogConditional(catalogVersionService, "store_id"), menuHandler.GetMenu)
+
+	// Availability routes
+	router.POST("/availability/schedules", availabilityHandler.CreateSchedule)
+	router.GET("/availability/schedules/:id", availabilityHandler.GetScheduleByID)
+	router.GET("/availability/schedules/store/:store_id", availabilityHandler.GetSchedulesByStoreID)
+	router.PUT("/availability/schedules/:id", availabilityHandler.UpdateSchedule)
+	router.DELETE("/availability/schedules/:id", availabilityHandler.DeleteSchedule)
+	router.POST("/availability/exceptions", availabilityHandler.CreateException)
+	router.GET("/availability/exceptions/store/:store_id", availabilityHandler.GetExceptionsByStoreID)
+	router.DELETE("/availability/exceptions/:id", availabilityHandler.DeleteException)
+	router.PUT("/availability/timezone/:store_id", availabilityHandler.SetStoreTimezone)
+	router.GET("/availability/timezone/:store_id", availabilityHandler.GetStoreTimezone)
+
+	// Catalog routes
+	router.POST("/catalog", catalogHandler.CreateCatalogProduct)
+	router.GET("/catalog/search", catalogHandler.SearchCatalog)
+	router.GET("/catalog/barcode/:barcode", catalogHandler.GetCatalogProductByBarcode)
+	router.GET("/catalog/:id", catalogHandler.GetCatalogProductByID)
+	router.PUT("/catalog/:id", catalogHandler.UpdateCatalogProduct)
+	router.DELETE("/catalog/:id", catalogHandler.DeleteCatalogProduct)
+
+	// Price comparison routes
+	router.GET("/offers/barcode/:barcode", offerHandler.GetOffersByBarcode)
+	router.GET("/offers/master/:id", offerHandler.GetOffersByMasterProduct)
+
+	// Image routes
+	router.POST("/images/product/:product_id", imageHandler.AddImages)
+	router.GET("/images/product/:product_id", imageHandler.GetProductImages)
+	router.POST("/images/product/:product_id/import", imageHandler.ImportImages)
+	router.PUT("/images/product/:product_id/order", imageHandler.ReorderImages)
+	router.PUT("/images/product/:product_id/primary/:image_id", imageHandler.SetPrimaryImage)
+	router.POST("/images/product/:product_id/retry", imageHandler.RetryProductImages)
+	router.PUT("/images/:id", imageHandler.UpdateImage)
+	router.DELETE("/images/:id", imageHandler.DeleteImage)
+
+	// Geo discovery routes
+	router.GET("/geo/products", geoHandler.GetNearbyProducts)
+	router.GET("/geo/posts", geoHandler.GetNearbyPosts)
+	router.PUT("/geo/stores/:store_id/location", geoHandler.SetStoreLocation)
+	router.GET("/geo/stores/:store_id/location", geoHandler.GetStoreLocation)
+	router.DELETE("/geo/stores/:store_id/location", geoHandler.DeleteStoreLocation)
+
+	// v1 routes answer errors with handlers.ErrorResponse and the status code
+	// of their kind
+	v1 := router.Group("/v1", handlers.V1())
+
+	// v1 product routes
+	v1.POST("/stores/:store_id/products", handler.CreateProduct)
+	v1.GET("/stores/:store_id/products", handlers.StoreCatalogConditional(catalogVersionService, "store_id"), handler.ListStoreProducts)
+	v1.PUT("/stores/:store_id/products/display_order", handler.BatchUpdateDisplayOrder)
+	v1.GET("/stores/:store_id/categories/:category_id/products", handlers.AliasParam("store_id", "id"), handlers.StoreCatalogConditional(catalogVersionService, "store_id"), categoryHandler.GetProductsByCategory)
+	v1.GET("/products/:id", handlers.ProductCatalogConditional(catalogVersionService, "id"), handler.GetProductByID)
+	v1.PUT("/products/:id", handler.UpdateProduct)
+	v1.DELETE("/products/:id", handler.DeleteProduct)
+	v1.PUT("/products/:id/quantity", handler.ChangeProductQuantity)
+	v1.PUT("/products/:id/display_order", handler.UpdateDisplayOrder)
+	v1.GET("/units", handler.GetUnits)
+	v1.GET("/pincodes/:pincode/posts", handler.GetPostByPincode)
+	v1.GET("/pincodes/:pincode/feed", handler.GetFeed)
+
+	// v1 inventory routes
+	v1.POST("/inventory/transactions", inventoryHandler.CreateInventoryTransaction)
+	v1.GET("/inventory/transactions/:id", inventoryHandler.GetInventoryTransactionByID)
+	v1.PUT("/inventory/transactions/:id", inventoryHandler.UpdateInventoryTransaction)
+	v1.DELETE("/inventory/transactions/:id", inventoryHandler.DeleteInventoryTransaction)
+	v1.GET("/inventory/products/:product_id/transactions", inventoryHandler.GetAllTransactionsByProductID)
+
+	// v1 replenishment routes
+	v1.PUT("/replenishment/settings", replenishmentHandler.UpsertReplenishmentSetting)
+	v1.GET("/stores/:store_id/replenishment/settings", replenishmentHandler.GetReplenishmentSettingsByStoreID)
+	v1.GET("/stores/:store_id/replenishment/suggestions", replenishmentHandler.GetReorderSuggestions)
+	v1.GET("/stores/:store_id/replenishment/draft_po", replenishmentHandler.GetDraftPurchaseOrder)
+
+	// v1 supplier routes
+	v1.POST("/suppliers", supplierHandler.CreateSupplier)
+	v1.GET("/suppliers/:id", supplierHandler.GetSupplierByID)
+	v1.PUT("/suppliers/:id", supplierHandler.UpdateSupplier)
+	v1.DELETE("/suppliers/:id", supplierHandler.DeleteSupplier)
+	v1.GET("/stores/:store_id/suppliers", supplierHandler.GetSuppliersByStoreID)
+
+	// v1 purchase order routes
+	v1.POST("/purchase_orders", purchaseOrderHandler.CreatePurchaseOrder)
+	v1.POST("/purchase_orders/replenishment", purchaseOrderHandler.CreatePurchaseOrderFromReplenishment)
+	v1.GET("/purchase_orders/:id", purchaseOrderHandler.GetPurchaseOrderByID)
+	v1.PUT("/purchase_orders/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+	v1.PUT("/purchase_orders/:id/status", purchaseOrderHandler.UpdatePurchaseOrderStatus)
+	v1.POST("/purchase_orders/:id/receive", purchaseOrderHandler.ReceiveGoods)
+	v1.DELETE("/purchase_orders/:id", purchaseOrderHandler.DeletePurchaseOrder)
+	v1.GET("/stores/:store_id/purchase_orders", purchaseOrderHandler.GetPurchaseOrdersByStoreID)
+
+	// v1 pricing routes
+	v1.POST("/price_rules", priceRuleHandler.CreatePriceRule)
+	v1.GET("/price_rules/:id", priceRuleHandler.GetPriceRuleByID)
+	v1.PUT("/price_rules/:id", priceRuleHandler.UpdatePriceRule)
+	v1.DELETE("/price_rules/:id", priceRuleHandler.DeletePriceRule)
+	v1.GET("/stores/:store_id/price_rules", priceRuleHandler.GetPriceRulesByStoreID)
+	v1.GET("/products/:id/price", handlers.AliasParam("id", "product_id"), priceRuleHandler.GetProductPrice)
+	v1.GET("/products/:id/price_history", handlers.AliasParam("id", "product_id"), priceRuleHandler.GetPriceHistoryByProductID)
+
+	// v1 tax routes
+	v1.GET("/products/:id/tax", handlers.AliasParam("id", "product_id"), taxHandler.GetProductTax)
+	v1.PUT("/tax/category_defaults", taxHandler.UpsertCategoryTaxDefault)
+	v1.DELETE("/tax/category_defaults/:id", taxHandler.DeleteCategoryTaxDefault)
+	v1.GET("/stores/:store_id/tax/category_defaults", taxHandler.GetCategoryTaxDefaults)
+	v1.PUT("/stores/:store_id/tax/profile", taxHandler.UpsertStoreTaxProfile)
+	v1.GET("/stores/:store_id/tax/profile", taxHandler.GetStoreTaxProfile)
+
+	// v1 category routes
+	v1.POST("/categories", categoryHandler.CreateCategory)
+	v1.GET("/categories/:id", categoryHandler.GetCategoryByID)
+	v1.PUT("/categories/:id", categoryHandler.UpdateCategory)
+	v1.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+	v1.GET("/stores/:store_id/categories", categoryHandler.GetCategoryTree)
+	v1.POST("/categories/:id/attributes", attributeHandler.CreateAttributeDefinition)
+	v1.GET("/categories/:id/attributes", attributeHandler.GetAttributeDefinitions)
+	v1.PUT("/attributes/:id", attributeHandler.UpdateAttributeDefinition)
+	v1.DELETE("/attributes/:id", attributeHandler.DeleteAttributeDefinition)
+
+	// v1 modifier routes
+	v1.POST("/modifier_groups", modifierHandler.CreateModifierGroup)
+	v1.GET("/modifier_groups/:id", modifierHandler.GetModifierGroupByID)
+	v1.PUT("/modifier_groups/:id", modifierHandler.UpdateModifierGroup)
+	v1.DELETE("/modifier_groups/:id", modifierHandler.DeleteModifierGroup)
+	v1.GET("/stores/:store_id/modifier_groups", modifierHandler.GetModifierGroupsByStoreID)
+	v1.PUT("/products/:id/modifier_groups", handlers.AliasParam("id", "product_id"), modifierHandler.SetProductModifierGroups)
+
+	// v1 menu routes
+	v1.GET("/stores/:store_id/menu", handlers.StoreCatalogConditional(catalogVersionService, "store_id"), menuHandler.GetMenu)
+
+	// v1 availability routes
+	v1.POST("/availability/schedules", availabilityHandler.CreateSchedule)
+	v1.GET("/availability/schedules/:id", availabilityHandler.GetScheduleByID)
+	v1.PUT("/availability/schedules/:id", availabilityHandler.UpdateSchedule)
+	v1.DELETE("/availability/schedules/:id", availabilityHandler.DeleteSchedule)
+	v1.POST("/availability/exceptions", availabilityHandler.CreateException)
+	v1.DELETE("/availability/exceptions/:id", availabilityHandler.DeleteException)
+	v1.GET("/stores/:store_id/availability/schedules", availabilityHandler.GetSchedulesByStoreID)
+	v1.GET("/stores/:store_id/availability/exceptions", availabilityHandler.GetExceptionsByStoreID)
+	v1.PUT("/stores/:store_id/timezone", availabilityHandler.SetStoreTimezone)
+	v1.GET("/stores/:store_id/timezone", availabilityHandler.GetStoreTimezone)
+
+	// v1 catalog routes
+	v1.POST("/catalog", catalogHandler.CreateCatalogProduct)
+	v1.GET("/catalog/search", catalogHandler.SearchCatalog)
+	v1.GET("/catalog/barcode/:barcode", catalogHandler.GetCatalogProductByBarcode)
+	v1.GET("/catalog/:id", catalogHandler.GetCatalogProductByID)
+	v1.PUT("/catalog/:id", catalogHandler.UpdateCatalogProduct)
+	v1.DELETE("/catalog/:id", catalogHandler.DeleteCatalogProduct)
+
+	// v1 price comparison routes
+	v1.GET("/offers/barcode/:barcode", offerHandler.GetOffersByBarcode)
+	v1.GET("/offers/master/:id", offerHandler.GetOffersByMasterProduct)
+
+	// v1 image routes
+	v1.POST("/products/:id/images", handlers.AliasParam("id", "product_id"), imageHandler.AddImages)
+	v1.GET("/products/:id/images", handlers.AliasParam("id", "product_id"), imageHandler.GetProductImages)
+	v1.POST("/products/:id/images/import", handlers.AliasParam("id", "product_id"), imageHandler.ImportImages)
+	v1.PUT("/products/:id/images/order", handlers.AliasParam("id", "product_id"), imageHandler.ReorderImages)
+	v1.PUT("/products/:id/images/primary/:image_id", handlers.AliasParam("id", "product_id"), imageHandler.SetPrimaryImage)
+	v1.POST("/products/:id/images/retry", handlers.AliasParam("id", "product_id"), imageHandler.RetryProductImages)
+	v1.PUT("/images/:id", imageHandler.UpdateImage)
+	v1.DELETE("/images/:id", imageHandler.DeleteImage)
+
+	// v1 geo discovery routes
+	v1.GET("/geo/products", geoHandler.GetNearbyProducts)
+	v1.GET("/geo/posts", geoHandler.GetNearbyPosts)
+	v1.PUT("/stores/:store_id/location", geoHandler.SetStoreLocation)
+	v1.GET("/stores/:store_id/location", geoHandler.GetStoreLocation)
+	v1.DELETE("/stores/:store_id/location", geoHandler.DeleteStoreLocation)
+
+	// API documentation, generated from the routes above
+	router.GET("/openapi.json", handlers.OpenAPI(router))
+	router.GET("/docs", handlers.APIDocs)
+	router.GET("/docs/:file", handlers.APIDocsAsset)
+
+	return &app{
+		router:            router,
+		productRepository: productRepository,
+		priceResolver:     priceResolver,
+		taxCalculator:     taxCalculator,
+		imageService:      imageService,
+		storeRepository:   storeRepository,
+		geoRepository:     geoRepository,
+	}
+}
@@ -17,7 +17,7 @@ func connectToDB(cfg *config.Config) (*gorm.DB, error) {
 	if cfg.MODE == "production" {
 		dsn := cfg.DB_URL
 
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 		if err != nil {
 			return nil, fmt.Errorf("failed to open database connection: %w", err)
 		}
@@ -27,17 +27,52 @@ func connectToDB(cfg *config.Config) (*gorm.DB, error) {
 		db, err = gorm.Open(
 			sqlite.Open("test.db"),
 
-			&gorm.Config{},
+			&gorm.Config{TranslateError: true},
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open database connection: %w", err)
 		}
 	}
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrate brings the schema of db up to date and runs the data migrations
+// that are due.
+func migrate(db *gorm.DB) error {
 	db.Migrator().AutoMigrate(&models.Product{})
 	db.Migrator().AutoMigrate(&models.InventoryTransaction{})
 	db.Migrator().AutoMigrate(&models.ProductImage{})
+	db.Migrator().AutoMigrate(&models.ImageUpload{})
+	db.Migrator().AutoMigrate(&models.TrackedImage{})
 	db.Migrator().AutoMigrate(&models.SizeVariant{})
 	db.Migrator().AutoMigrate(&models.ColorVariant{})
+	db.Migrator().AutoMigrate(&models.ReplenishmentSetting{})
+	db.Migrator().AutoMigrate(&models.Supplier{})
+	db.Migrator().AutoMigrate(&models.PurchaseOrder{})
+	db.Migrator().AutoMigrate(&models.PurchaseOrderLine{})
+	db.Migrator().AutoMigrate(&models.PriceRule{})
+	db.Migrator().AutoMigrate(&models.PriceHistory{})
+	db.Migrator().AutoMigrate(&models.CategoryTaxDefault{})
+	db.Migrator().AutoMigrate(&models.StoreTaxProfile{})
+	db.Migrator().AutoMigrate(&models.Category{})
+	db.Migrator().AutoMigrate(&models.AttributeDefinition{})
+	db.Migrator().AutoMigrate(&models.ProductAttribute{})
+	db.Migrator().AutoMigrate(&models.ModifierGroup{})
+	db.Migrator().AutoMigrate(&models.ModifierOption{})
+	db.Migrator().AutoMigrate(&models.ComboItem{})
+	db.Migrator().AutoMigrate(&models.AvailabilitySchedule{})
+	db.Migrator().AutoMigrate(&models.AvailabilityException{})
+	db.Migrator().AutoMigrate(&models.StoreTimezone{})
+	db.Migrator().AutoMigrate(&models.StoreCatalogVersion{})
+	db.Migrator().AutoMigrate(&models.CatalogProduct{})
+	db.Migrator().AutoMigrate(&models.StoreLocation{})
+	db.Migrator().AutoMigrate(&models.StoreDetails{})
+	db.Migrator().AutoMigrate(&models.StoreBackfillMiss{})
+	db.Migrator().AutoMigrate(&models.SchemaMigration{})
 
-	return db, nil
+	return runMigrations(db)
 }
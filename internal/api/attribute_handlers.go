@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type AttributeHandler struct {
+	AttributeService service.AttributeService
+}
+
+func NewAttributeHandler(AttributeService *service.AttributeService) *AttributeHandler {
+	return &AttributeHandler{AttributeService: *AttributeService}
+}
+
+func (h *AttributeHandler) CreateAttributeDefinition(ctx *gin.Context) {
+	var definition models.AttributeDefinition
+
+	err := ctx.ShouldBindJSON(&definition)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	definition.CategoryID = ctx.Param("id")
+
+	createdDefinition, err := h.AttributeService.CreateAttributeDefinition(ctx, &definition)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdDefinition)
+}
+
+func (h *AttributeHandler) GetAttributeDefinitions(ctx *gin.Context) {
+	categoryID := ctx.Param("id")
+
+	definitions, err := h.AttributeService.GetAttributeDefinitions(ctx, categoryID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, definitions)
+}
+
+func (h *AttributeHandler) UpdateAttributeDefinition(ctx *gin.Context) {
+	var definition models.AttributeDefinition
+
+	err := ctx.ShouldBindJSON(&definition)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	definition.ID = utils.StringToInt(ctx.Param("id"))
+
+	updatedDefinition, err := h.AttributeService.UpdateAttributeDefinition(ctx, &definition)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedDefinition)
+}
+
+func (h *AttributeHandler) DeleteAttributeDefinition(ctx *gin.Context) {
+	id := utils.StringToInt(ctx.Param("id"))
+
+	err := h.AttributeService.DeleteAttributeDefinition(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Attribute deleted"})
+}
+
+// parseAttributeFilters reads attribute filters from the query string:
+// attr.<key>=<value> matches a value, attr.<key>.min and attr.<key>.max
+// bound a NUMBER attribute.
+func parseAttributeFilters(ctx *gin.Context) ([]service.AttributeFilter, error) {
+	filters := map[string]*service.AttributeFilter{}
+	keys := []string{}
+	for param, values := range ctx.Request.URL.Query() {
+		if !strings.HasPrefix(param, "attr.") || len(values) == 0 {
+			continue
+		}
+
+		key, bound := strings.TrimPrefix(param, "attr."), ""
+		if strings.HasSuffix(key, ".min") || strings.HasSuffix(key, ".max") {
+			key, bound = key[:len(key)-4], key[len(key)-3:]
+		}
+		filter, ok := filters[key]
+		if !ok {
+			filter = &service.AttributeFilter{Key: key}
+			filters[key] = filter
+			keys = append(keys, key)
+		}
+
+		if bound == "" {
+			filter.Value = values[0]
+			continue
+		}
+		number, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", param)
+		}
+		if bound == "min" {
+			filter.Min = &number
+		} else {
+			filter.Max = &number
+		}
+	}
+
+	result := make([]service.AttributeFilter, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *filters[key])
+	}
+	return result, nil
+}
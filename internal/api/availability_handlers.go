@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type AvailabilityHandler struct {
+	AvailabilityService service.AvailabilityService
+}
+
+func NewAvailabilityHandler(AvailabilityService *service.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{AvailabilityService: *AvailabilityService}
+}
+
+func (h *AvailabilityHandler) CreateSchedule(ctx *gin.Context) {
+	var schedule models.AvailabilitySchedule
+
+	err := ctx.ShouldBindJSON(&schedule)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdSchedule, err := h.AvailabilityService.CreateSchedule(ctx, &schedule)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdSchedule)
+}
+
+func (h *AvailabilityHandler) GetScheduleByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	schedule, err := h.AvailabilityService.GetScheduleByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+func (h *AvailabilityHandler) GetSchedulesByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	schedules, err := h.AvailabilityService.GetSchedulesByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedules)
+}
+
+func (h *AvailabilityHandler) UpdateSchedule(ctx *gin.Context) {
+	var schedule models.AvailabilitySchedule
+
+	err := ctx.ShouldBindJSON(&schedule)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	schedule.ID = ctx.Param("id")
+
+	updatedSchedule, err := h.AvailabilityService.UpdateSchedule(ctx, &schedule)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedSchedule)
+}
+
+func (h *AvailabilityHandler) DeleteSchedule(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.AvailabilityService.DeleteSchedule(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Availability schedule deleted"})
+}
+
+func (h *AvailabilityHandler) CreateException(ctx *gin.Context) {
+	var exception models.AvailabilityException
+
+	err := ctx.ShouldBindJSON(&exception)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdException, err := h.AvailabilityService.CreateException(ctx, &exception)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdException)
+}
+
+func (h *AvailabilityHandler) GetExceptionsByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	exceptions, err := h.AvailabilityService.GetExceptionsByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, exceptions)
+}
+
+func (h *AvailabilityHandler) DeleteException(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.AvailabilityService.DeleteException(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Availability exception deleted"})
+}
+
+func (h *AvailabilityHandler) SetStoreTimezone(ctx *gin.Context) {
+	var timezone models.StoreTimezone
+
+	err := ctx.ShouldBindJSON(&timezone)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	timezone.StoreID = ctx.Param("store_id")
+
+	updatedTimezone, err := h.AvailabilityService.SetStoreTimezone(ctx, &timezone)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedTimezone)
+}
+
+func (h *AvailabilityHandler) GetStoreTimezone(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	timezone, err := h.AvailabilityService.GetStoreTimezone(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, timezone)
+}
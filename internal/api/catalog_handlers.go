@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type CatalogHandler struct {
+	CatalogService service.CatalogService
+}
+
+func NewCatalogHandler(CatalogService *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{CatalogService: *CatalogService}
+}
+
+func (h *CatalogHandler) CreateCatalogProduct(ctx *gin.Context) {
+	var product models.CatalogProduct
+
+	err := ctx.ShouldBindJSON(&product)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdProduct, err := h.CatalogService.CreateCatalogProduct(ctx, &product)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdProduct)
+}
+
+func (h *CatalogHandler) GetCatalogProductByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	product, err := h.CatalogService.GetCatalogProductByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+func (h *CatalogHandler) GetCatalogProductByBarcode(ctx *gin.Context) {
+	barcode := ctx.Param("barcode")
+
+	product, err := h.CatalogService.GetCatalogProductByBarcode(ctx, barcode)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+func (h *CatalogHandler) SearchCatalog(ctx *gin.Context) {
+	query := ctx.Query("q")
+	limit := utils.StringToInt(ctx.Query("limit"))
+
+	products, err := h.CatalogService.SearchCatalog(ctx, query, limit)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
+
+func (h *CatalogHandler) UpdateCatalogProduct(ctx *gin.Context) {
+	var product models.CatalogProduct
+
+	err := ctx.ShouldBindJSON(&product)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	product.ID = ctx.Param("id")
+
+	updatedProduct, err := h.CatalogService.UpdateCatalogProduct(ctx, &product)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedProduct)
+}
+
+func (h *CatalogHandler) DeleteCatalogProduct(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.CatalogService.DeleteCatalogProduct(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Catalog product deleted"})
+}
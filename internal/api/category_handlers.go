@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type CategoryHandler struct {
+	CategoryService service.CategoryService
+}
+
+func NewCategoryHandler(CategoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{CategoryService: *CategoryService}
+}
+
+func (h *CategoryHandler) CreateCategory(ctx *gin.Context) {
+	var category models.Category
+
+	err := ctx.ShouldBindJSON(&category)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdCategory, err := h.CategoryService.CreateCategory(ctx, &category)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdCategory)
+}
+
+func (h *CategoryHandler) GetCategoryByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	category, err := h.CategoryService.GetCategoryByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (h *CategoryHandler) GetCategoryTree(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	categories, err := h.CategoryService.GetCategoryTree(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, categories)
+}
+
+func (h *CategoryHandler) UpdateCategory(ctx *gin.Context) {
+	var category models.Category
+
+	err := ctx.ShouldBindJSON(&category)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	category.ID = ctx.Param("id")
+
+	updatedCategory, err := h.CategoryService.UpdateCategory(ctx, &category)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedCategory)
+}
+
+func (h *CategoryHandler) DeleteCategory(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.CategoryService.DeleteCategory(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+}
+
+func (h *CategoryHandler) GetProductsByCategory(ctx *gin.Context) {
+	storeID := ctx.Param("id")
+	categoryID := ctx.Param("category_id")
+
+	filters, err := parseAttributeFilters(ctx)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	products, err := h.CategoryService.GetProductsByCategory(ctx, storeID, categoryID, filters)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
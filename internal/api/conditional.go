@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+// StoreCatalogConditional validates reads of the products of the store named
+// by the path parameter param against the catalog state of the store.
+func StoreCatalogConditional(catalogVersionService service.CatalogVersionService, param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		state, err := catalogVersionService.GetStoreCatalogState(ctx, ctx.Param(param))
+		if err == nil {
+			answerConditional(ctx, state)
+		}
+	}
+}
+
+// ProductCatalogConditional validates reads of the product named by the path
+// parameter param against the catalog state of its store. Requests for
+// missing products are left to the handler.
+func ProductCatalogConditional(catalogVersionService service.CatalogVersionService, param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		state, err := catalogVersionService.GetProductCatalogState(ctx, ctx.Param(param))
+		if err == nil {
+			answerConditional(ctx, state)
+		}
+	}
+}
+
+// answerConditional sets the ETag and Last-Modified of a catalog state on the
+// response, and answers 304 Not Modified when the request's If-None-Match or,
+// lacking it, If-Modified-Since match them. The state is read before the
+// products, so that a change made in between leaves the validators older
+// than the response rather than newer.
+func answerConditional(ctx *gin.Context, state *service.CatalogState) {
+	etag := catalogETag(state)
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "no-cache")
+	if !state.ModifiedAt.IsZero() {
+		ctx.Header("Last-Modified", state.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		return
+	}
+	if matches := ctx.Request.Header.Values("If-None-Match"); len(matches) > 0 {
+		for _, match := range strings.Split(strings.Join(matches, ","), ",") {
+			match = strings.TrimSpace(match)
+			// If-None-Match compares weakly
+			if match == "*" || strings.TrimPrefix(match, "W/") == strings.TrimPrefix(etag, "W/") {
+				ctx.AbortWithStatus(http.StatusNotModified)
+				return
+			}
+		}
+		return
+	}
+	if since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since")); err == nil && !state.ModifiedAt.IsZero() {
+		if !state.ModifiedAt.Truncate(time.Second).After(since) {
+			ctx.AbortWithStatus(http.StatusNotModified)
+		}
+	}
+}
+
+// catalogETag is a weak validator of the catalog state of a store, as the
+// products read from the same state can be serialized differently. The
+// state of a product ends in the version of the product, W/"7.1700000000.3",
+// so that the ETag of a product read can be sent back as If-Match.
+func catalogETag(state *service.CatalogState) string {
+	var modifiedAt int64
+	if !state.ModifiedAt.IsZero() {
+		modifiedAt = state.ModifiedAt.Unix()
+	}
+	if state.ProductVersion > 0 {
+		return fmt.Sprintf(`W/"%d.%d.%d"`, state.Version, modifiedAt, state.ProductVersion)
+	}
+	return fmt.Sprintf(`W/"%d.%d"`, state.Version, modifiedAt)
+}
+
+// expectedVersion is the version of a product a write is based on, given as
+// the If-Match header or as the version form or query field. If-Match is the
+// version itself, such as "3", or the ETag of a read of the product. The ETag
+// of a store listing says nothing of the version of one product and is
+// refused. A write given neither, or If-Match: *, is not checked, which is
+// version 0.
+func expectedVersion(ctx *gin.Context) (int64, error) {
+	field, value := "If-Match", ctx.GetHeader("If-Match")
+	if value == "" {
+		field, value = "version", ctx.PostForm("version")
+	}
+	if value == "" {
+		value = ctx.Query("version")
+	}
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	if field == "If-Match" && strings.HasPrefix(value, "W/") {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(value, "W/"), `"`), ".")
+		if len(parts) != 3 {
+			return 0, &service.Error{
+				Kind:    service.ErrorKindInvalid,
+				Field:   field,
+				Message: "If-Match must be the ETag of a read of the product, not of a listing",
+			}
+		}
+		value = parts[2]
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || version < 1 {
+		return 0, &service.Error{
+			Kind:    service.ErrorKindInvalid,
+			Field:   field,
+			Message: field + " must be a version of the product",
+		}
+	}
+	return version, nil
+}
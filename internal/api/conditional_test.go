@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+// catalogStates answers every store and product with state.
+type catalogStates struct {
+	state service.CatalogState
+}
+
+func (c *catalogStates) GetStoreCatalogState(ctx *gin.Context, storeID string) (*service.CatalogState, error) {
+	state := c.state
+	state.ProductVersion = 0
+	return &state, nil
+}
+
+func (c *catalogStates) GetProductCatalogState(ctx *gin.Context, productID string) (*service.CatalogState, error) {
+	state := c.state
+	return &state, nil
+}
+
+func TestCatalogConditional(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	states := &catalogStates{state: service.CatalogState{
+		StoreID:        "s1",
+		Version:        3,
+		ModifiedAt:     time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+		ProductVersion: 2,
+	}}
+	router := gin.New()
+	ok := func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{}) }
+	router.GET("/stores/:store_id/products", StoreCatalogConditional(states, "store_id"), ok)
+	router.GET("/products/:id", ProductCatalogConditional(states, "id"), ok)
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/stores/s1/products", "/products/p1"} {
+		states.state.ModifiedAt = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+		first := get(path, nil)
+		etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+		if first.Code != http.StatusOK || etag == "" || lastModified != "Mon, 02 Mar 2026 08:00:00 GMT" {
+			t.Fatalf("%s: %d, ETag %q, Last-Modified %q", path, first.Code, etag, lastModified)
+		}
+
+		for _, header := range []map[string]string{
+			{"If-None-Match": etag},
+			{"If-None-Match": `"other", ` + etag},
+			{"If-Modified-Since": lastModified},
+		} {
+			if w := get(path, header); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+				t.Errorf("%s %v: %d", path, header, w.Code)
+			}
+		}
+		if w := get(path, map[string]string{"If-None-Match": `W/"other"`}); w.Code != http.StatusOK {
+			t.Errorf("%s with another ETag: %d", path, w.Code)
+		}
+
+		// a price rule starting, a window opening or a holiday beginning
+		// moves the state on without a write
+		states.state.ModifiedAt = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+		later := get(path, map[string]string{"If-None-Match": etag})
+		if later.Code != http.StatusOK || later.Header().Get("ETag") == etag {
+			t.Errorf("%s after a boundary: %d, ETag %s", path, later.Code, later.Header().Get("ETag"))
+		}
+		if w := get(path, map[string]string{"If-Modified-Since": lastModified}); w.Code != http.StatusOK {
+			t.Errorf("%s after a boundary, If-Modified-Since: %d", path, w.Code)
+		}
+		if w := get(path, map[string]string{"If-None-Match": later.Header().Get("ETag")}); w.Code != http.StatusNotModified {
+			t.Errorf("%s with the new ETag: %d", path, w.Code)
+		}
+	}
+
+	if etag := get("/products/p1", nil).Header().Get("ETag"); etag != `W/"3.1772445600.2"` {
+		t.Errorf("product ETag %s", etag)
+	}
+	if etag := get("/stores/s1/products", nil).Header().Get("ETag"); etag != `W/"3.1772445600"` {
+		t.Errorf("store ETag %s", etag)
+	}
+}
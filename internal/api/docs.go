@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"embed"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUI holds the assets of the release of Swagger UI committed under
+// swaggerui, so that the documentation page loads nothing from outside the
+// service.
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// swaggerUIAssets are the assets the documentation page loads.
+var swaggerUIAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+}
+
+// APIDocs serves a Swagger UI page for the document at /openapi.json.
+func APIDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+// APIDocsAsset serves an asset of the Swagger UI page.
+func APIDocsAsset(ctx *gin.Context) {
+	name := ctx.Param("file")
+	contentType, ok := swaggerUIAssets[name]
+	if !ok {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	data, err := swaggerUI.ReadFile(path.Join("swaggerui", name))
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// swaggerUIPage renders /openapi.json with the bundled Swagger UI.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Product service API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"gorm.io/gorm"
+)
+
+// envelopeKey marks the requests of the v1 API, which answer errors with an
+// ErrorResponse.
+const envelopeKey = "error_envelope"
+
+// ErrorResponse is the body of every error of the v1 API.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an error by a stable code, a message for people and,
+// for invalid requests, the fields at fault. Conflicts with a state clients
+// can merge with, such as a stale product version, carry it as Current.
+type ErrorBody struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []FieldDetail `json:"details,omitempty"`
+	Current interface{}   `json:"current,omitempty"`
+}
+
+// FieldDetail is a problem with one field of a request.
+type FieldDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// name fields in binding errors as clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// V1 marks the routes of the v1 API, so that their errors are answered with
+// an ErrorResponse. The unversioned routes keep answering {"error": message}.
+func V1() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(envelopeKey, true)
+		ctx.Next()
+	}
+}
+
+// AliasParam makes the path parameter from also available as to, for
+// handlers shared between routes that name a parameter differently.
+func AliasParam(from, to string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Params = append(ctx.Params, gin.Param{Key: to, Value: ctx.Param(from)})
+		ctx.Next()
+	}
+}
+
+// respondError answers the error of a service with the status code of its
+// kind. Records not found are 404, duplicates 409, and errors of no kind
+// are internal errors.
+func respondError(ctx *gin.Context, err error) {
+	var serviceErr *service.Error
+	switch {
+	case errors.As(err, &serviceErr):
+		body := ErrorBody{Code: serviceErr.Kind, Message: serviceErr.Message, Current: serviceErr.Current}
+		if serviceErr.Field != "" {
+			body.Details = []FieldDetail{{Field: serviceErr.Field, Message: serviceErr.Message}}
+		}
+		writeError(ctx, errorStatus[serviceErr.Kind], body)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(ctx, http.StatusNotFound, ErrorBody{Code: service.ErrorKindNotFound, Message: err.Error()})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		writeError(ctx, http.StatusConflict, ErrorBody{Code: service.ErrorKindConflict, Message: "already exists"})
+	default:
+		log.Printf("%s %s: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		writeError(ctx, http.StatusInternalServerError, ErrorBody{Code: "internal", Message: err.Error()})
+	}
+}
+
+// respondBadRequest answers a request that could not be bound, naming the
+// fields at fault when it can.
+func respondBadRequest(ctx *gin.Context, err error) {
+	body := ErrorBody{Code: service.ErrorKindInvalid, Message: err.Error()}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		body.Message = "invalid request"
+		for _, fieldErr := range validationErrs {
+			body.Details = append(body.Details, FieldDetail{
+				Field:   fieldErr.Field(),
+				Message: validationMessage(fieldErr),
+			})
+		}
+	case errors.As(err, &typeErr):
+		body.Details = []FieldDetail{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}}
+	}
+	writeError(ctx, http.StatusBadRequest, body)
+}
+
+var errorStatus = map[string]int{
+	service.ErrorKindInvalid:    http.StatusBadRequest,
+	service.ErrorKindNotFound:   http.StatusNotFound,
+	service.ErrorKindConflict:   http.StatusConflict,
+	service.ErrorKindValidation: http.StatusUnprocessableEntity,
+}
+
+func writeError(ctx *gin.Context, status int, body ErrorBody) {
+	if !ctx.GetBool(envelopeKey) {
+		if body.Current != nil {
+			ctx.JSON(status, gin.H{"error": body.Message, "current": body.Current})
+			return
+		}
+		ctx.JSON(status, gin.H{"error": body.Message})
+		return
+	}
+	if status == http.StatusInternalServerError {
+		// the cause is logged, not shown
+		body.Message = "internal error"
+	}
+	ctx.JSON(status, ErrorResponse{Error: body})
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + fieldErr.Param()
+	case "max", "lte":
+		return "must be at most " + fieldErr.Param()
+	case "oneof":
+		return "must be one of " + fieldErr.Param()
+	}
+	return "is invalid"
+}
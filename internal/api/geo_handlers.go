@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type GeoHandler struct {
+	GeoService service.GeoService
+}
+
+func NewGeoHandler(GeoService *service.GeoService) *GeoHandler {
+	return &GeoHandler{GeoService: *GeoService}
+}
+
+func (h *GeoHandler) SetStoreLocation(ctx *gin.Context) {
+	var location models.StoreLocation
+
+	err := ctx.ShouldBindJSON(&location)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	location.StoreID = ctx.Param("store_id")
+
+	updatedLocation, err := h.GeoService.SetStoreLocation(ctx, &location)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedLocation)
+}
+
+func (h *GeoHandler) GetStoreLocation(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	location, err := h.GeoService.GetStoreLocation(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, location)
+}
+
+func (h *GeoHandler) DeleteStoreLocation(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	err := h.GeoService.DeleteStoreLocation(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Store location deleted"})
+}
+
+func (h *GeoHandler) GetNearbyProducts(ctx *gin.Context) {
+	query, err := queryNearby(ctx)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	page, err := h.GeoService.GetNearbyProducts(ctx, query)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	if page.NextCursor != "" {
+		ctx.Header("X-Next-Cursor", page.NextCursor)
+	}
+	ctx.JSON(http.StatusOK, page.Listings)
+}
+
+func (h *GeoHandler) GetNearbyPosts(ctx *gin.Context) {
+	query, err := queryNearby(ctx)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	page, err := h.GeoService.GetNearbyPosts(ctx, query)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	if page.NextCursor != "" {
+		ctx.Header("X-Next-Cursor", page.NextCursor)
+	}
+	ctx.JSON(http.StatusOK, page.Listings)
+}
+
+// queryNearby reads the point, radius, limit and cursor of a distance search.
+// The point is required; the radius is in kilometres.
+func queryNearby(ctx *gin.Context) (service.NearbyQuery, error) {
+	query := service.NearbyQuery{Limit: utils.StringToInt(ctx.Query("limit")), Cursor: ctx.Query("cursor")}
+
+	var err error
+	if query.Latitude, err = strconv.ParseFloat(ctx.Query("lat"), 64); err != nil {
+		return query, fmt.Errorf("invalid lat %q", ctx.Query("lat"))
+	}
+	if query.Longitude, err = strconv.ParseFloat(ctx.Query("lng"), 64); err != nil {
+		return query, fmt.Errorf("invalid lng %q", ctx.Query("lng"))
+	}
+	if radius := ctx.Query("radius_km"); radius != "" {
+		if query.RadiusKm, err = strconv.ParseFloat(radius, 64); err != nil {
+			return query, fmt.Errorf("invalid radius_km %q", radius)
+		}
+	}
+	return query, nil
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tanush-128/openzo_backend/product/internal/models"
@@ -21,6 +24,9 @@ func NewHandler(ProductService *service.ProductService) *Handler {
 type ProductDisplayOrderUpdate struct {
 	ProductID    string `json:"product_id"`
 	DisplayOrder int    `json:"display_order"`
+	// Version is the version of the product the update is based on; the
+	// batch is refused if it is stale. Updates without one are not checked.
+	Version int64 `json:"version,omitempty"`
 }
 
 type BatchUpdateRequest struct {
@@ -32,30 +38,45 @@ func (h *Handler) CreateProduct(ctx *gin.Context) {
 
 	product.Name = ctx.PostForm("name")
 	product.Description = ctx.PostForm("description")
-	product.QuantityUnit = ctx.PostForm("quantity_unit")
-	product.MRP = utils.StringToInt(ctx.PostForm("mrp"))
-	product.MSRP = utils.StringToInt(ctx.PostForm("msrp"))
-	product.DiscountPrice = utils.StringToInt(ctx.PostForm("discount_price"))
+	currency := ctx.PostForm("currency")
+	product.QuantityUnit = models.Unit(ctx.PostForm("quantity_unit"))
+	product.MRP = utils.StringToMoney(ctx.PostForm("mrp"), currency)
+	product.MSRP = utils.StringToMoney(ctx.PostForm("msrp"), currency)
+	product.DiscountPrice = utils.StringToMoney(ctx.PostForm("discount_price"), currency)
 	product.Barcode = ctx.PostForm("barcode")
+	product.MasterProductID = ctx.PostForm("master_product_id")
 	product.StoreID = ctx.PostForm("store_id")
+	if storeID := ctx.Param("store_id"); storeID != "" {
+		product.StoreID = storeID
+	}
 	product.Category = ctx.PostForm("category")
-	product.Quantity = utils.StringToInt(ctx.PostForm("quantity"))
+	product.CategoryID = ctx.PostForm("category_id")
+	product.Quantity = utils.StringToQuantity(ctx.PostForm("quantity"))
 	product.Brand = ctx.PostForm("brand")
-	product.CriticalQuantity = utils.StringToInt(ctx.PostForm("critical_quantity"))
+	product.CriticalQuantity = utils.StringToQuantity(ctx.PostForm("critical_quantity"))
 	product.Type = ctx.PostForm("type")
 	product.VegType = ctx.PostForm("veg_type")
 	product.Servers = utils.StringToInt(ctx.PostForm("servers"))
 	product.CustomCode = ctx.PostForm("custom_code")
 	product.SizeVariants = []models.SizeVariant{}
 	product.ColorVariants = []models.ColorVariant{}
+	product.Attributes = []models.ProductAttribute{}
 	product.OutOfStock = ctx.PostForm("out_of_stock") == "true"
+	product.ExpiresAt = utils.StringToTimePointer(ctx.PostForm("expires_at"))
+	product.Pinned = ctx.PostForm("pinned") == "true"
+	product.HSNCode = ctx.PostForm("hsn_code")
+	product.TaxClass = ctx.PostForm("tax_class")
+	product.TaxInclusive = utils.StringToBoolPointer(ctx.PostForm("tax_inclusive"))
 
 	json.Unmarshal([]byte(ctx.PostForm("size_variants")), &product.SizeVariants)
 	json.Unmarshal([]byte(ctx.PostForm("color_variants")), &product.ColorVariants)
+	json.Unmarshal([]byte(ctx.PostForm("attributes")), &product.Attributes)
+	product.ComboItems = []models.ComboItem{}
+	json.Unmarshal([]byte(ctx.PostForm("combo_items")), &product.ComboItems)
 
 	createdProduct, err := h.ProductService.CreateProduct(ctx, product)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -68,71 +89,175 @@ func (h *Handler) GetProductByID(ctx *gin.Context) {
 
 	Product, err := h.ProductService.GetProductByID(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, Product)
 }
 
+// GetProductsByStoreID lists the products of a store as a plain array.
+// Without a limit or cursor it lists every product, and without include or
+// fields with every relation, as it always has. With a limit or cursor it
+// answers one page and passes the cursor of the next in X-Next-Cursor.
 func (h *Handler) GetProductsByStoreID(ctx *gin.Context) {
-	storeID := ctx.Param("id")
+	query, fields, err := parseProductListQuery(ctx, ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if ctx.Query("include") == "" && ctx.Query("fields") == "" {
+		query.Include = service.ProductIncludes
+	}
+	paged := ctx.Query("limit") != "" || query.Cursor != ""
+	if !paged {
+		query.Limit = service.MaxProductListLimit
+	}
 
-	Products, err := h.ProductService.GetProductsByStoreID(ctx, storeID)
+	products := []models.Product{}
+	for {
+		page, err := h.ProductService.ListStoreProducts(ctx, query)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		products = append(products, page.Products...)
+		if paged && page.NextCursor != "" {
+			ctx.Header("X-Next-Cursor", page.NextCursor)
+		}
+		if paged || page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	ctx.JSON(http.StatusOK, sparseProducts(products, fields))
+}
+
+// ListStoreProducts answers a page of the products of a store. Relations are
+// left out unless asked for with include or fields.
+func (h *Handler) ListStoreProducts(ctx *gin.Context) {
+	query, fields, err := parseProductListQuery(ctx, ctx.Param("store_id"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, Products)
+	page, err := h.ProductService.ListStoreProducts(ctx, query)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
 
+	if len(fields) == 0 {
+		ctx.JSON(http.StatusOK, page)
+		return
+	}
+	response := gin.H{"products": sparseProducts(page.Products, fields)}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	ctx.JSON(http.StatusOK, response)
 }
 
+// GetPostByPincode lists the newest posts of a pincode as a plain array. Use
+// GetFeed to page through the feed.
 func (h *Handler) GetPostByPincode(ctx *gin.Context) {
 	pincode := ctx.Param("pincode")
 
-	Products, err := h.ProductService.GetPostByPincode(ctx, pincode)
+	page, err := h.ProductService.GetFeed(ctx, service.FeedQuery{
+		Pincode: pincode,
+		Mode:    service.FeedModeRecent,
+		Limit:   utils.StringToInt(ctx.Query("limit")),
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, Products)
+	ctx.JSON(http.StatusOK, page.Posts)
+}
+
+func (h *Handler) GetFeed(ctx *gin.Context) {
+	mode := ctx.Query("sort")
+	if mode == "" {
+		mode = ctx.Query("mode")
+	}
+
+	page, err := h.ProductService.GetFeed(ctx, service.FeedQuery{
+		Pincode:     ctx.Param("pincode"),
+		Mode:        mode,
+		Limit:       utils.StringToInt(ctx.Query("limit")),
+		Cursor:      ctx.Query("cursor"),
+		PerStoreCap: utils.StringToInt(ctx.Query("per_store")),
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) GetUnits(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.Units())
 }
 
 func (h *Handler) UpdateProduct(ctx *gin.Context) {
 
 	var product models.Product
 	product.ID = ctx.PostForm("id")
+	if id := ctx.Param("id"); id != "" {
+		product.ID = id
+	}
 	product.Name = ctx.PostForm("name")
 	product.Description = ctx.PostForm("description")
-	product.QuantityUnit = ctx.PostForm("quantity_unit")
-	product.MRP = utils.StringToInt(ctx.PostForm("mrp"))
-	product.MSRP = utils.StringToInt(ctx.PostForm("msrp"))
-	product.DiscountPrice = utils.StringToInt(ctx.PostForm("discount_price"))
+	currency := ctx.PostForm("currency")
+	product.QuantityUnit = models.Unit(ctx.PostForm("quantity_unit"))
+	product.MRP = utils.StringToMoney(ctx.PostForm("mrp"), currency)
+	product.MSRP = utils.StringToMoney(ctx.PostForm("msrp"), currency)
+	product.DiscountPrice = utils.StringToMoney(ctx.PostForm("discount_price"), currency)
 	product.Barcode = ctx.PostForm("barcode")
+	product.MasterProductID = ctx.PostForm("master_product_id")
 	product.StoreID = ctx.PostForm("store_id")
 	product.Category = ctx.PostForm("category")
-	product.Quantity = utils.StringToInt(ctx.PostForm("quantity"))
+	product.CategoryID = ctx.PostForm("category_id")
+	product.Quantity = utils.StringToQuantity(ctx.PostForm("quantity"))
 	product.Brand = ctx.PostForm("brand")
-	product.CriticalQuantity = utils.StringToInt(ctx.PostForm("critical_quantity"))
+	product.CriticalQuantity = utils.StringToQuantity(ctx.PostForm("critical_quantity"))
 	product.CustomCode = ctx.PostForm("custom_code")
 	product.Type = ctx.PostForm("type")
 	product.VegType = ctx.PostForm("veg_type")
 	product.Servers = utils.StringToInt(ctx.PostForm("servers"))
 	product.SizeVariants = []models.SizeVariant{}
 	product.ColorVariants = []models.ColorVariant{}
+	product.Attributes = []models.ProductAttribute{}
 	product.Images = []models.ProductImage{}
 
 	product.OutOfStock = ctx.PostForm("out_of_stock") == "true"
+	product.ExpiresAt = utils.StringToTimePointer(ctx.PostForm("expires_at"))
+	product.Pinned = ctx.PostForm("pinned") == "true"
+	product.HSNCode = ctx.PostForm("hsn_code")
+	product.TaxClass = ctx.PostForm("tax_class")
+	product.TaxInclusive = utils.StringToBoolPointer(ctx.PostForm("tax_inclusive"))
 
 	json.Unmarshal([]byte(ctx.PostForm("product_images")), &product.Images)
 	json.Unmarshal([]byte(ctx.PostForm("size_variants")), &product.SizeVariants)
 	json.Unmarshal([]byte(ctx.PostForm("color_variants")), &product.ColorVariants)
+	json.Unmarshal([]byte(ctx.PostForm("attributes")), &product.Attributes)
+	product.ComboItems = []models.ComboItem{}
+	json.Unmarshal([]byte(ctx.PostForm("combo_items")), &product.ComboItems)
+
+	version, err := expectedVersion(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	product.Version = version
 
 	updatedProduct, err := h.ProductService.UpdateProduct(ctx, product)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -142,10 +267,15 @@ func (h *Handler) UpdateProduct(ctx *gin.Context) {
 func (h *Handler) UpdateDisplayOrder(ctx *gin.Context) {
 	id := ctx.Param("id")
 	displayOrder := utils.StringToInt(ctx.Query("display_order"))
+	version, err := expectedVersion(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
 
-	err := h.ProductService.UpdateDisplayOrder(ctx, id, displayOrder)
+	err = h.ProductService.UpdateDisplayOrder(ctx, id, displayOrder, version)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -155,7 +285,7 @@ func (h *Handler) UpdateDisplayOrder(ctx *gin.Context) {
 func (h *Handler) BatchUpdateDisplayOrder(c *gin.Context) {
 	var req BatchUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
@@ -164,11 +294,13 @@ func (h *Handler) BatchUpdateDisplayOrder(c *gin.Context) {
 		products[i] = models.Product{
 			ID:           update.ProductID,
 			DisplayOrder: update.DisplayOrder,
+			Version:      update.Version,
 		}
 	}
 
-	if err := h.ProductService.BatchUpdateDisplayOrder(c, products); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product display orders"})
+	// the unversioned route has no store in its path
+	if err := h.ProductService.BatchUpdateDisplayOrder(c, c.Param("store_id"), products); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -177,11 +309,20 @@ func (h *Handler) BatchUpdateDisplayOrder(c *gin.Context) {
 
 func (h *Handler) ChangeProductQuantity(ctx *gin.Context) {
 	id := ctx.Param("id")
-	quantity := utils.StringToInt(ctx.Query("quantity"))
+	quantity, err := models.ParseQuantity(ctx.Query("quantity"))
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	version, err := expectedVersion(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
 
-	err := h.ProductService.ChangeProductQuantity(ctx, id, quantity)
+	err = h.ProductService.ChangeProductQuantity(ctx, id, quantity, version)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -190,12 +331,102 @@ func (h *Handler) ChangeProductQuantity(ctx *gin.Context) {
 
 func (h *Handler) DeleteProduct(ctx *gin.Context) {
 	id := ctx.Param("id")
+	version, err := expectedVersion(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
 
-	err := h.ProductService.DeleteProduct(ctx, id)
+	err = h.ProductService.DeleteProduct(ctx, id, version)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
 }
+
+// productFields are the JSON fields of a product a listing can be narrowed to.
+var productFields = func() map[string]bool {
+	fields := map[string]bool{}
+	for _, field := range jsonFields(reflect.TypeOf(models.Product{})) {
+		fields[field.name] = true
+	}
+	return fields
+}()
+
+// fieldIncludes are the relations loaded for the fields that hold them.
+var fieldIncludes = map[string]string{
+	"images":                 service.ProductIncludeImages,
+	"size_variants":          service.ProductIncludeVariants,
+	"color_variants":         service.ProductIncludeVariants,
+	"inventory_transactions": service.ProductIncludeTransactions,
+	"attributes":             service.ProductIncludeAttributes,
+	"modifier_groups":        service.ProductIncludeModifierGroups,
+}
+
+// parseProductListQuery reads a store product listing from the query string:
+// limit, cursor, sort, category_id, stock, the attr.* filters, include, a
+// comma separated list of relations, and fields, a comma separated list of
+// the product fields to answer. Naming a relation in fields includes it.
+func parseProductListQuery(ctx *gin.Context, storeID string) (service.ProductListQuery, []string, error) {
+	filters, err := parseAttributeFilters(ctx)
+	if err != nil {
+		return service.ProductListQuery{}, nil, &service.Error{Kind: service.ErrorKindInvalid, Message: err.Error()}
+	}
+	query := service.ProductListQuery{
+		StoreID:    storeID,
+		Limit:      utils.StringToInt(ctx.Query("limit")),
+		Cursor:     ctx.Query("cursor"),
+		Sort:       ctx.Query("sort"),
+		CategoryID: ctx.Query("category_id"),
+		Stock:      ctx.Query("stock"),
+		Attributes: filters,
+		Include:    splitList(ctx.Query("include")),
+	}
+
+	fields := splitList(ctx.Query("fields"))
+	for _, field := range fields {
+		if !productFields[field] {
+			return service.ProductListQuery{}, nil, &service.Error{Kind: service.ErrorKindInvalid, Field: "fields", Message: fmt.Sprintf("unknown field %q", field)}
+		}
+		if include, ok := fieldIncludes[field]; ok {
+			query.Include = append(query.Include, include)
+		}
+	}
+	return query, fields, nil
+}
+
+// sparseProducts narrows products to the given JSON fields and their id, or
+// leaves them whole when no fields are given.
+func sparseProducts(products []models.Product, fields []string) interface{} {
+	if len(fields) == 0 {
+		return products
+	}
+
+	sparse := make([]map[string]json.RawMessage, len(products))
+	for i, product := range products {
+		data, _ := json.Marshal(product)
+		var whole map[string]json.RawMessage
+		json.Unmarshal(data, &whole)
+
+		sparse[i] = map[string]json.RawMessage{"id": whole["id"]}
+		for _, field := range fields {
+			// fields left empty are omitted, as in whole products
+			if value, ok := whole[field]; ok {
+				sparse[i][field] = value
+			}
+		}
+	}
+	return sparse
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
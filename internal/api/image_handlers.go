@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type ImageHandler struct {
+	ImageService service.ImageService
+}
+
+func NewImageHandler(ImageService *service.ImageService) *ImageHandler {
+	return &ImageHandler{ImageService: *ImageService}
+}
+
+// ImageImportRequest names the remote images to add to a product.
+type ImageImportRequest struct {
+	URLs        []string `json:"urls" binding:"required"`
+	AltText     string   `json:"alt_text"`
+	VariantType string   `json:"variant_type"`
+	VariantID   int      `json:"variant_id"`
+}
+
+// ImageOrderRequest lists the images of a product in their new order.
+type ImageOrderRequest struct {
+	ImageIDs []int `json:"image_ids" binding:"required"`
+}
+
+func (h *ImageHandler) AddImages(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	details := models.ProductImage{
+		AltText:     ctx.PostForm("alt_text"),
+		VariantType: ctx.PostForm("variant_type"),
+		VariantID:   utils.StringToInt(ctx.PostForm("variant_id")),
+	}
+
+	images, err := h.ImageService.AddImages(ctx, productID, form.File["images"], details)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (h *ImageHandler) ImportImages(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	var req ImageImportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	details := models.ProductImage{AltText: req.AltText, VariantType: req.VariantType, VariantID: req.VariantID}
+
+	images, err := h.ImageService.ImportImages(ctx, productID, req.URLs, details)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, images)
+}
+
+func (h *ImageHandler) GetProductImages(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	images, err := h.ImageService.GetProductImages(ctx, productID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (h *ImageHandler) UpdateImage(ctx *gin.Context) {
+	var image models.ProductImage
+
+	err := ctx.ShouldBindJSON(&image)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	image.ID = utils.StringToInt(ctx.Param("id"))
+
+	updatedImage, err := h.ImageService.UpdateImage(ctx, &image)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedImage)
+}
+
+func (h *ImageHandler) DeleteImage(ctx *gin.Context) {
+	id := utils.StringToInt(ctx.Param("id"))
+
+	err := h.ImageService.DeleteImage(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
+}
+
+func (h *ImageHandler) ReorderImages(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	var req ImageOrderRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	images, err := h.ImageService.ReorderImages(ctx, productID, req.ImageIDs)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (h *ImageHandler) SetPrimaryImage(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+	imageID := utils.StringToInt(ctx.Param("image_id"))
+
+	images, err := h.ImageService.SetPrimaryImage(ctx, productID, imageID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (h *ImageHandler) RetryProductImages(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	images, err := h.ImageService.RetryProductImages(ctx, productID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
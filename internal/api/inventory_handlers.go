@@ -21,13 +21,13 @@ func (h *InventoryHandler) CreateInventoryTransaction(ctx *gin.Context) {
 
 	err := ctx.ShouldBindJSON(&inventoryTransaction)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(ctx, err)
 		return
 	}
 
 	createdInventoryTransaction, err := h.InventoryService.CreateTransaction(ctx, &inventoryTransaction)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusCreated, createdInventoryTransaction)
@@ -38,12 +38,12 @@ func (h *InventoryHandler) GetInventoryTransactionByID(ctx *gin.Context) {
 
 	inventoryTransaction, err := h.InventoryService.GetTransactionByID(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
 	if inventoryTransaction == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		writeError(ctx, http.StatusNotFound, ErrorBody{Code: service.ErrorKindNotFound, Message: "Transaction not found"})
 		return
 	}
 
@@ -55,13 +55,16 @@ func (h *InventoryHandler) UpdateInventoryTransaction(ctx *gin.Context) {
 
 	err := ctx.ShouldBindJSON(&inventoryTransaction)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(ctx, err)
 		return
 	}
+	if id := ctx.Param("id"); id != "" {
+		inventoryTransaction.ID = id
+	}
 
 	updatedInventoryTransaction, err := h.InventoryService.UpdateTransaction(ctx, &inventoryTransaction)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -73,7 +76,7 @@ func (h *InventoryHandler) DeleteInventoryTransaction(ctx *gin.Context) {
 
 	err := h.InventoryService.DeleteTransaction(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -85,7 +88,7 @@ func (h *InventoryHandler) GetAllTransactionsByProductID(ctx *gin.Context) {
 
 	inventoryTransactions, err := h.InventoryService.GetAllTransactionsByProductID(ctx, productID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
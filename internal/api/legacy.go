@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+)
+
+// LegacyMoney keeps the unversioned routes answering prices as numbers of
+// major units, as they did before amounts carried a currency. The v1 routes
+// answer Money objects.
+func LegacyMoney() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		writer := &bufferedWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+		ctx.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if !ctx.GetBool(envelopeKey) && len(body) > 0 &&
+			strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			encoded, err := models.EncodeLegacyMoney(body)
+			if err != nil {
+				log.Printf("%s %s: failed to encode legacy prices: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+			} else {
+				body = encoded
+			}
+		}
+		if len(body) > 0 {
+			writer.ResponseWriter.Write(body)
+		}
+	}
+}
+
+// bufferedWriter holds back the body of a response until its handlers are
+// done.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type MenuHandler struct {
+	MenuService service.MenuService
+}
+
+func NewMenuHandler(MenuService *service.MenuService) *MenuHandler {
+	return &MenuHandler{MenuService: *MenuService}
+}
+
+func (h *MenuHandler) GetMenu(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	menu, err := h.MenuService.GetMenu(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, menu)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type ModifierHandler struct {
+	ModifierService service.ModifierService
+}
+
+func NewModifierHandler(ModifierService *service.ModifierService) *ModifierHandler {
+	return &ModifierHandler{ModifierService: *ModifierService}
+}
+
+// ProductModifierGroupsRequest lists the modifier groups offered with a product.
+type ProductModifierGroupsRequest struct {
+	ModifierGroupIDs []string `json:"modifier_group_ids"`
+}
+
+func (h *ModifierHandler) CreateModifierGroup(ctx *gin.Context) {
+	var group models.ModifierGroup
+
+	err := ctx.ShouldBindJSON(&group)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdGroup, err := h.ModifierService.CreateModifierGroup(ctx, &group)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdGroup)
+}
+
+func (h *ModifierHandler) GetModifierGroupByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	group, err := h.ModifierService.GetModifierGroupByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, group)
+}
+
+func (h *ModifierHandler) GetModifierGroupsByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	groups, err := h.ModifierService.GetModifierGroupsByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groups)
+}
+
+func (h *ModifierHandler) UpdateModifierGroup(ctx *gin.Context) {
+	var group models.ModifierGroup
+
+	err := ctx.ShouldBindJSON(&group)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	group.ID = ctx.Param("id")
+
+	updatedGroup, err := h.ModifierService.UpdateModifierGroup(ctx, &group)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedGroup)
+}
+
+func (h *ModifierHandler) DeleteModifierGroup(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.ModifierService.DeleteModifierGroup(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Modifier group deleted"})
+}
+
+func (h *ModifierHandler) SetProductModifierGroups(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	var req ProductModifierGroupsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	groups, err := h.ModifierService.SetProductModifierGroups(ctx, productID, req.ModifierGroupIDs)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groups)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type OfferHandler struct {
+	OfferService service.OfferService
+}
+
+func NewOfferHandler(OfferService *service.OfferService) *OfferHandler {
+	return &OfferHandler{OfferService: *OfferService}
+}
+
+func (h *OfferHandler) GetOffersByBarcode(ctx *gin.Context) {
+	barcode := ctx.Param("barcode")
+
+	offers, err := h.OfferService.GetOffersByBarcode(ctx, barcode, queryPincodes(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, offers)
+}
+
+func (h *OfferHandler) GetOffersByMasterProduct(ctx *gin.Context) {
+	masterProductID := ctx.Param("id")
+
+	offers, err := h.OfferService.GetOffersByMasterProduct(ctx, masterProductID, queryPincodes(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, offers)
+}
+
+// queryPincodes reads the pincodes of a request, given either as repeated
+// pincode parameters or as a comma separated pincodes parameter.
+func queryPincodes(ctx *gin.Context) []string {
+	pincodes := ctx.QueryArray("pincode")
+	if list := ctx.Query("pincodes"); list != "" {
+		pincodes = append(pincodes, strings.Split(list, ",")...)
+	}
+	return pincodes
+}
@@ -0,0 +1,943 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+// MessageResponse is the body of the routes that answer with a message only.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// StatusResponse is the body of the routes that answer with a status only.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// LegacyErrorResponse is the body of every error of the unversioned routes.
+type LegacyErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ProductConflictResponse is the body of a v1 write refused as based on a
+// stale version of a product. The error carries the current product.
+type ProductConflictResponse struct {
+	Error ProductConflict `json:"error"`
+}
+
+// ProductConflict is an ErrorBody carrying the current product.
+type ProductConflict struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Current models.Product `json:"current"`
+}
+
+// LegacyProductConflictResponse is the ProductConflictResponse of the
+// unversioned routes.
+type LegacyProductConflictResponse struct {
+	Error   string         `json:"error"`
+	Current models.Product `json:"current"`
+}
+
+// operation documents what the handler of a route reads besides its path
+// parameters, and what it answers with.
+type operation struct {
+	summary     string
+	description string
+	query       []param
+	// form fields make the request a multipart form, body a JSON document
+	form         []param
+	body         interface{}
+	optionalBody bool
+	response     interface{}
+	// status is the status of a successful response, 200 if unset
+	status int
+	// csv responses are offered besides JSON
+	csv bool
+	// conditional responses carry the catalog state of the store as ETag
+	// and Last-Modified, and answer 304 Not Modified to requests holding them
+	conditional bool
+	// versioned writes are refused with 409 Conflict and the current product
+	// when based on a stale version of it, given as If-Match or a version
+	// field unless the body carries the versions
+	versioned bool
+}
+
+// param is a query parameter or form field. Its type is one of string,
+// integer, number, boolean, date-time, strings, file, files or json.
+type param struct {
+	name        string
+	typ         string
+	description string
+	required    bool
+}
+
+// versionParam is the version of a product a write is based on, when not
+// given as If-Match.
+var versionParam = param{name: "version", typ: "integer", description: "version of the product the write is based on, if not given as If-Match"}
+
+// productFormFields are the multipart fields read by CreateProduct and
+// UpdateProduct.
+var productFormFields = []param{
+	{name: "name", typ: "string"},
+	{name: "description", typ: "string"},
+	{name: "currency", typ: "string", description: "ISO 4217 code of the prices, INR if unset"},
+	{name: "quantity_unit", typ: "string", description: "unit of Quantity, one of the units of GET /units"},
+	{name: "mrp", typ: "number", description: "in major units of currency"},
+	{name: "msrp", typ: "number", description: "in major units of currency"},
+	{name: "discount_price", typ: "number", description: "in major units of currency"},
+	{name: "barcode", typ: "string"},
+	{name: "master_product_id", typ: "string", description: "master catalog entry to fill the product from"},
+	{name: "store_id", typ: "string", description: "taken from the path on /v1/stores/{store_id}/products"},
+	{name: "category", typ: "string"},
+	{name: "category_id", typ: "string"},
+	{name: "quantity", typ: "number"},
+	{name: "brand", typ: "string"},
+	{name: "critical_quantity", typ: "number"},
+	{name: "type", typ: "string", description: "post, bundle or empty for a product"},
+	{name: "veg_type", typ: "string"},
+	{name: "servers", typ: "integer"},
+	{name: "custom_code", typ: "string"},
+	{name: "out_of_stock", typ: "boolean"},
+	{name: "expires_at", typ: "date-time", description: "RFC 3339 or 2006-01-02 15:04:05"},
+	{name: "pinned", typ: "boolean"},
+	{name: "hsn_code", typ: "string"},
+	{name: "tax_class", typ: "string"},
+	{name: "tax_inclusive", typ: "boolean"},
+	{name: "size_variants", typ: "json", description: "JSON array of SizeVariant"},
+	{name: "color_variants", typ: "json", description: "JSON array of ColorVariant"},
+	{name: "attributes", typ: "json", description: "JSON array of ProductAttribute"},
+	{name: "combo_items", typ: "json", description: "JSON array of ComboItem"},
+	{name: "images", typ: "files", description: "image files, uploaded as JPEG renditions"},
+	{name: "image_urls", typ: "strings", description: "remote images, imported in the background"},
+}
+
+const attributeFilterDescription = "Filter by custom attributes with attr.<key>=<value>, " +
+	"and bound NUMBER attributes with attr.<key>.min and attr.<key>.max."
+
+var (
+	variantParams = []param{
+		{name: "variant_type", typ: "string", description: "size or color"},
+		{name: "variant_id", typ: "integer"},
+	}
+	nearbyParams = []param{
+		{name: "lat", typ: "number", required: true},
+		{name: "lng", typ: "number", required: true},
+		{name: "radius_km", typ: "number"},
+		{name: "limit", typ: "integer"},
+		{name: "cursor", typ: "string", description: "X-Next-Cursor of the previous page"},
+	}
+	productListParams = []param{
+		{name: "limit", typ: "integer", description: "at most 200, 50 by default"},
+		{name: "cursor", typ: "string", description: "next cursor of the previous page"},
+		{name: "sort", typ: "string", description: "display_order, name, price (the effective price), newest or stock; prefix with - to reverse"},
+		{name: "category_id", typ: "string", description: "products of a category and its subcategories"},
+		{name: "stock", typ: "string", description: "in_stock, low_stock or out_of_stock"},
+		{name: "include", typ: "string", description: "comma separated relations: variants, images, transactions, attributes, modifier_groups"},
+		{name: "fields", typ: "string", description: "comma separated product fields to answer, besides id"},
+	}
+	pincodeParams = []param{
+		{name: "pincode", typ: "strings", description: "repeat for several pincodes"},
+		{name: "pincodes", typ: "string", description: "comma separated pincodes"},
+	}
+)
+
+// operations documents the handlers by their name, as in Handler.CreateProduct.
+var operations = map[string]operation{
+	// products
+	"Handler.CreateProduct": {
+		summary:  "Create a product",
+		form:     productFormFields,
+		response: models.Product{},
+		status:   http.StatusCreated,
+	},
+	"Handler.GetProductByID": {summary: "Get a product", response: models.Product{}, conditional: true},
+	"Handler.GetProductsByStoreID": {
+		summary: "List the products of a store",
+		description: "Without limit or cursor every product is listed, and without include or fields with every relation. " +
+			"With limit or cursor one page is answered, and the cursor of the next is passed in the X-Next-Cursor header. " +
+			attributeFilterDescription,
+		query:       productListParams,
+		response:    []models.Product{},
+		conditional: true,
+	},
+	"Handler.ListStoreProducts": {
+		summary:     "Page through the products of a store",
+		description: "Relations are left out unless named in include or fields. " + attributeFilterDescription,
+		query:       productListParams,
+		response:    service.ProductPage{},
+		conditional: true,
+	},
+	"Handler.GetPostByPincode": {
+		summary:  "List the newest posts of a pincode",
+		query:    []param{{name: "limit", typ: "integer"}},
+		response: []repository.ProductWithStore{},
+	},
+	"Handler.GetFeed": {
+		summary: "Page through the feed of a pincode",
+		query: []param{
+			{name: "sort", typ: "string", description: "recent or ranked"},
+			{name: "mode", typ: "string", description: "alias of sort"},
+			{name: "limit", typ: "integer"},
+			{name: "cursor", typ: "string", description: "next_cursor of the previous page"},
+			{name: "per_store", typ: "integer", description: "most posts of one store on a page"},
+		},
+		response: service.FeedPage{},
+	},
+	"Handler.GetUnits": {summary: "List the units of measure", response: []models.UnitDefinition{}},
+	"Handler.UpdateProduct": {
+		summary: "Update a product",
+		form: append([]param{
+			{name: "id", typ: "string", description: "taken from the path on /v1/products/{id}"},
+			{name: "product_images", typ: "json", description: "JSON array of ProductImage to keep"},
+			versionParam,
+		}, productFormFields...),
+		response:  models.Product{},
+		versioned: true,
+	},
+	"Handler.UpdateDisplayOrder": {
+		summary:   "Set the display order of a product",
+		query:     []param{{name: "display_order", typ: "integer", required: true}, versionParam},
+		response:  MessageResponse{},
+		versioned: true,
+	},
+	"Handler.BatchUpdateDisplayOrder": {
+		summary:     "Set the display order of several products",
+		description: "The batch is refused if the version of any of its updates is stale.",
+		body:        BatchUpdateRequest{},
+		response:    StatusResponse{},
+		versioned:   true,
+	},
+	"Handler.ChangeProductQuantity": {
+		summary:   "Set the stock quantity of a product",
+		query:     []param{{name: "quantity", typ: "number", required: true}, versionParam},
+		response:  MessageResponse{},
+		versioned: true,
+	},
+	"Handler.DeleteProduct": {
+		summary:   "Delete a product",
+		query:     []param{versionParam},
+		response:  MessageResponse{},
+		versioned: true,
+	},
+
+	// inventory
+	"InventoryHandler.CreateInventoryTransaction":    {summary: "Record an inventory transaction", body: models.InventoryTransaction{}, response: models.InventoryTransaction{}, status: http.StatusCreated},
+	"InventoryHandler.GetInventoryTransactionByID":   {summary: "Get an inventory transaction", response: models.InventoryTransaction{}},
+	"InventoryHandler.UpdateInventoryTransaction":    {summary: "Update an inventory transaction", body: models.InventoryTransaction{}, response: models.InventoryTransaction{}},
+	"InventoryHandler.DeleteInventoryTransaction":    {summary: "Delete an inventory transaction", response: MessageResponse{}},
+	"InventoryHandler.GetAllTransactionsByProductID": {summary: "List the inventory transactions of a product", response: []models.InventoryTransaction{}},
+
+	// replenishment
+	"ReplenishmentHandler.UpsertReplenishmentSetting":        {summary: "Set the replenishment setting of a product", body: models.ReplenishmentSetting{}, response: models.ReplenishmentSetting{}},
+	"ReplenishmentHandler.GetReplenishmentSettingsByStoreID": {summary: "List the replenishment settings of a store", response: []models.ReplenishmentSetting{}},
+	"ReplenishmentHandler.GetReorderSuggestions": {
+		summary: "Suggest the products to reorder",
+		query: []param{
+			{name: "days", typ: "integer", description: "sales window the suggestions are based on"},
+			{name: "all", typ: "boolean", description: "include products that need no reorder"},
+		},
+		response: []service.ReorderSuggestion{},
+	},
+	"ReplenishmentHandler.GetDraftPurchaseOrder": {
+		summary: "Draft a purchase order from the reorder suggestions",
+		query: []param{
+			{name: "days", typ: "integer"},
+			{name: "format", typ: "string", description: "csv for a CSV attachment"},
+		},
+		response: service.DraftPurchaseOrder{},
+		csv:      true,
+	},
+
+	// suppliers
+	"SupplierHandler.CreateSupplier":        {summary: "Create a supplier", body: models.Supplier{}, response: models.Supplier{}, status: http.StatusCreated},
+	"SupplierHandler.GetSupplierByID":       {summary: "Get a supplier", response: models.Supplier{}},
+	"SupplierHandler.GetSuppliersByStoreID": {summary: "List the suppliers of a store", response: []models.Supplier{}},
+	"SupplierHandler.UpdateSupplier":        {summary: "Update a supplier", body: models.Supplier{}, response: models.Supplier{}},
+	"SupplierHandler.DeleteSupplier":        {summary: "Delete a supplier", response: MessageResponse{}},
+
+	// purchase orders
+	"PurchaseOrderHandler.CreatePurchaseOrder":                  {summary: "Create a purchase order", body: models.PurchaseOrder{}, response: models.PurchaseOrder{}, status: http.StatusCreated},
+	"PurchaseOrderHandler.CreatePurchaseOrderFromReplenishment": {summary: "Create a purchase order from the reorder suggestions", body: PurchaseOrderFromReplenishmentRequest{}, response: models.PurchaseOrder{}, status: http.StatusCreated},
+	"PurchaseOrderHandler.GetPurchaseOrderByID":                 {summary: "Get a purchase order", response: models.PurchaseOrder{}},
+	"PurchaseOrderHandler.GetPurchaseOrdersByStoreID": {
+		summary:  "List the purchase orders of a store",
+		query:    []param{{name: "status", typ: "string"}},
+		response: []models.PurchaseOrder{},
+	},
+	"PurchaseOrderHandler.UpdatePurchaseOrder":       {summary: "Update a draft purchase order", body: models.PurchaseOrder{}, response: models.PurchaseOrder{}},
+	"PurchaseOrderHandler.UpdatePurchaseOrderStatus": {summary: "Move a purchase order to another status", body: PurchaseOrderStatusUpdate{}, response: models.PurchaseOrder{}},
+	"PurchaseOrderHandler.ReceiveGoods": {
+		summary:      "Receive goods against a purchase order",
+		description:  "An empty body receives everything still outstanding.",
+		body:         service.GoodsReceipt{},
+		optionalBody: true,
+		response:     models.PurchaseOrder{},
+	},
+	"PurchaseOrderHandler.DeletePurchaseOrder": {summary: "Delete a purchase order", response: MessageResponse{}},
+
+	// pricing
+	"PriceRuleHandler.CreatePriceRule":        {summary: "Create a price rule", body: models.PriceRule{}, response: models.PriceRule{}, status: http.StatusCreated},
+	"PriceRuleHandler.GetPriceRuleByID":       {summary: "Get a price rule", response: models.PriceRule{}},
+	"PriceRuleHandler.GetPriceRulesByStoreID": {summary: "List the price rules of a store", response: []models.PriceRule{}},
+	"PriceRuleHandler.UpdatePriceRule":        {summary: "Update a price rule", body: models.PriceRule{}, response: models.PriceRule{}},
+	"PriceRuleHandler.DeletePriceRule":        {summary: "Delete a price rule", response: MessageResponse{}},
+	"PriceRuleHandler.GetPriceHistoryByProductID": {
+		summary:  "List the price changes of a product",
+		response: []models.PriceHistory{},
+	},
+	"PriceRuleHandler.GetProductPrice": {
+		summary:  "Get the effective price of a product",
+		query:    append(append([]param{}, variantParams...), param{name: "at", typ: "date-time", description: "RFC 3339, now if unset"}),
+		response: service.ProductPrice{},
+	},
+
+	// tax
+	"TaxHandler.GetProductTax": {
+		summary: "Compute the GST due on a product",
+		query: append(append([]param{}, variantParams...),
+			param{name: "quantity", typ: "number"},
+			param{name: "origin_state", typ: "string", description: "state of the store's tax profile if unset"},
+			param{name: "destination_state", typ: "string", required: true},
+		),
+		response: service.TaxBreakdown{},
+	},
+	"TaxHandler.UpsertCategoryTaxDefault": {summary: "Set the tax default of a category", body: models.CategoryTaxDefault{}, response: models.CategoryTaxDefault{}},
+	"TaxHandler.GetCategoryTaxDefaults":   {summary: "List the category tax defaults of a store", response: []models.CategoryTaxDefault{}},
+	"TaxHandler.DeleteCategoryTaxDefault": {summary: "Delete a category tax default", response: MessageResponse{}},
+	"TaxHandler.UpsertStoreTaxProfile":    {summary: "Set the GST registration of a store", body: models.StoreTaxProfile{}, response: models.StoreTaxProfile{}},
+	"TaxHandler.GetStoreTaxProfile":       {summary: "Get the GST registration of a store", response: models.StoreTaxProfile{}},
+
+	// categories and attributes
+	"CategoryHandler.CreateCategory":  {summary: "Create a category", body: models.Category{}, response: models.Category{}, status: http.StatusCreated},
+	"CategoryHandler.GetCategoryByID": {summary: "Get a category", response: models.Category{}},
+	"CategoryHandler.GetCategoryTree": {summary: "Get the category tree of a store", response: []models.Category{}},
+	"CategoryHandler.UpdateCategory":  {summary: "Update a category", body: models.Category{}, response: models.Category{}},
+	"CategoryHandler.DeleteCategory":  {summary: "Delete a category", response: MessageResponse{}},
+	"CategoryHandler.GetProductsByCategory": {
+		summary:     "List the products of a store in a category",
+		description: attributeFilterDescription,
+		response:    []models.Product{},
+		conditional: true,
+	},
+	"AttributeHandler.CreateAttributeDefinition": {summary: "Define an attribute of a category", body: models.AttributeDefinition{}, response: models.AttributeDefinition{}, status: http.StatusCreated},
+	"AttributeHandler.GetAttributeDefinitions":   {summary: "List the attributes of a category", response: []models.AttributeDefinition{}},
+	"AttributeHandler.UpdateAttributeDefinition": {summary: "Update an attribute definition", body: models.AttributeDefinition{}, response: models.AttributeDefinition{}},
+	"AttributeHandler.DeleteAttributeDefinition": {summary: "Delete an attribute definition", response: MessageResponse{}},
+
+	// modifiers and menus
+	"ModifierHandler.CreateModifierGroup":        {summary: "Create a modifier group", body: models.ModifierGroup{}, response: models.ModifierGroup{}, status: http.StatusCreated},
+	"ModifierHandler.GetModifierGroupByID":       {summary: "Get a modifier group", response: models.ModifierGroup{}},
+	"ModifierHandler.GetModifierGroupsByStoreID": {summary: "List the modifier groups of a store", response: []models.ModifierGroup{}},
+	"ModifierHandler.UpdateModifierGroup":        {summary: "Update a modifier group", body: models.ModifierGroup{}, response: models.ModifierGroup{}},
+	"ModifierHandler.DeleteModifierGroup":        {summary: "Delete a modifier group", response: MessageResponse{}},
+	"ModifierHandler.SetProductModifierGroups":   {summary: "Set the modifier groups of a product", body: ProductModifierGroupsRequest{}, response: []models.ModifierGroup{}},
+	"MenuHandler.GetMenu":                        {summary: "Get the menu of a store", response: service.Menu{}, conditional: true},
+
+	// availability
+	"AvailabilityHandler.CreateSchedule":         {summary: "Create an availability schedule", body: models.AvailabilitySchedule{}, response: models.AvailabilitySchedule{}, status: http.StatusCreated},
+	"AvailabilityHandler.GetScheduleByID":        {summary: "Get an availability schedule", response: models.AvailabilitySchedule{}},
+	"AvailabilityHandler.GetSchedulesByStoreID":  {summary: "List the availability schedules of a store", response: []models.AvailabilitySchedule{}},
+	"AvailabilityHandler.UpdateSchedule":         {summary: "Update an availability schedule", body: models.AvailabilitySchedule{}, response: models.AvailabilitySchedule{}},
+	"AvailabilityHandler.DeleteSchedule":         {summary: "Delete an availability schedule", response: MessageResponse{}},
+	"AvailabilityHandler.CreateException":        {summary: "Create an availability exception", body: models.AvailabilityException{}, response: models.AvailabilityException{}, status: http.StatusCreated},
+	"AvailabilityHandler.GetExceptionsByStoreID": {summary: "List the availability exceptions of a store", response: []models.AvailabilityException{}},
+	"AvailabilityHandler.DeleteException":        {summary: "Delete an availability exception", response: MessageResponse{}},
+	"AvailabilityHandler.SetStoreTimezone":       {summary: "Set the timezone of a store", body: models.StoreTimezone{}, response: models.StoreTimezone{}},
+	"AvailabilityHandler.GetStoreTimezone":       {summary: "Get the timezone of a store", response: models.StoreTimezone{}},
+
+	// catalog and offers
+	"CatalogHandler.CreateCatalogProduct":       {summary: "Add a product to the master catalog", body: models.CatalogProduct{}, response: models.CatalogProduct{}, status: http.StatusCreated},
+	"CatalogHandler.GetCatalogProductByID":      {summary: "Get a master catalog entry", response: models.CatalogProduct{}},
+	"CatalogHandler.GetCatalogProductByBarcode": {summary: "Look up a barcode in the master catalog", response: models.CatalogProduct{}},
+	"CatalogHandler.SearchCatalog": {
+		summary: "Search the master catalog by name or brand",
+		query: []param{
+			{name: "q", typ: "string", required: true},
+			{name: "limit", typ: "integer"},
+		},
+		response: []models.CatalogProduct{},
+	},
+	"CatalogHandler.UpdateCatalogProduct":   {summary: "Update a master catalog entry", body: models.CatalogProduct{}, response: models.CatalogProduct{}},
+	"CatalogHandler.DeleteCatalogProduct":   {summary: "Delete a master catalog entry", response: MessageResponse{}},
+	"OfferHandler.GetOffersByBarcode":       {summary: "Compare the offers for a barcode", query: pincodeParams, response: []service.Offer{}},
+	"OfferHandler.GetOffersByMasterProduct": {summary: "Compare the offers for a master catalog entry", query: pincodeParams, response: []service.Offer{}},
+
+	// images
+	"ImageHandler.AddImages": {
+		summary: "Upload images of a product",
+		form: append([]param{
+			{name: "images", typ: "files", required: true},
+			{name: "alt_text", typ: "string"},
+		}, variantParams...),
+		response: []models.ProductImage{},
+	},
+	"ImageHandler.ImportImages": {
+		summary:     "Import images of a product from remote URLs",
+		description: "The images are fetched in the background and answered as pending.",
+		body:        ImageImportRequest{},
+		response:    []models.ProductImage{},
+		status:      http.StatusAccepted,
+	},
+	"ImageHandler.GetProductImages":   {summary: "List the images of a product", response: []models.ProductImage{}},
+	"ImageHandler.UpdateImage":        {summary: "Update the details of an image", body: models.ProductImage{}, response: models.ProductImage{}},
+	"ImageHandler.DeleteImage":        {summary: "Delete an image", response: MessageResponse{}},
+	"ImageHandler.ReorderImages":      {summary: "Reorder the images of a product", body: ImageOrderRequest{}, response: []models.ProductImage{}},
+	"ImageHandler.SetPrimaryImage":    {summary: "Make an image the primary image of its product", response: []models.ProductImage{}},
+	"ImageHandler.RetryProductImages": {summary: "Retry the failed uploads of a product", response: []models.ProductImage{}},
+
+	// geo discovery
+	"GeoHandler.SetStoreLocation":    {summary: "Set the location of a store", body: models.StoreLocation{}, response: models.StoreLocation{}},
+	"GeoHandler.GetStoreLocation":    {summary: "Get the location of a store", response: models.StoreLocation{}},
+	"GeoHandler.DeleteStoreLocation": {summary: "Delete the location of a store", response: MessageResponse{}},
+	"GeoHandler.GetNearbyProducts": {
+		summary:     "List the products of stores nearby",
+		description: "Nearest store first. The cursor of the next page is passed in the X-Next-Cursor header.",
+		query:       nearbyParams,
+		response:    []service.NearbyListing{},
+	},
+	"GeoHandler.GetNearbyPosts": {
+		summary:     "List the posts of stores nearby",
+		description: "Nearest store first. The cursor of the next page is passed in the X-Next-Cursor header.",
+		query:       nearbyParams,
+		response:    []service.NearbyListing{},
+	},
+}
+
+// openAPIDocument is an OpenAPI 3.0 document.
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Tags       []openAPITag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPITag struct {
+	Name string `json:"name"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                     `json:"required,omitempty"`
+	Content  map[string]openAPIMedium `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                    `json:"description"`
+	Headers     map[string]*openAPIHeader `json:"headers,omitempty"`
+	Content     map[string]openAPIMedium  `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMedium struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// openAPISchema is the OpenAPI schema of a value. Component schemas are
+// generated from the Go types of the models, as encoding/json writes them.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+	OneOf                []*openAPISchema          `json:"oneOf,omitempty"`
+}
+
+// OpenAPI serves the OpenAPI document of the routes of router. The document
+// is built on the first request, once every route is registered.
+func OpenAPI(router *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var document *openAPIDocument
+	return func(ctx *gin.Context) {
+		once.Do(func() {
+			routes := router.Routes()
+			for _, route := range UndocumentedRoutes(routes) {
+				log.Printf("route %s has no OpenAPI operation", route)
+			}
+			document = buildOpenAPI(routes)
+		})
+		ctx.JSON(http.StatusOK, document)
+	}
+}
+
+// UndocumentedRoutes lists the routes whose handler has no operation to
+// document it, as "METHOD path (Type.Method)". Plain functions, which serve
+// ping and the documentation itself, need none.
+func UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	undocumented := []string{}
+	for _, route := range routes {
+		typeName, methodName := handlerName(route.Handler)
+		if typeName == "" {
+			continue
+		}
+		if _, ok := operations[typeName+"."+methodName]; !ok {
+			undocumented = append(undocumented, fmt.Sprintf("%s %s (%s.%s)", route.Method, route.Path, typeName, methodName))
+		}
+	}
+	return undocumented
+}
+
+// buildOpenAPI documents routes by the operations of their handlers. Routes
+// of handlers without an operation are listed with their path parameters only.
+func buildOpenAPI(routes gin.RoutesInfo) *openAPIDocument {
+	document := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title: "Product service",
+			Description: "Routes under /v1 answer errors with an ErrorResponse. " +
+				"The unversioned routes answer errors with a LegacyErrorResponse, and prices as numbers of major units.",
+			Version: "1",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+	}
+	schemas := newSchemaBuilder()
+	errorRef := schemas.schema(reflect.TypeOf(ErrorResponse{}))
+	legacyErrorRef := schemas.schema(reflect.TypeOf(LegacyErrorResponse{}))
+	conflictRef := schemas.schema(reflect.TypeOf(ProductConflictResponse{}))
+	legacyConflictRef := schemas.schema(reflect.TypeOf(LegacyProductConflictResponse{}))
+
+	operationIDs := map[string]bool{}
+	tags := map[string]bool{}
+	for _, route := range routes {
+		typeName, methodName := handlerName(route.Handler)
+		if typeName == "" {
+			// plain functions serve ping and the documentation itself
+			continue
+		}
+		doc := operations[typeName+"."+methodName]
+
+		v1 := strings.HasPrefix(route.Path, "/v1/")
+		op := &openAPIOperation{
+			Summary:     doc.summary,
+			Description: doc.description,
+			OperationID: uniqueOperationID(operationIDs, methodName, v1),
+			Responses:   map[string]*openAPIResponse{},
+		}
+		tag := strings.TrimSuffix(typeName, "Handler")
+		if tag == "" {
+			tag = "Product"
+		}
+		op.Tags = []string{tag}
+		tags[tag] = true
+
+		openAPIPath, pathParams := convertPath(route.Path)
+		for _, name := range pathParams {
+			op.Parameters = append(op.Parameters, openAPIParameter{Name: name, In: "path", Required: true, Schema: &openAPISchema{Type: "string"}})
+		}
+		for _, p := range doc.query {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:        p.name,
+				In:          "query",
+				Description: p.description,
+				Required:    p.required,
+				Schema:      paramSchema(p.typ),
+			})
+		}
+
+		switch {
+		case len(doc.form) > 0:
+			form := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+			for _, p := range doc.form {
+				field := paramSchema(p.typ)
+				field.Description = p.description
+				form.Properties[p.name] = field
+				if p.required {
+					form.Required = append(form.Required, p.name)
+				}
+			}
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]openAPIMedium{"multipart/form-data": {Schema: form}},
+			}
+		case doc.body != nil:
+			op.RequestBody = &openAPIRequestBody{
+				Required: !doc.optionalBody,
+				Content:  map[string]openAPIMedium{"application/json": {Schema: schemas.schema(reflect.TypeOf(doc.body))}},
+			}
+		}
+
+		status := doc.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &openAPIResponse{Description: http.StatusText(status)}
+		if doc.response != nil {
+			success.Content = map[string]openAPIMedium{"application/json": {Schema: schemas.schema(reflect.TypeOf(doc.response))}}
+			if doc.csv {
+				success.Content["text/csv"] = openAPIMedium{Schema: &openAPISchema{Type: "string"}}
+			}
+		}
+		op.Responses[fmt.Sprint(status)] = success
+		if doc.conditional {
+			op.Parameters = append(op.Parameters,
+				openAPIParameter{Name: "If-None-Match", In: "header", Schema: &openAPISchema{Type: "string"}},
+				openAPIParameter{Name: "If-Modified-Since", In: "header", Schema: &openAPISchema{Type: "string"}},
+			)
+			success.Headers = map[string]*openAPIHeader{
+				"ETag":          {Description: "catalog state of the store, and version of the product on product reads", Schema: &openAPISchema{Type: "string"}},
+				"Last-Modified": {Description: "omitted for stores whose catalog never changed", Schema: &openAPISchema{Type: "string"}},
+			}
+			op.Responses[fmt.Sprint(http.StatusNotModified)] = &openAPIResponse{Description: http.StatusText(http.StatusNotModified)}
+		}
+		errorSchema, conflictSchema := legacyErrorRef, legacyConflictRef
+		if v1 {
+			errorSchema, conflictSchema = errorRef, conflictRef
+		}
+		if doc.versioned {
+			if doc.body == nil {
+				op.Parameters = append(op.Parameters, openAPIParameter{
+					Name:        "If-Match",
+					In:          "header",
+					Description: `version of the product the write is based on, such as "3", or the ETag of a read of the product`,
+					Schema:      &openAPISchema{Type: "string"},
+				})
+			}
+			op.Responses[fmt.Sprint(http.StatusConflict)] = &openAPIResponse{
+				Description: "the product was changed since the given version",
+				Content:     map[string]openAPIMedium{"application/json": {Schema: conflictSchema}},
+			}
+		}
+		op.Responses["default"] = &openAPIResponse{
+			Description: "Error",
+			Content:     map[string]openAPIMedium{"application/json": {Schema: errorSchema}},
+		}
+
+		if document.Paths[openAPIPath] == nil {
+			document.Paths[openAPIPath] = map[string]*openAPIOperation{}
+		}
+		document.Paths[openAPIPath][strings.ToLower(route.Method)] = op
+	}
+
+	for tag := range tags {
+		document.Tags = append(document.Tags, openAPITag{Name: tag})
+	}
+	sortTags(document.Tags)
+	document.Components.Schemas = schemas.schemas
+	return document
+}
+
+var handlerNamePattern = regexp.MustCompile(`\(\*(\w+)\)\.(\w+)-fm$`)
+
+// handlerName splits the name of a handler method value, such as
+// ".../internal/api.(*Handler).CreateProduct-fm", into its type and method.
+// Handlers that are not methods have no type name.
+func handlerName(name string) (string, string) {
+	if match := handlerNamePattern.FindStringSubmatch(name); match != nil {
+		return match[1], match[2]
+	}
+	return "", strings.Trim(path.Ext(name), ".")
+}
+
+func uniqueOperationID(taken map[string]bool, methodName string, v1 bool) string {
+	id := methodName
+	if v1 {
+		id = "V1" + methodName
+	}
+	unique := id
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", id, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// convertPath turns a gin path into an OpenAPI path template, returning the
+// names of its parameters.
+func convertPath(ginPath string) (string, []string) {
+	segments := strings.Split(ginPath, "/")
+	params := []string{}
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	openAPIPath := strings.Join(segments, "/")
+	if !strings.HasPrefix(openAPIPath, "/") {
+		openAPIPath = "/" + openAPIPath
+	}
+	return openAPIPath, params
+}
+
+func paramSchema(typ string) *openAPISchema {
+	switch typ {
+	case "integer", "number", "boolean":
+		return &openAPISchema{Type: typ}
+	case "date-time":
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case "strings":
+		return &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string"}}
+	case "file":
+		return &openAPISchema{Type: "string", Format: "binary"}
+	case "files":
+		return &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string", Format: "binary"}}
+	case "json":
+		return &openAPISchema{Type: "string", Format: "json"}
+	}
+	return &openAPISchema{Type: "string"}
+}
+
+func sortTags(tags []openAPITag) {
+	for i := 1; i < len(tags); i++ {
+		for j := i; j > 0 && tags[j].Name < tags[j-1].Name; j-- {
+			tags[j], tags[j-1] = tags[j-1], tags[j]
+		}
+	}
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	quantityType    = reflect.TypeOf(models.Quantity(0))
+	moneyType       = reflect.TypeOf(models.Money{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaBuilder generates the component schemas of named struct types.
+type schemaBuilder struct {
+	schemas map[string]*openAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: map[string]*openAPISchema{}, names: map[reflect.Type]string{}}
+}
+
+// schema returns the schema of values of type t, a reference for named
+// structs. Nil pointers, slices and maps encode as null and are nullable.
+func (b *schemaBuilder) schema(t reflect.Type) *openAPISchema {
+	switch t {
+	case timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case quantityType:
+		return &openAPISchema{Type: "number"}
+	case rawMessageType:
+		return &openAPISchema{}
+	case moneyType:
+		// the unversioned routes answer prices as numbers
+		if _, ok := b.names[t]; !ok {
+			b.names[t] = "Money"
+			b.schemas["Money"] = &openAPISchema{
+				Description: "An object on /v1 routes, a number of major units on the unversioned routes.",
+				OneOf:       []*openAPISchema{b.object(t), {Type: "number"}},
+			}
+		}
+		return &openAPISchema{Ref: "#/components/schemas/Money"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(b.schema(t.Elem()))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: b.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &openAPISchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: b.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Implements(jsonMarshalType) {
+			return &openAPISchema{}
+		}
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.schemas[name] = b.object(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	}
+	return &openAPISchema{}
+}
+
+// componentName names the schema of t by its type name, qualified by its
+// package when another package has a type of the same name.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	for other, taken := range b.names {
+		if taken == name && other != t {
+			pkg := path.Base(t.PkgPath())
+			return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	return name
+}
+
+// object lists the properties of a struct as encoding/json writes them:
+// fields of embedded structs are promoted unless a shallower field has the
+// same name, and fields without omitempty are always present.
+func (b *schemaBuilder) object(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	for _, field := range jsonFields(t) {
+		schema.Properties[field.name] = b.schema(field.typ)
+		if !field.omitEmpty {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitEmpty bool
+	depth     int
+	tagged    bool
+}
+
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	collectJSONFields(t, 0, &fields)
+
+	// the shallowest field of a name wins, a tagged one among equals, and
+	// names that stay ambiguous are left out
+	byName := map[string][]jsonField{}
+	order := []string{}
+	for _, field := range fields {
+		if _, seen := byName[field.name]; !seen {
+			order = append(order, field.name)
+		}
+		byName[field.name] = append(byName[field.name], field)
+	}
+
+	result := []jsonField{}
+	for _, name := range order {
+		candidates := byName[name]
+		depth := candidates[0].depth
+		for _, candidate := range candidates {
+			if candidate.depth < depth {
+				depth = candidate.depth
+			}
+		}
+		var shallowest, tagged []jsonField
+		for _, candidate := range candidates {
+			if candidate.depth == depth {
+				shallowest = append(shallowest, candidate)
+				if candidate.tagged {
+					tagged = append(tagged, candidate)
+				}
+			}
+		}
+		switch {
+		case len(shallowest) == 1:
+			result = append(result, shallowest[0])
+		case len(tagged) == 1:
+			result = append(result, tagged[0])
+		}
+	}
+	return result
+}
+
+func collectJSONFields(t reflect.Type, depth int, fields *[]jsonField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectJSONFields(embedded, depth+1, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*fields = append(*fields, jsonField{
+			name:      name,
+			typ:       field.Type,
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+			depth:     depth,
+			tagged:    tagged,
+		})
+	}
+}
+
+func nullable(schema *openAPISchema) *openAPISchema {
+	if schema.Ref != "" {
+		return &openAPISchema{AllOf: []*openAPISchema{schema}, Nullable: true}
+	}
+	copied := *schema
+	copied.Nullable = true
+	return &copied
+}
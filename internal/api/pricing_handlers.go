@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type PriceRuleHandler struct {
+	PriceRuleService service.PriceRuleService
+}
+
+func NewPriceRuleHandler(PriceRuleService *service.PriceRuleService) *PriceRuleHandler {
+	return &PriceRuleHandler{PriceRuleService: *PriceRuleService}
+}
+
+func (h *PriceRuleHandler) CreatePriceRule(ctx *gin.Context) {
+	var rule models.PriceRule
+
+	err := ctx.ShouldBindJSON(&rule)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdRule, err := h.PriceRuleService.CreatePriceRule(ctx, &rule)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdRule)
+}
+
+func (h *PriceRuleHandler) GetPriceRuleByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	rule, err := h.PriceRuleService.GetPriceRuleByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rule)
+}
+
+func (h *PriceRuleHandler) GetPriceRulesByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	rules, err := h.PriceRuleService.GetPriceRulesByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rules)
+}
+
+func (h *PriceRuleHandler) UpdatePriceRule(ctx *gin.Context) {
+	var rule models.PriceRule
+
+	err := ctx.ShouldBindJSON(&rule)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	rule.ID = ctx.Param("id")
+
+	updatedRule, err := h.PriceRuleService.UpdatePriceRule(ctx, &rule)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedRule)
+}
+
+func (h *PriceRuleHandler) DeletePriceRule(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.PriceRuleService.DeletePriceRule(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Price rule deleted"})
+}
+
+func (h *PriceRuleHandler) GetPriceHistoryByProductID(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+
+	history, err := h.PriceRuleService.GetPriceHistoryByProductID(ctx, productID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// GetProductPrice resolves the effective price now, or at the RFC 3339 time given in the at query parameter.
+func (h *PriceRuleHandler) GetProductPrice(ctx *gin.Context) {
+	productID := ctx.Param("product_id")
+	variantType := ctx.Query("variant_type")
+	variantID := utils.StringToInt(ctx.Query("variant_id"))
+
+	at := time.Now()
+	if ctx.Query("at") != "" {
+		parsed, err := time.Parse(time.RFC3339, ctx.Query("at"))
+		if err != nil {
+			respondBadRequest(ctx, err)
+			return
+		}
+		at = parsed
+	}
+
+	price, err := h.PriceRuleService.GetProductPrice(ctx, productID, variantType, variantID, at)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, price)
+}
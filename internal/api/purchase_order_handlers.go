@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type PurchaseOrderHandler struct {
+	PurchaseOrderService service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(PurchaseOrderService *service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{PurchaseOrderService: *PurchaseOrderService}
+}
+
+type PurchaseOrderStatusUpdate struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type PurchaseOrderFromReplenishmentRequest struct {
+	StoreID    string `json:"store_id" binding:"required"`
+	SupplierID string `json:"supplier_id"`
+	Days       int    `json:"days"`
+}
+
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(ctx *gin.Context) {
+	var order models.PurchaseOrder
+
+	err := ctx.ShouldBindJSON(&order)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdOrder, err := h.PurchaseOrderService.CreatePurchaseOrder(ctx, &order)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdOrder)
+}
+
+func (h *PurchaseOrderHandler) CreatePurchaseOrderFromReplenishment(ctx *gin.Context) {
+	var req PurchaseOrderFromReplenishmentRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdOrder, err := h.PurchaseOrderService.CreatePurchaseOrderFromReplenishment(ctx, req.StoreID, req.SupplierID, req.Days)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdOrder)
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrderByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	order, err := h.PurchaseOrderService.GetPurchaseOrderByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrdersByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	orders, err := h.PurchaseOrderService.GetPurchaseOrdersByStoreID(ctx, storeID, ctx.Query("status"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, orders)
+}
+
+func (h *PurchaseOrderHandler) UpdatePurchaseOrder(ctx *gin.Context) {
+	var order models.PurchaseOrder
+
+	err := ctx.ShouldBindJSON(&order)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	order.ID = ctx.Param("id")
+
+	updatedOrder, err := h.PurchaseOrderService.UpdatePurchaseOrder(ctx, &order)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedOrder)
+}
+
+func (h *PurchaseOrderHandler) UpdatePurchaseOrderStatus(ctx *gin.Context) {
+	var req PurchaseOrderStatusUpdate
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	updatedOrder, err := h.PurchaseOrderService.UpdatePurchaseOrderStatus(ctx, ctx.Param("id"), req.Status)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedOrder)
+}
+
+func (h *PurchaseOrderHandler) ReceiveGoods(ctx *gin.Context) {
+	var receipt service.GoodsReceipt
+
+	// an empty body receives everything still outstanding
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&receipt); err != nil {
+			respondBadRequest(ctx, err)
+			return
+		}
+	}
+
+	updatedOrder, err := h.PurchaseOrderService.ReceiveGoods(ctx, ctx.Param("id"), receipt)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedOrder)
+}
+
+func (h *PurchaseOrderHandler) DeletePurchaseOrder(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.PurchaseOrderService.DeletePurchaseOrder(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Purchase order deleted"})
+}
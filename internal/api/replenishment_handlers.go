@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type ReplenishmentHandler struct {
+	ReplenishmentService service.ReplenishmentService
+}
+
+func NewReplenishmentHandler(ReplenishmentService *service.ReplenishmentService) *ReplenishmentHandler {
+	return &ReplenishmentHandler{ReplenishmentService: *ReplenishmentService}
+}
+
+func (h *ReplenishmentHandler) UpsertReplenishmentSetting(ctx *gin.Context) {
+	var setting models.ReplenishmentSetting
+
+	err := ctx.ShouldBindJSON(&setting)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	savedSetting, err := h.ReplenishmentService.UpsertSetting(ctx, &setting)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, savedSetting)
+}
+
+func (h *ReplenishmentHandler) GetReplenishmentSettingsByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	settings, err := h.ReplenishmentService.GetSettingsByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, settings)
+}
+
+func (h *ReplenishmentHandler) GetReorderSuggestions(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+	days := utils.StringToInt(ctx.Query("days"))
+	includeAll := ctx.Query("all") == "true"
+
+	suggestions, err := h.ReplenishmentService.GetReorderSuggestions(ctx, storeID, days, includeAll)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suggestions)
+}
+
+// GetDraftPurchaseOrder returns the draft as JSON, or as a CSV attachment when called with format=csv.
+func (h *ReplenishmentHandler) GetDraftPurchaseOrder(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+	days := utils.StringToInt(ctx.Query("days"))
+
+	draft, err := h.ReplenishmentService.GetDraftPurchaseOrder(ctx, storeID, days)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	if ctx.Query("format") != "csv" {
+		ctx.JSON(http.StatusOK, draft)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=draft_po_%s.csv", storeID))
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Status(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write([]string{"product_id", "product_name", "variant_type", "variant_id", "variant", "quantity", "quantity_unit", "unit_cost", "line_total"})
+	for _, line := range draft.Lines {
+		variantID := ""
+		if line.VariantID != 0 {
+			variantID = strconv.Itoa(line.VariantID)
+		}
+		writer.Write([]string{
+			line.ProductID,
+			line.ProductName,
+			line.VariantType,
+			variantID,
+			line.VariantLabel,
+			line.Quantity.String(),
+			string(line.QuantityUnit),
+			line.UnitCost.String(),
+			line.LineTotal.String(),
+		})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+)
+
+type SupplierHandler struct {
+	SupplierService service.SupplierService
+}
+
+func NewSupplierHandler(SupplierService *service.SupplierService) *SupplierHandler {
+	return &SupplierHandler{SupplierService: *SupplierService}
+}
+
+func (h *SupplierHandler) CreateSupplier(ctx *gin.Context) {
+	var supplier models.Supplier
+
+	err := ctx.ShouldBindJSON(&supplier)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	createdSupplier, err := h.SupplierService.CreateSupplier(ctx, &supplier)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdSupplier)
+}
+
+func (h *SupplierHandler) GetSupplierByID(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	supplier, err := h.SupplierService.GetSupplierByID(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, supplier)
+}
+
+func (h *SupplierHandler) GetSuppliersByStoreID(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	suppliers, err := h.SupplierService.GetSuppliersByStoreID(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suppliers)
+}
+
+func (h *SupplierHandler) UpdateSupplier(ctx *gin.Context) {
+	var supplier models.Supplier
+
+	err := ctx.ShouldBindJSON(&supplier)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	supplier.ID = ctx.Param("id")
+
+	updatedSupplier, err := h.SupplierService.UpdateSupplier(ctx, &supplier)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedSupplier)
+}
+
+func (h *SupplierHandler) DeleteSupplier(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	err := h.SupplierService.DeleteSupplier(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Supplier deleted"})
+}
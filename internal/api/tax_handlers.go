@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/service"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+type TaxHandler struct {
+	TaxService service.TaxService
+}
+
+func NewTaxHandler(TaxService *service.TaxService) *TaxHandler {
+	return &TaxHandler{TaxService: *TaxService}
+}
+
+func (h *TaxHandler) GetProductTax(ctx *gin.Context) {
+	req := service.TaxRequest{
+		ProductID:        ctx.Param("product_id"),
+		VariantType:      ctx.Query("variant_type"),
+		VariantID:        utils.StringToInt(ctx.Query("variant_id")),
+		Quantity:         utils.StringToQuantity(ctx.Query("quantity")),
+		OriginState:      ctx.Query("origin_state"),
+		DestinationState: ctx.Query("destination_state"),
+	}
+
+	breakdown, err := h.TaxService.GetProductTax(ctx, req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, breakdown)
+}
+
+func (h *TaxHandler) UpsertCategoryTaxDefault(ctx *gin.Context) {
+	var taxDefault models.CategoryTaxDefault
+
+	err := ctx.ShouldBindJSON(&taxDefault)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+
+	savedDefault, err := h.TaxService.UpsertCategoryTaxDefault(ctx, &taxDefault)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, savedDefault)
+}
+
+func (h *TaxHandler) GetCategoryTaxDefaults(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	defaults, err := h.TaxService.GetCategoryTaxDefaults(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, defaults)
+}
+
+func (h *TaxHandler) DeleteCategoryTaxDefault(ctx *gin.Context) {
+	id := utils.StringToInt(ctx.Param("id"))
+
+	err := h.TaxService.DeleteCategoryTaxDefault(ctx, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Category tax default deleted"})
+}
+
+func (h *TaxHandler) UpsertStoreTaxProfile(ctx *gin.Context) {
+	var profile models.StoreTaxProfile
+
+	err := ctx.ShouldBindJSON(&profile)
+	if err != nil {
+		respondBadRequest(ctx, err)
+		return
+	}
+	profile.StoreID = ctx.Param("store_id")
+
+	savedProfile, err := h.TaxService.UpsertStoreTaxProfile(ctx, &profile)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, savedProfile)
+}
+
+func (h *TaxHandler) GetStoreTaxProfile(ctx *gin.Context) {
+	storeID := ctx.Param("store_id")
+
+	profile, err := h.TaxService.GetStoreTaxProfile(ctx, storeID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GTINLength is the length barcodes are stored in, so that the UPC-A, EAN-13
+// and GTIN-14 forms of the same code match.
+const GTINLength = 14
+
+// NormalizeGTIN validates a GTIN-8, UPC-A, EAN-13 or GTIN-14 barcode and
+// returns it zero padded to GTINLength digits. Spaces and dashes are ignored.
+func NormalizeGTIN(barcode string) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(barcode))
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("barcode %q must have 8, 12, 13 or 14 digits", barcode)
+	}
+
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		c := digits[len(digits)-1-i]
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("barcode %q must only contain digits", barcode)
+		}
+		if i == 0 {
+			continue
+		}
+		// weights alternate 3, 1, ... starting next to the check digit
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(c-'0') * weight
+	}
+	if check := (10 - sum%10) % 10; int(digits[len(digits)-1]-'0') != check {
+		return "", fmt.Errorf("barcode %q has an invalid check digit", barcode)
+	}
+
+	return strings.Repeat("0", GTINLength-len(digits)) + digits, nil
+}
+
+// GTINForms returns the forms a normalized GTIN may have been entered in:
+// the GTIN-14 itself and its GTIN-13, UPC-A and GTIN-8 forms where the
+// dropped digits are leading zeros.
+func GTINForms(gtin string) []string {
+	forms := []string{gtin}
+	for _, length := range []int{13, 12, 8} {
+		if len(gtin) < length {
+			continue
+		}
+		cut := len(gtin) - length
+		if strings.Trim(gtin[:cut], "0") == "" {
+			forms = append(forms, gtin[cut:])
+		}
+	}
+	return forms
+}
@@ -1,37 +1,81 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Product struct {
-	ID              string         `json:"id" gorm:"primaryKey`
-	StoreID         string         `json:"store_id" gorm:"size:36;not null"`
-	CreatedAt       time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	Name            string         `json:"name" gorm:"not null"`
-	Description     string         `json:"description" gorm:"type:text"`
-	QuantityUnit    string         `json:"quantity_unit" gorm:"default:'Piece';not null"`
-	MRP             int            `json:"mrp" gorm:"not null"`
-	DiscountPrice   int            `json:"discount_price" gorm:"default:0"`
-	Images          []ProductImage `json:"images"`
-	Brand           string         `json:"brand"`
-	Barcode         string         `json:"barcode" gorm:"index;size:36"`
-	Category        string         `json:"category"`
-	DisplayOrder    int            `json:"display_order" gorm:"default:0"`
-	SizeVariants    []SizeVariant  `json:"size_variants" `
-	ColorVariants   []ColorVariant `json:"color_variants"`
-	Type            string         `json:"type,omitempty"`
-	MetaDescription string         `json:"meta_description,omitempty"`
-	MetaTags        string         `json:"meta_tags,omitempty"`
-	VegType         string         `json:"veg_type,omitempty"`
-	Servers         int            `json:"servers,omitempty"`
-	OutOfStock      bool           `json:"out_of_stock" gorm:"default:false"`
+	ID              string             `json:"id" gorm:"primaryKey"`
+	StoreID         string             `json:"store_id" gorm:"size:36;not null;index"`
+	CreatedAt       time.Time          `json:"created_at" gorm:"autoCreateTime"`
+	Name            string             `json:"name" gorm:"not null"`
+	Description     string             `json:"description" gorm:"type:text"`
+	QuantityUnit    Unit               `json:"quantity_unit" gorm:"default:'piece';not null"`
+	MRP             Money              `json:"mrp" gorm:"embedded;embeddedPrefix:mrp_"`
+	DiscountPrice   Money              `json:"discount_price" gorm:"embedded;embeddedPrefix:discount_price_"`
+	Images          []ProductImage     `json:"images"`
+	Brand           string             `json:"brand"`
+	Barcode         string             `json:"barcode" gorm:"index;size:36"`
+	MasterProductID string             `json:"master_product_id,omitempty" gorm:"size:36;index"`
+	Category        string             `json:"category"`
+	CategoryID      string             `json:"category_id,omitempty" gorm:"size:36;index"`
+	Attributes      []ProductAttribute `json:"attributes,omitempty"`
+	DisplayOrder    int                `json:"display_order" gorm:"default:0"`
+	SizeVariants    []SizeVariant      `json:"size_variants" `
+	ColorVariants   []ColorVariant     `json:"color_variants"`
+	Type            string             `json:"type,omitempty"`
+	MetaDescription string             `json:"meta_description,omitempty"`
+	MetaTags        string             `json:"meta_tags,omitempty"`
+	VegType         string             `json:"veg_type,omitempty"`
+	Servers         int                `json:"servers,omitempty"`
+	OutOfStock      bool               `json:"out_of_stock" gorm:"default:false"`
+
+	// Version counts the writes to the product. Writes given the version
+	// they were based on are refused once it is no longer current, so that
+	// concurrent edits don't overwrite each other.
+	Version int64 `json:"version" gorm:"not null;default:1"`
+
+	// ExpiresAt takes a post off the feed once passed. Pinned posts lead the
+	// feed of their pincode.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	Pinned    bool       `json:"pinned" gorm:"default:false"`
+
+	HSNCode  string `json:"hsn_code,omitempty" gorm:"size:16"`
+	TaxClass string `json:"tax_class,omitempty" gorm:"size:16"`
+
+	// TaxInclusive tells whether the prices of the product include GST. When
+	// unset, the default of the product's category applies, and prices are
+	// tax inclusive if the category has no default either.
+	TaxInclusive *bool `json:"tax_inclusive,omitempty"`
 	ProductPrivate
+
+	// ModifierGroups are the add-on and choice groups offered with the
+	// product. ComboItems make the product a combo of other products, or the
+	// components of a product of type bundle.
+	ModifierGroups []ModifierGroup `json:"modifier_groups,omitempty" gorm:"many2many:product_modifier_groups"`
+	ComboItems     []ComboItem     `json:"combo_items,omitempty" gorm:"foreignKey:ComboProductID"`
+
+	// EffectivePrice is the price after the price rules active at read time,
+	// AppliedPriceRuleID the rule that produced it.
+	EffectivePrice     Money  `json:"effective_price" gorm:"-"`
+	AppliedPriceRuleID string `json:"applied_price_rule_id,omitempty" gorm:"-"`
+
+	// Available tells whether the product can be ordered at read time, given
+	// its stock and availability schedules. UnavailableReason says why not.
+	Available         bool   `json:"available" gorm:"-"`
+	UnavailableReason string `json:"unavailable_reason,omitempty" gorm:"-"`
 }
 
 type InventoryTransaction struct {
-	ID        string `json:"id" gorm:"primaryKey"`
-	ProductID string `json:"product_id" gorm:"size:36;index"`
-	Quantity  int    `json:"quantity" gorm:"not null"`
-	Price     int    `json:"price" gorm:"not null"`
+	ID        string   `json:"id" gorm:"primaryKey"`
+	ProductID string   `json:"product_id" gorm:"size:36;index"`
+	Quantity  Quantity `json:"quantity" gorm:"not null"`
+	Price     Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+
+	// Unit is the unit Quantity is expressed in. Transactions recorded in
+	// another unit of the same dimension are converted to the product's unit.
+	Unit Unit `json:"unit,omitempty" gorm:"size:16"`
 
 	// TransactionType can be one of the following:
 	// 1. INVENTORY_ADJUSTMENT
@@ -41,34 +85,512 @@ type InventoryTransaction struct {
 	TransactionType string    `json:"transaction_type" gorm:"not null"`
 	Description     string    `json:"description" gorm:"type:text"`
 	CreatedAt       time.Time `json:"created_at"`
+
+	// VariantType and VariantID are set when the transaction moves stock of a
+	// single size or color variant rather than the product as a whole.
+	VariantType string `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID   int    `json:"variant_id,omitempty"`
+
+	// ReferenceID links the transaction to the document that caused it, such as a purchase order.
+	ReferenceID string `json:"reference_id,omitempty" gorm:"size:36;index"`
+
+	// Components are the transactions a sale or return of a bundle wrote
+	// against the components of the bundle.
+	Components []InventoryTransaction `json:"components,omitempty" gorm:"-"`
 }
 
+const (
+	TransactionTypeInventoryAdjustment = "INVENTORY_ADJUSTMENT"
+	TransactionTypePurchase            = "PURCHASE"
+	TransactionTypeSale                = "SALE"
+	TransactionTypeReturn              = "RETURN"
+)
+
+const (
+	VariantTypeSize  = "size"
+	VariantTypeColor = "color"
+)
+
 type ProductPrivate struct {
-	MSRP                  int                    `json:"msrp,omitempty"`
-	Quantity              int                    `json:"quantity,omitempty"`
-	CriticalQuantity      int                    `json:"critical_quantity,omitempty"`
+	MSRP                  Money                  `json:"msrp" gorm:"embedded;embeddedPrefix:msrp_"`
+	Quantity              Quantity               `json:"quantity,omitempty"`
+	CriticalQuantity      Quantity               `json:"critical_quantity,omitempty"`
 	CustomCode            string                 `json:"custom_code,omitempty"`
 	InventoryTransactions []InventoryTransaction `json:"inventory_transactions,omitempty"`
 }
 
 type SizeVariant struct {
-	ID        int    `json:"id" gorm:"primaryKey;autoIncrement"`
-	ProductID string `json:"product_id" gorm:"size:36;index"`
-	Size      string `json:"size" gorm:"not null"`
-	Price     int    `json:"price" gorm:"not null"`
-	Quantity  int    `json:"quantity" gorm:"not null"`
+	ID        int      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID string   `json:"product_id" gorm:"size:36;index"`
+	Size      string   `json:"size" gorm:"not null"`
+	Price     Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity  Quantity `json:"quantity" gorm:"not null"`
+
+	EffectivePrice     Money  `json:"effective_price" gorm:"-"`
+	AppliedPriceRuleID string `json:"applied_price_rule_id,omitempty" gorm:"-"`
 }
 
 type ColorVariant struct {
-	ID        int    `json:"id" gorm:"primaryKey;autoIncrement"`
-	ProductID string `json:"product_id" gorm:"size:36;index"`
-	Color     string `json:"color" gorm:"not null"`
-	Price     int    `json:"price" gorm:"not null"`
-	Quantity  int    `json:"quantity" gorm:"not null"`
+	ID        int      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID string   `json:"product_id" gorm:"size:36;index"`
+	Color     string   `json:"color" gorm:"not null"`
+	Price     Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity  Quantity `json:"quantity" gorm:"not null"`
+
+	EffectivePrice     Money  `json:"effective_price" gorm:"-"`
+	AppliedPriceRuleID string `json:"applied_price_rule_id,omitempty" gorm:"-"`
 }
 
+// ProductImage is an image of a product, with medium and thumbnail sized
+// renditions. Image is empty until the upload to the image service succeeds;
+// Status tells whether it is ready, waiting for an upload or failed to
+// upload, with the reason in Error. SourceURL is set on images imported from
+// a remote URL.
+//
+// The primary image is the cover of the product and comes first, followed by
+// the others in display order. An image with a VariantType shows that variant.
 type ProductImage struct {
-	ID        int    `json:"id" gorm:"primaryKey;autoIncrement"`
-	ProductID string `json:"product_id" gorm:"size:36;index"`
-	Image     string `json:"image" gorm:"type:text"`
+	ID           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID    string `json:"product_id" gorm:"size:36;index"`
+	Image        string `json:"image" gorm:"type:text"`
+	MediumURL    string `json:"medium_url,omitempty" gorm:"type:text"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" gorm:"type:text"`
+	SourceURL    string `json:"source_url,omitempty" gorm:"type:text"`
+	Status       string `json:"status" gorm:"size:16;default:'ready'"`
+	Error        string `json:"error,omitempty" gorm:"type:text"`
+	DisplayOrder int    `json:"display_order" gorm:"default:0"`
+	IsPrimary    bool   `json:"is_primary" gorm:"default:false"`
+	AltText      string `json:"alt_text,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	VariantType  string `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID    int    `json:"variant_id,omitempty"`
+}
+
+const (
+	ImageStatusPending = "pending"
+	ImageStatusReady   = "ready"
+	ImageStatusFailed  = "failed"
+)
+
+// ImageUpload keeps the data of a product image whose upload failed, as
+// uploaded by the owner, so that it can be retried. It is removed once the
+// upload succeeds. An image imported from a remote URL keeps its SourceURL
+// instead, and is fetched again on every attempt.
+type ImageUpload struct {
+	ImageID   int       `json:"image_id" gorm:"primaryKey;autoIncrement:false"`
+	ProductID string    `json:"product_id" gorm:"size:36;index"`
+	Data      []byte    `json:"-"`
+	SourceURL string    `json:"source_url,omitempty" gorm:"type:text"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TrackedImage is an image uploaded to the image service, tracked so that it
+// is deleted there once no product refers to it. An image is unreferenced from
+// its upload until a product is seen to refer to it, and again from when it is
+// detached; once UnreferencedAt is older than a grace period, it is deleted.
+// A delete that fails is tried again from NextDeleteAt.
+type TrackedImage struct {
+	URL            string     `json:"url" gorm:"primaryKey;size:512"`
+	UnreferencedAt *time.Time `json:"unreferenced_at,omitempty" gorm:"index"`
+	DeleteAttempts int        `json:"delete_attempts"`
+	NextDeleteAt   *time.Time `json:"next_delete_at,omitempty" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ReplenishmentSetting holds the reorder parameters of a product, or of one of
+// its variants when VariantType is set.
+type ReplenishmentSetting struct {
+	ID               int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	StoreID          string    `json:"store_id" gorm:"size:36;index"`
+	ProductID        string    `json:"product_id" gorm:"size:36;uniqueIndex:idx_replenishment_target"`
+	VariantType      string    `json:"variant_type,omitempty" gorm:"size:16;uniqueIndex:idx_replenishment_target"`
+	VariantID        int       `json:"variant_id,omitempty" gorm:"uniqueIndex:idx_replenishment_target"`
+	LeadTimeDays     int       `json:"lead_time_days"`
+	SafetyStockDays  int       `json:"safety_stock_days"`
+	ReviewPeriodDays int       `json:"review_period_days"`
+	MinOrderQuantity Quantity  `json:"min_order_quantity"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type Supplier struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	StoreID     string    `json:"store_id" gorm:"size:36;index;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	ContactName string    `json:"contact_name"`
+	Phone       string    `json:"phone"`
+	Email       string    `json:"email"`
+	Address     string    `json:"address" gorm:"type:text"`
+	GSTIN       string    `json:"gstin"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type PurchaseOrder struct {
+	ID         string `json:"id" gorm:"primaryKey"`
+	StoreID    string `json:"store_id" gorm:"size:36;index;not null"`
+	SupplierID string `json:"supplier_id" gorm:"size:36;index"`
+
+	// Status can be one of the following:
+	// 1. DRAFT
+	// 2. ORDERED
+	// 3. PARTIALLY_RECEIVED
+	// 4. RECEIVED
+	// 5. CANCELLED
+	Status     string              `json:"status" gorm:"size:32;not null"`
+	Notes      string              `json:"notes" gorm:"type:text"`
+	Lines      []PurchaseOrderLine `json:"lines"`
+	ExpectedAt *time.Time          `json:"expected_at,omitempty"`
+	OrderedAt  *time.Time          `json:"ordered_at,omitempty"`
+	ReceivedAt *time.Time          `json:"received_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+const (
+	PurchaseOrderStatusDraft             = "DRAFT"
+	PurchaseOrderStatusOrdered           = "ORDERED"
+	PurchaseOrderStatusPartiallyReceived = "PARTIALLY_RECEIVED"
+	PurchaseOrderStatusReceived          = "RECEIVED"
+	PurchaseOrderStatusCancelled         = "CANCELLED"
+)
+
+type PurchaseOrderLine struct {
+	ID               int      `json:"id" gorm:"primaryKey;autoIncrement"`
+	PurchaseOrderID  string   `json:"purchase_order_id" gorm:"size:36;index"`
+	ProductID        string   `json:"product_id" gorm:"size:36;index;not null"`
+	VariantType      string   `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID        int      `json:"variant_id,omitempty"`
+	Quantity         Quantity `json:"quantity" gorm:"not null"`
+	ReceivedQuantity Quantity `json:"received_quantity" gorm:"default:0"`
+	Cost             Money    `json:"cost" gorm:"embedded;embeddedPrefix:cost_"`
+}
+
+type PriceRule struct {
+	ID      string `json:"id" gorm:"primaryKey"`
+	StoreID string `json:"store_id" gorm:"size:36;index;not null"`
+	Name    string `json:"name"`
+
+	// Scope can be one of the following:
+	// 1. PRODUCT - ProductID, including all of its variants
+	// 2. VARIANT - the VariantType/VariantID variant of ProductID
+	// 3. CATEGORY - every product of the store in Category
+	// 4. STORE - every product of the store
+	Scope       string `json:"scope" gorm:"size:16;not null"`
+	ProductID   string `json:"product_id,omitempty" gorm:"size:36;index"`
+	VariantType string `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID   int    `json:"variant_id,omitempty"`
+	Category    string `json:"category,omitempty"`
+
+	// DiscountType is PERCENTAGE, where Value is the percentage taken off the
+	// price, or FIXED, where Value is the amount taken off the price in minor
+	// units of the product's currency.
+	DiscountType string     `json:"discount_type" gorm:"size:16;not null"`
+	Value        int64      `json:"value" gorm:"not null"`
+	StartsAt     time.Time  `json:"starts_at" gorm:"index"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" gorm:"index"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+const (
+	PriceRuleScopeProduct  = "PRODUCT"
+	PriceRuleScopeVariant  = "VARIANT"
+	PriceRuleScopeCategory = "CATEGORY"
+	PriceRuleScopeStore    = "STORE"
+
+	DiscountTypePercentage = "PERCENTAGE"
+	DiscountTypeFixed      = "FIXED"
+)
+
+// PriceHistory records the list prices of a product, or of one of its
+// variants when VariantType is set, from ChangedAt onwards.
+type PriceHistory struct {
+	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID     string    `json:"product_id" gorm:"size:36;index"`
+	StoreID       string    `json:"store_id" gorm:"size:36;index"`
+	VariantType   string    `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID     int       `json:"variant_id,omitempty"`
+	MRP           Money     `json:"mrp" gorm:"embedded;embeddedPrefix:mrp_"`
+	DiscountPrice Money     `json:"discount_price" gorm:"embedded;embeddedPrefix:discount_price_"`
+	Price         Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	ChangedAt     time.Time `json:"changed_at" gorm:"index"`
+}
+
+const (
+	TaxClassExempt = "EXEMPT"
+	TaxClassGST0   = "GST_0"
+	TaxClassGST3   = "GST_3"
+	TaxClassGST5   = "GST_5"
+	TaxClassGST12  = "GST_12"
+	TaxClassGST18  = "GST_18"
+	TaxClassGST28  = "GST_28"
+)
+
+// Category is a node of the category tree. Categories without a StoreID form
+// the global taxonomy shared by every store, the others are custom
+// categories of one store. Root categories have no ParentID.
+type Category struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:36"`
+	StoreID      string     `json:"store_id,omitempty" gorm:"size:36;uniqueIndex:idx_category_slug"`
+	ParentID     string     `json:"parent_id,omitempty" gorm:"size:36;index;uniqueIndex:idx_category_slug"`
+	Name         string     `json:"name" gorm:"not null"`
+	Slug         string     `json:"slug" gorm:"size:191;not null;uniqueIndex:idx_category_slug"`
+	Icon         string     `json:"icon,omitempty"`
+	DisplayOrder int        `json:"display_order" gorm:"default:0"`
+	Children     []Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AttributeDefinition declares a custom attribute of the products of a
+// category, such as fabric for clothing. Subcategories inherit the
+// attributes of their ancestors.
+type AttributeDefinition struct {
+	ID         int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CategoryID string `json:"category_id" gorm:"size:36;not null;uniqueIndex:idx_attribute_key"`
+	Key        string `json:"key" gorm:"size:64;not null;uniqueIndex:idx_attribute_key"`
+	Name       string `json:"name" gorm:"not null"`
+
+	// Type is TEXT, NUMBER, ENUM or BOOLEAN. ENUM values must be one of Options.
+	Type         string    `json:"type" gorm:"size:16;not null"`
+	Options      []string  `json:"options,omitempty" gorm:"serializer:json"`
+	Required     bool      `json:"required" gorm:"default:false"`
+	DisplayOrder int       `json:"display_order" gorm:"default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	AttributeTypeText    = "TEXT"
+	AttributeTypeNumber  = "NUMBER"
+	AttributeTypeEnum    = "ENUM"
+	AttributeTypeBoolean = "BOOLEAN"
+)
+
+// ProductAttribute is the value of a custom attribute of a product. Value
+// holds the normalized text of every type, NumberValue the parsed value of
+// NUMBER attributes for range filters.
+type ProductAttribute struct {
+	ID          int      `json:"-" gorm:"primaryKey;autoIncrement"`
+	ProductID   string   `json:"-" gorm:"size:36;index"`
+	AttributeID int      `json:"-" gorm:"index"`
+	Key         string   `json:"key" gorm:"size:64;not null"`
+	Value       string   `json:"value" gorm:"type:text"`
+	NumberValue *float64 `json:"-"`
+}
+
+// UnmarshalJSON accepts the value as a JSON string, number or boolean.
+func (a *ProductAttribute) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	a.Key = decoded.Key
+	a.Value = ""
+	if len(decoded.Value) == 0 || string(decoded.Value) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(decoded.Value, &a.Value); err != nil {
+		// numbers and booleans are kept in their JSON text form
+		a.Value = string(decoded.Value)
+	}
+	return nil
+}
+
+// ModifierGroup is a set of options a customer picks from when ordering a
+// product, such as "Choose your bread" or "Add-ons". A customer picks at
+// least MinSelections and, unless MaxSelections is 0, at most MaxSelections
+// options. Groups belong to a store and can be shared by several products.
+type ModifierGroup struct {
+	ID            string           `json:"id" gorm:"primaryKey;size:36"`
+	StoreID       string           `json:"store_id" gorm:"size:36;index;not null"`
+	Name          string           `json:"name" gorm:"not null"`
+	MinSelections int              `json:"min_selections" gorm:"default:0"`
+	MaxSelections int              `json:"max_selections" gorm:"default:0"`
+	Required      bool             `json:"required" gorm:"default:false"`
+	DisplayOrder  int              `json:"display_order" gorm:"default:0"`
+	Options       []ModifierOption `json:"options"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// ModifierOption is a choice of a ModifierGroup. Price is added to the price
+// of the product when the option is picked.
+type ModifierOption struct {
+	ID              int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ModifierGroupID string `json:"modifier_group_id" gorm:"size:36;index"`
+	Name            string `json:"name" gorm:"not null"`
+	Price           Money  `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	VegType         string `json:"veg_type,omitempty"`
+	SoldOut         bool   `json:"sold_out" gorm:"default:false"`
+	DisplayOrder    int    `json:"display_order" gorm:"default:0"`
+}
+
+// ComboItem is a component of a combo product, such as the dal of a thali,
+// or of a bundle, such as the jar of honey in a gift hamper. Quantity is in
+// the unit of the component.
+type ComboItem struct {
+	ID             int      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ComboProductID string   `json:"combo_product_id" gorm:"size:36;index"`
+	ProductID      string   `json:"product_id" gorm:"size:36;not null"`
+	VariantType    string   `json:"variant_type,omitempty" gorm:"size:16"`
+	VariantID      int      `json:"variant_id,omitempty"`
+	Quantity       Quantity `json:"quantity" gorm:"not null"`
+	ProductName    string   `json:"product_name,omitempty" gorm:"-"`
+}
+
+// ProductTypeBundle marks a product sold as a kit of its ComboItems. Its stock
+// is derived from the stock of its components, and selling or returning it
+// moves the stock of the components.
+const ProductTypeBundle = "bundle"
+
+// CatalogProduct is the canonical entry of the master catalog for a packaged
+// product, shared by every store that sells it. Barcode is the GTIN padded
+// to GTINLength digits. Store products link to it through MasterProductID.
+type CatalogProduct struct {
+	ID           string             `json:"id" gorm:"primaryKey;size:36"`
+	Barcode      string             `json:"barcode" gorm:"size:14;uniqueIndex;not null"`
+	Name         string             `json:"name" gorm:"not null"`
+	Brand        string             `json:"brand"`
+	Description  string             `json:"description" gorm:"type:text"`
+	CategoryID   string             `json:"category_id,omitempty" gorm:"size:36"`
+	Category     string             `json:"category"`
+	QuantityUnit Unit               `json:"quantity_unit" gorm:"default:'piece';not null"`
+	MRP          Money              `json:"mrp" gorm:"embedded;embeddedPrefix:mrp_"`
+	HSNCode      string             `json:"hsn_code,omitempty" gorm:"size:16"`
+	TaxClass     string             `json:"tax_class,omitempty" gorm:"size:16"`
+	Images       []string           `json:"images" gorm:"serializer:json"`
+	Attributes   []ProductAttribute `json:"attributes,omitempty" gorm:"serializer:json"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// AvailabilitySchedule is a window in which a product, or every product of a
+// category and its subcategories, can be ordered. Schedules of a product
+// override those of its category; products without any are always available.
+type AvailabilitySchedule struct {
+	ID         string `json:"id" gorm:"primaryKey;size:36"`
+	StoreID    string `json:"store_id" gorm:"size:36;index;not null"`
+	ProductID  string `json:"product_id,omitempty" gorm:"size:36;index"`
+	CategoryID string `json:"category_id,omitempty" gorm:"size:36;index"`
+	Name       string `json:"name"`
+
+	// Days are the days of the week the window opens on, 0 being Sunday. An
+	// empty list means every day. StartTime and EndTime are HH:MM in the store
+	// timezone; a window ending before it starts runs past midnight, and one
+	// ending when it starts lasts the whole day.
+	Days      []int     `json:"days" gorm:"serializer:json"`
+	StartTime string    `json:"start_time" gorm:"size:5;not null"`
+	EndTime   string    `json:"end_time" gorm:"size:5;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AvailabilityException makes products unavailable for a whole day, such as a
+// holiday. It covers a product, a category and its subcategories, or the
+// whole store when neither is set.
+type AvailabilityException struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:36"`
+	StoreID    string    `json:"store_id" gorm:"size:36;index:idx_availability_exception_date;not null"`
+	ProductID  string    `json:"product_id,omitempty" gorm:"size:36"`
+	CategoryID string    `json:"category_id,omitempty" gorm:"size:36"`
+	Date       string    `json:"date" gorm:"size:10;index:idx_availability_exception_date;not null"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// StoreTimezone is the timezone availability schedules of a store are
+// evaluated in. Stores without one use DefaultStoreTimezone.
+type StoreTimezone struct {
+	StoreID   string    `json:"store_id" gorm:"primaryKey;size:36"`
+	Timezone  string    `json:"timezone" gorm:"size:64;not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const DefaultStoreTimezone = "Asia/Kolkata"
+
+// StoreCatalogVersion counts the changes to what the products of a store
+// read as: the products themselves, their images, variants, stock and
+// modifiers, and the categories, price rules and availability schedules
+// applied to them. UpdatedAt is the time of the last change.
+type StoreCatalogVersion struct {
+	StoreID   string    `json:"store_id" gorm:"primaryKey;size:36"`
+	Version   int64     `json:"version" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	UnavailableOutOfStock   = "OUT_OF_STOCK"
+	UnavailableOutsideHours = "OUTSIDE_HOURS"
+	UnavailableHoliday      = "HOLIDAY"
+)
+
+// CategoryTaxDefault holds the HSN code and tax class used by products of a
+// category that don't set their own. Defaults without a StoreID apply to every store.
+type CategoryTaxDefault struct {
+	ID           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	StoreID      string    `json:"store_id,omitempty" gorm:"size:36;uniqueIndex:idx_category_tax_default"`
+	Category     string    `json:"category" gorm:"size:191;not null;uniqueIndex:idx_category_tax_default"`
+	HSNCode      string    `json:"hsn_code" gorm:"size:16"`
+	TaxClass     string    `json:"tax_class" gorm:"size:16"`
+	TaxInclusive *bool     `json:"tax_inclusive,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StoreTaxProfile holds the GST registration of a store. StateCode is the
+// origin state when deciding between CGST/SGST and IGST.
+type StoreTaxProfile struct {
+	StoreID   string    `json:"store_id" gorm:"primaryKey;size:36"`
+	GSTIN     string    `json:"gstin" gorm:"size:15"`
+	StateCode string    `json:"state_code" gorm:"size:8;not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StoreDetails is the local replica of the store details shown with listings.
+// It is kept up to date from the events of the store service, so that queries
+// don't depend on the tables of that service.
+type StoreDetails struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:36"`
+	Name        string    `json:"name"`
+	Image       string    `json:"image"`
+	Address     string    `json:"address" gorm:"type:text"`
+	Category    string    `json:"category"`
+	SubCategory string    `json:"sub_category"`
+	Description string    `json:"description" gorm:"type:text"`
+	Rating      float64   `json:"rating" gorm:"default:0"`
+	ReviewCount int       `json:"review_count" gorm:"default:0"`
+	Pincode     string    `json:"pincode" gorm:"size:16;index"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StoreBackfillMiss records a store the backfill of store details could not
+// fetch, either unknown to the store service or failing, so that it is left
+// out of backfills until RetryAt instead of holding up the stores after it.
+type StoreBackfillMiss struct {
+	StoreID  string    `json:"store_id" gorm:"primaryKey;size:36"`
+	Attempts int       `json:"attempts" gorm:"not null;default:0"`
+	Error    string    `json:"error" gorm:"type:text"`
+	RetryAt  time.Time `json:"retry_at" gorm:"index"`
+}
+
+// StoreLocation holds the coordinates of a store, in decimal degrees, used to
+// discover its products and posts by distance.
+type StoreLocation struct {
+	StoreID   string    `json:"store_id" gorm:"primaryKey;size:36"`
+	Latitude  float64   `json:"latitude" gorm:"not null;index:idx_store_location"`
+	Longitude float64   `json:"longitude" gorm:"not null;index:idx_store_location"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SchemaMigration records a data migration that has been applied to the database.
+type SchemaMigration struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:64"`
+	AppliedAt time.Time `json:"applied_at"`
 }
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts that don't name a currency.
+const DefaultCurrency = "INR"
+
+// currencyExponents lists the currencies whose minor unit is not a hundredth.
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+// Money is an amount in the minor unit of its currency, paise for INR.
+// Models store it as two columns, <prefix>amount and <prefix>currency.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"not null;default:0"`
+	Currency string `json:"currency" gorm:"size:3;not null;default:'INR'"`
+}
+
+// NewMoney returns an amount of minor units in the given currency.
+func NewMoney(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+func minorUnitsPerMajor(currency string) int64 {
+	exponent, ok := currencyExponents[strings.ToUpper(currency)]
+	if !ok {
+		exponent = 2
+	}
+	return int64(math.Pow10(exponent))
+}
+
+// ParseMoney parses a decimal amount in major units, such as "12.50" rupees.
+func ParseMoney(s string, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return NewMoney(0, currency), nil
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	money := NewMoney(0, currency)
+	money.Amount = int64(math.Round(value * float64(minorUnitsPerMajor(money.Currency))))
+	return money, nil
+}
+
+// Major returns the amount in major units, such as rupees.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / float64(minorUnitsPerMajor(m.Currency))
+}
+
+// String formats the amount in major units with all minor digits, such as "12.50".
+func (m Money) String() string {
+	perMajor := minorUnitsPerMajor(m.Currency)
+	digits := len(strconv.FormatInt(perMajor, 10)) - 1
+	return strconv.FormatFloat(m.Major(), 'f', digits, 64)
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) currencyWith(other Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return NewMoney(0, other.Currency).Currency
+}
+
+func (m Money) Add(other Money) Money {
+	return NewMoney(m.Amount+other.Amount, m.currencyWith(other))
+}
+
+func (m Money) Sub(other Money) Money {
+	return NewMoney(m.Amount-other.Amount, m.currencyWith(other))
+}
+
+// Times returns the price of a quantity at m per unit, rounded to the nearest minor unit.
+func (m Money) Times(quantity Quantity) Money {
+	amount := math.Round(float64(m.Amount) * float64(quantity) / QuantityScale)
+	return NewMoney(int64(amount), m.Currency)
+}
+
+// Percent returns the given share of m in basis points, rounded to the nearest minor unit.
+func (m Money) Percent(basisPoints int64) Money {
+	amount := math.Round(float64(m.Amount) * float64(basisPoints) / 10000)
+	return NewMoney(int64(amount), m.Currency)
+}
+
+// UnmarshalJSON accepts the {"amount", "currency"} object as well as a bare
+// number or numeric string in major units, which is how clients sent prices
+// before amounts carried a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		type money Money
+		var decoded money
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+		*m = NewMoney(decoded.Amount, decoded.Currency)
+		return nil
+	}
+
+	parsed, err := ParseMoney(strings.Trim(trimmed, `"`), DefaultCurrency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// EncodeLegacyMoney rewrites the Money objects of a JSON document as numbers
+// of major units, such as 12.5 for {"amount":1250,"currency":"INR"}, which is
+// how prices were encoded before amounts carried a currency. Everything else
+// is kept as it is, in its order.
+func EncodeLegacyMoney(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	if err := encodeLegacyValue(decoder, &out); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON document")
+	}
+	return out.Bytes(), nil
+}
+
+func encodeLegacyValue(decoder *json.Decoder, out *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('['):
+		out.WriteByte('[')
+		for i := 0; decoder.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := encodeLegacyValue(decoder, out); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+		_, err := decoder.Token()
+		return err
+	case json.Delim('{'):
+		return encodeLegacyObject(decoder, out)
+	}
+
+	if number, ok := token.(json.Number); ok {
+		out.WriteString(number.String())
+		return nil
+	}
+	encoded, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	out.Write(encoded)
+	return nil
+}
+
+func encodeLegacyObject(decoder *json.Decoder, out *bytes.Buffer) error {
+	type member struct {
+		key   string
+		value []byte
+	}
+	members := []member{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		var value bytes.Buffer
+		if err := encodeLegacyValue(decoder, &value); err != nil {
+			return err
+		}
+		members = append(members, member{key, value.Bytes()})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	if len(members) == 2 {
+		values := map[string][]byte{members[0].key: members[0].value, members[1].key: members[1].value}
+		var currency string
+		amount, err := strconv.ParseInt(string(values["amount"]), 10, 64)
+		if err == nil && bytes.HasPrefix(values["currency"], []byte(`"`)) && json.Unmarshal(values["currency"], &currency) == nil {
+			out.WriteString(strconv.FormatFloat(NewMoney(amount, currency).Major(), 'f', -1, 64))
+			return nil
+		}
+	}
+
+	out.WriteByte('{')
+	for i, member := range members {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(member.value)
+	}
+	out.WriteByte('}')
+	return nil
+}
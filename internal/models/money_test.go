@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"12.50", "INR", Money{1250, "INR"}, false},
+		{" 7 ", "", Money{700, "INR"}, false},
+		{"", "USD", Money{0, "USD"}, false},
+		{"19.999", "INR", Money{2000, "INR"}, false},
+		{"0.014", "INR", Money{1, "INR"}, false},
+		{"120", "JPY", Money{120, "JPY"}, false},
+		{"1.234", "KWD", Money{1234, "KWD"}, false},
+		{"-3.5", "INR", Money{-350, "INR"}, false},
+		{"12,50", "INR", Money{}, true},
+		{"rupees", "INR", Money{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseMoney(test.in, test.currency)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseMoney(%q, %q) = %v, %v, want %v", test.in, test.currency, got, err, test.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1250, "INR"}, "12.50"},
+		{Money{5, "INR"}, "0.05"},
+		{Money{120, "JPY"}, "120"},
+		{Money{1234, "KWD"}, "1.234"},
+		{Money{-350, "USD"}, "-3.50"},
+	}
+	for _, test := range tests {
+		if got := test.money.String(); got != test.want {
+			t.Errorf("%#v.String() = %q, want %q", test.money, got, test.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want Money
+	}{
+		{"times a fraction", Money{1250, "INR"}.Times(QuantityFromFloat(1.5)), Money{1875, "INR"}},
+		{"times rounds half away from zero", Money{333, "INR"}.Times(QuantityFromFloat(0.5)), Money{167, "INR"}},
+		{"times grams", Money{10000, "INR"}.Times(Quantity(250)), Money{2500, "INR"}},
+		{"percent", Money{1999, "INR"}.Percent(1000), Money{200, "INR"}},
+		{"add takes the other currency", Money{Amount: 100}.Add(Money{50, "USD"}), Money{150, "USD"}},
+		{"sub", Money{100, "INR"}.Sub(Money{150, "INR"}), Money{-50, "INR"}},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{`{"amount":1250,"currency":"USD"}`, Money{1250, "USD"}, false},
+		{`{"amount":5}`, Money{5, "INR"}, false},
+		{`12.5`, Money{1250, "INR"}, false},
+		{`"12.5"`, Money{1250, "INR"}, false},
+		{`null`, Money{42, "INR"}, false},
+		{`"twelve"`, Money{42, "INR"}, true},
+		{`{"amount":"5"}`, Money{42, "INR"}, true},
+	}
+	for _, test := range tests {
+		got := Money{42, "INR"}
+		err := json.Unmarshal([]byte(test.in), &got)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestEncodeLegacyMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{
+			`{"name":"Rice","mrp":{"amount":1250,"currency":"INR"},"quantity":1.5}`,
+			`{"name":"Rice","mrp":12.5,"quantity":1.5}`, false,
+		},
+		{`[{"amount":100,"currency":"JPY"},{"currency":"INR","amount":5}]`, `[100,0.05]`, false},
+		{`{"products":[{"price":{"amount":1234,"currency":"KWD"}}]}`, `{"products":[{"price":1.234}]}`, false},
+		// objects that only look like Money are kept
+		{`{"amount":1,"currency":"INR","note":"x"}`, `{"amount":1,"currency":"INR","note":"x"}`, false},
+		{`{"amount":"1","currency":"INR"}`, `{"amount":"1","currency":"INR"}`, false},
+		{`{"amount":1.5,"currency":"INR"}`, `{"amount":1.5,"currency":"INR"}`, false},
+		{`{"big":12345678901234567890,"ok":true,"none":null}`, `{"big":12345678901234567890,"ok":true,"none":null}`, false},
+		{`{"mrp":`, ``, true},
+		{`{} {}`, ``, true},
+	}
+	for _, test := range tests {
+		got, err := EncodeLegacyMoney([]byte(test.in))
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("EncodeLegacyMoney(%s) = %s, want an error", test.in, got)
+			}
+			continue
+		}
+		if err != nil || string(got) != test.want {
+			t.Errorf("EncodeLegacyMoney(%s) = %s, %v, want %s", test.in, got, err, test.want)
+		}
+	}
+}
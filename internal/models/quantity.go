@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QuantityScale is the number of Quantity steps in one unit.
+const QuantityScale = 1000
+
+// Quantity is a decimal amount of some unit stored in thousandths, so that
+// 0.75 kg of rice is 750. It is serialized as a plain decimal number.
+type Quantity int64
+
+// NewQuantity returns a whole number of units.
+func NewQuantity(units int64) Quantity {
+	return Quantity(units * QuantityScale)
+}
+
+// ParseQuantity parses a decimal quantity such as "0.75". Digits beyond the
+// third decimal are rounded.
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return QuantityFromFloat(value), nil
+}
+
+// QuantityFromFloat rounds a decimal number of units to a Quantity.
+func QuantityFromFloat(value float64) Quantity {
+	return Quantity(math.Round(value * QuantityScale))
+}
+
+func (q Quantity) Float64() float64 {
+	return float64(q) / QuantityScale
+}
+
+func (q Quantity) String() string {
+	return strconv.FormatFloat(q.Float64(), 'f', -1, 64)
+}
+
+// Times returns q multiplied by another quantity, such as the amount of a
+// component needed for several bundles, rounded to the nearest thousandth.
+func (q Quantity) Times(other Quantity) Quantity {
+	return Quantity(math.Round(float64(q) * float64(other) / QuantityScale))
+}
+
+// Ceil rounds the quantity up to whole units.
+func (q Quantity) Ceil() Quantity {
+	return QuantityFromFloat(math.Ceil(q.Float64()))
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON accepts a number or a numeric string.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	trimmed := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if trimmed == "null" {
+		return nil
+	}
+
+	parsed, err := ParseQuantity(trimmed)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// Unit is a unit of measure from the catalog below.
+type Unit string
+
+const (
+	UnitPiece      Unit = "piece"
+	UnitDozen      Unit = "dozen"
+	UnitGram       Unit = "g"
+	UnitKilogram   Unit = "kg"
+	UnitMillilitre Unit = "ml"
+	UnitLitre      Unit = "l"
+)
+
+const (
+	DimensionCount  = "count"
+	DimensionMass   = "mass"
+	DimensionVolume = "volume"
+)
+
+// UnitDefinition describes a unit of the catalog. Factor is the number of
+// base units (piece, g or ml) of its dimension in one unit.
+type UnitDefinition struct {
+	Unit      Unit   `json:"unit"`
+	Name      string `json:"name"`
+	Dimension string `json:"dimension"`
+	Factor    int64  `json:"factor"`
+}
+
+var unitCatalog = []UnitDefinition{
+	{Unit: UnitPiece, Name: "Piece", Dimension: DimensionCount, Factor: 1},
+	{Unit: UnitDozen, Name: "Dozen", Dimension: DimensionCount, Factor: 12},
+	{Unit: UnitGram, Name: "Gram", Dimension: DimensionMass, Factor: 1},
+	{Unit: UnitKilogram, Name: "Kilogram", Dimension: DimensionMass, Factor: 1000},
+	{Unit: UnitMillilitre, Name: "Millilitre", Dimension: DimensionVolume, Factor: 1},
+	{Unit: UnitLitre, Name: "Litre", Dimension: DimensionVolume, Factor: 1000},
+}
+
+// unitAliases maps the spellings found in free-text quantity units to the catalog.
+var unitAliases = map[string]Unit{
+	"piece": UnitPiece, "pieces": UnitPiece, "pc": UnitPiece, "pcs": UnitPiece,
+	"nos": UnitPiece, "no": UnitPiece, "unit": UnitPiece, "units": UnitPiece,
+	"dozen": UnitDozen, "dz": UnitDozen, "doz": UnitDozen,
+	"g": UnitGram, "gm": UnitGram, "gms": UnitGram, "gram": UnitGram, "grams": UnitGram, "gr": UnitGram,
+	"kg": UnitKilogram, "kgs": UnitKilogram, "kilo": UnitKilogram, "kilogram": UnitKilogram, "kilograms": UnitKilogram,
+	"ml": UnitMillilitre, "millilitre": UnitMillilitre, "milliliter": UnitMillilitre, "millilitres": UnitMillilitre,
+	"l": UnitLitre, "ltr": UnitLitre, "ltrs": UnitLitre, "litre": UnitLitre, "liter": UnitLitre, "litres": UnitLitre, "liters": UnitLitre,
+}
+
+// Units returns the unit of measure catalog.
+func Units() []UnitDefinition {
+	return append([]UnitDefinition{}, unitCatalog...)
+}
+
+func (u Unit) definition() (UnitDefinition, bool) {
+	for _, definition := range unitCatalog {
+		if definition.Unit == u {
+			return definition, true
+		}
+	}
+	return UnitDefinition{}, false
+}
+
+// ParseUnit maps a unit name or common alias, case-insensitively, to the catalog.
+func ParseUnit(s string) (Unit, error) {
+	unit, ok := unitAliases[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", s)
+	}
+	return unit, nil
+}
+
+// ConvertQuantity converts a quantity between two units of the same dimension.
+func ConvertQuantity(quantity Quantity, from Unit, to Unit) (Quantity, error) {
+	if from == to {
+		return quantity, nil
+	}
+
+	fromDefinition, ok := from.definition()
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toDefinition, ok := to.definition()
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromDefinition.Dimension != toDefinition.Dimension {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+
+	converted := math.Round(float64(quantity) * float64(fromDefinition.Factor) / float64(toDefinition.Factor))
+	return Quantity(converted), nil
+}
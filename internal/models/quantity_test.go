@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{"0.75", 750, false},
+		{" 3 ", 3000, false},
+		{"", 0, false},
+		{"2.3456", 2346, false},
+		{"-1.5", -1500, false},
+		{"0.0004", 0, false},
+		{"1/2", 0, true},
+		{"kg", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseQuantity(test.in)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseQuantity(%q) = %d, %v, want %d", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"string of a fraction", Quantity(750).String(), "0.75"},
+		{"string of whole units", NewQuantity(3).String(), "3"},
+		{"string of a thousandth", Quantity(1).String(), "0.001"},
+		{"times", Quantity(1500).Times(2500), Quantity(3750)},
+		{"times rounds", Quantity(333).Times(500), Quantity(167)},
+		{"ceil of a fraction", Quantity(1001).Ceil(), Quantity(2000)},
+		{"ceil of whole units", Quantity(2000).Ceil(), Quantity(2000)},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Quantity Quantity `json:"quantity"`
+	}{1500})
+	if err != nil || string(data) != `{"quantity":1.5}` {
+		t.Fatalf("got %s, %v", data, err)
+	}
+
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{`2`, 2000, false},
+		{`"0.25"`, 250, false},
+		{`null`, 42, false},
+		{`"two"`, 42, true},
+	}
+	for _, test := range tests {
+		got := Quantity(42)
+		err := json.Unmarshal([]byte(test.in), &got)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Unit
+		wantErr bool
+	}{
+		{"KGS", UnitKilogram, false},
+		{" Ltr ", UnitLitre, false},
+		{"pcs", UnitPiece, false},
+		{"doz", UnitDozen, false},
+		{"gms", UnitGram, false},
+		{"box", "", true},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		got, err := ParseUnit(test.in)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseUnit(%q) = %q, %v, want %q", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestConvertQuantity(t *testing.T) {
+	tests := []struct {
+		quantity Quantity
+		from, to Unit
+		want     Quantity
+		wantErr  bool
+	}{
+		{NewQuantity(2), UnitDozen, UnitPiece, NewQuantity(24), false},
+		{QuantityFromFloat(1.5), UnitKilogram, UnitGram, NewQuantity(1500), false},
+		{NewQuantity(250), UnitGram, UnitKilogram, Quantity(250), false},
+		{NewQuantity(1), UnitMillilitre, UnitLitre, Quantity(1), false},
+		{NewQuantity(1), UnitGram, UnitGram, NewQuantity(1), false},
+		{NewQuantity(1), UnitKilogram, UnitLitre, 0, true},
+		{NewQuantity(1), Unit("box"), UnitPiece, 0, true},
+	}
+	for _, test := range tests {
+		got, err := ConvertQuantity(test.quantity, test.from, test.to)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ConvertQuantity(%v, %s, %s) = %v, %v, want %v", test.quantity, test.from, test.to, got, err, test.want)
+		}
+	}
+}
@@ -24,6 +24,8 @@ const _ = grpc.SupportPackageIsVersion7
 type ImageServiceClient interface {
 	UploadImage(ctx context.Context, in *ImageMessage, opts ...grpc.CallOption) (*ImageURL, error)
 	UploadMultipleImage(ctx context.Context, opts ...grpc.CallOption) (ImageService_UploadMultipleImageClient, error)
+	// DeleteImage removes an uploaded image by its URL
+	DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error)
 }
 
 type imageServiceClient struct {
@@ -74,12 +76,23 @@ func (x *imageServiceUploadMultipleImageClient) Recv() (*ImageURL, error) {
 	return m, nil
 }
 
+func (c *imageServiceClient) DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error) {
+	out := new(DeleteImageResponse)
+	err := c.cc.Invoke(ctx, "/store.internal.pb.ImageService/DeleteImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ImageServiceServer is the server API for ImageService service.
 // All implementations must embed UnimplementedImageServiceServer
 // for forward compatibility
 type ImageServiceServer interface {
 	UploadImage(context.Context, *ImageMessage) (*ImageURL, error)
 	UploadMultipleImage(ImageService_UploadMultipleImageServer) error
+	// DeleteImage removes an uploaded image by its URL
+	DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error)
 	mustEmbedUnimplementedImageServiceServer()
 }
 
@@ -93,6 +106,9 @@ func (UnimplementedImageServiceServer) UploadImage(context.Context, *ImageMessag
 func (UnimplementedImageServiceServer) UploadMultipleImage(ImageService_UploadMultipleImageServer) error {
 	return status.Errorf(codes.Unimplemented, "method UploadMultipleImage not implemented")
 }
+func (UnimplementedImageServiceServer) DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteImage not implemented")
+}
 func (UnimplementedImageServiceServer) mustEmbedUnimplementedImageServiceServer() {}
 
 // UnsafeImageServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -150,6 +166,24 @@ func (x *imageServiceUploadMultipleImageServer) Recv() (*ImageMessage, error) {
 	return m, nil
 }
 
+func _ImageService_DeleteImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).DeleteImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/store.internal.pb.ImageService/DeleteImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).DeleteImage(ctx, req.(*DeleteImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ImageService_ServiceDesc is the grpc.ServiceDesc for ImageService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +195,10 @@ var ImageService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UploadImage",
 			Handler:    _ImageService_UploadImage_Handler,
 		},
+		{
+			MethodName: "DeleteImage",
+			Handler:    _ImageService_DeleteImage_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
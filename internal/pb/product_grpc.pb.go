@@ -24,6 +24,8 @@ const _ = grpc.SupportPackageIsVersion7
 type ProductServiceClient interface {
 	// rpc GetFCMToken(StoreId) returns (FCMToken) {}
 	ChangeProductQuantity(ctx context.Context, in *ChangeProductQuantityRequest, opts ...grpc.CallOption) (*ChangeProductQuantityResponse, error)
+	GetProductPrice(ctx context.Context, in *GetProductPriceRequest, opts ...grpc.CallOption) (*GetProductPriceResponse, error)
+	GetProductTax(ctx context.Context, in *GetProductTaxRequest, opts ...grpc.CallOption) (*GetProductTaxResponse, error)
 }
 
 type productServiceClient struct {
@@ -43,12 +45,32 @@ func (c *productServiceClient) ChangeProductQuantity(ctx context.Context, in *Ch
 	return out, nil
 }
 
+func (c *productServiceClient) GetProductPrice(ctx context.Context, in *GetProductPriceRequest, opts ...grpc.CallOption) (*GetProductPriceResponse, error) {
+	out := new(GetProductPriceResponse)
+	err := c.cc.Invoke(ctx, "/product.internal.pb.ProductService/GetProductPrice", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *productServiceClient) GetProductTax(ctx context.Context, in *GetProductTaxRequest, opts ...grpc.CallOption) (*GetProductTaxResponse, error) {
+	out := new(GetProductTaxResponse)
+	err := c.cc.Invoke(ctx, "/product.internal.pb.ProductService/GetProductTax", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProductServiceServer is the server API for ProductService service.
 // All implementations must embed UnimplementedProductServiceServer
 // for forward compatibility
 type ProductServiceServer interface {
 	// rpc GetFCMToken(StoreId) returns (FCMToken) {}
 	ChangeProductQuantity(context.Context, *ChangeProductQuantityRequest) (*ChangeProductQuantityResponse, error)
+	GetProductPrice(context.Context, *GetProductPriceRequest) (*GetProductPriceResponse, error)
+	GetProductTax(context.Context, *GetProductTaxRequest) (*GetProductTaxResponse, error)
 	mustEmbedUnimplementedProductServiceServer()
 }
 
@@ -59,6 +81,12 @@ type UnimplementedProductServiceServer struct {
 func (UnimplementedProductServiceServer) ChangeProductQuantity(context.Context, *ChangeProductQuantityRequest) (*ChangeProductQuantityResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChangeProductQuantity not implemented")
 }
+func (UnimplementedProductServiceServer) GetProductPrice(context.Context, *GetProductPriceRequest) (*GetProductPriceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProductPrice not implemented")
+}
+func (UnimplementedProductServiceServer) GetProductTax(context.Context, *GetProductTaxRequest) (*GetProductTaxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProductTax not implemented")
+}
 func (UnimplementedProductServiceServer) mustEmbedUnimplementedProductServiceServer() {}
 
 // UnsafeProductServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -90,6 +118,42 @@ func _ProductService_ChangeProductQuantity_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProductService_GetProductPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProductPriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductServiceServer).GetProductPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/product.internal.pb.ProductService/GetProductPrice",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductServiceServer).GetProductPrice(ctx, req.(*GetProductPriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProductService_GetProductTax_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProductTaxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductServiceServer).GetProductTax(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/product.internal.pb.ProductService/GetProductTax",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductServiceServer).GetProductTax(ctx, req.(*GetProductTaxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProductService_ServiceDesc is the grpc.ServiceDesc for ProductService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -101,6 +165,14 @@ var ProductService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ChangeProductQuantity",
 			Handler:    _ProductService_ChangeProductQuantity_Handler,
 		},
+		{
+			MethodName: "GetProductPrice",
+			Handler:    _ProductService_GetProductPrice_Handler,
+		},
+		{
+			MethodName: "GetProductTax",
+			Handler:    _ProductService_GetProductTax_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "product.proto",
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// AttributeRepository defines the interface for category attribute definitions.
+type AttributeRepository interface {
+	Create(definition *models.AttributeDefinition) error
+	GetByID(id int) (*models.AttributeDefinition, error)
+	GetByCategoryIDs(categoryIDs []string) ([]models.AttributeDefinition, error)
+	Update(definition *models.AttributeDefinition) error
+	Delete(id int) error
+}
+
+type attributeRepository struct {
+	db *gorm.DB
+}
+
+// NewAttributeRepository creates a new instance of AttributeRepository.
+func NewAttributeRepository(db *gorm.DB) AttributeRepository {
+	return &attributeRepository{db: db}
+}
+
+// Create inserts a new attribute definition into the database.
+func (r *attributeRepository) Create(definition *models.AttributeDefinition) error {
+	return r.db.Create(definition).Error
+}
+
+// GetByID retrieves an attribute definition by its ID.
+func (r *attributeRepository) GetByID(id int) (*models.AttributeDefinition, error) {
+	var definition models.AttributeDefinition
+	if err := r.db.First(&definition, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// GetByCategoryIDs retrieves the attribute definitions of the given categories in display order.
+func (r *attributeRepository) GetByCategoryIDs(categoryIDs []string) ([]models.AttributeDefinition, error) {
+	var definitions []models.AttributeDefinition
+	tx := r.db.Where("category_id IN ?", categoryIDs).
+		Order("display_order ASC, id ASC").
+		Find(&definitions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return definitions, nil
+}
+
+// Update modifies an existing attribute definition.
+func (r *attributeRepository) Update(definition *models.AttributeDefinition) error {
+	return r.db.Save(definition).Error
+}
+
+// Delete removes an attribute definition together with the values products hold for it.
+func (r *attributeRepository) Delete(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attribute_id = ?", id).Delete(&models.ProductAttribute{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AttributeDefinition{}, "id = ?", id).Error
+	})
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AvailabilityRepository defines the interface for availability schedules,
+// their exceptions and store timezones.
+type AvailabilityRepository interface {
+	CreateSchedule(schedule *models.AvailabilitySchedule) error
+	GetScheduleByID(id string) (*models.AvailabilitySchedule, error)
+	GetSchedulesByStoreIDs(storeIDs []string) ([]models.AvailabilitySchedule, error)
+	UpdateSchedule(schedule *models.AvailabilitySchedule) error
+	DeleteSchedule(id string) error
+
+	CreateException(exception *models.AvailabilityException) error
+	GetExceptionsByStoreID(storeID string) ([]models.AvailabilityException, error)
+	GetExceptionsByDates(storeIDs []string, dates []string) ([]models.AvailabilityException, error)
+	DeleteException(id string) error
+
+	UpsertTimezone(timezone *models.StoreTimezone) error
+	GetTimezones(storeIDs []string) ([]models.StoreTimezone, error)
+}
+
+type availabilityRepository struct {
+	db *gorm.DB
+}
+
+// NewAvailabilityRepository creates a new instance of AvailabilityRepository.
+func NewAvailabilityRepository(db *gorm.DB) AvailabilityRepository {
+	return &availabilityRepository{db: db}
+}
+
+// CreateSchedule inserts a new availability schedule into the database.
+func (r *availabilityRepository) CreateSchedule(schedule *models.AvailabilitySchedule) error {
+	schedule.ID = uuid.New().String()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(schedule).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, schedule.StoreID)
+	})
+}
+
+// GetScheduleByID retrieves an availability schedule by its ID.
+func (r *availabilityRepository) GetScheduleByID(id string) (*models.AvailabilitySchedule, error) {
+	var schedule models.AvailabilitySchedule
+	if err := r.db.First(&schedule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetSchedulesByStoreIDs retrieves the availability schedules of the given stores.
+func (r *availabilityRepository) GetSchedulesByStoreIDs(storeIDs []string) ([]models.AvailabilitySchedule, error) {
+	schedules := []models.AvailabilitySchedule{}
+	if len(storeIDs) == 0 {
+		return schedules, nil
+	}
+	if err := r.db.Where("store_id IN ?", storeIDs).Order("start_time ASC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule modifies an existing availability schedule.
+func (r *availabilityRepository) UpdateSchedule(schedule *models.AvailabilitySchedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(schedule).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, schedule.StoreID)
+	})
+}
+
+// DeleteSchedule removes an availability schedule by its ID.
+func (r *availabilityRepository) DeleteSchedule(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpOwnerCatalogVersion(tx, &models.AvailabilitySchedule{}, id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.AvailabilitySchedule{}, "id = ?", id).Error
+	})
+}
+
+// CreateException inserts a new availability exception into the database.
+func (r *availabilityRepository) CreateException(exception *models.AvailabilityException) error {
+	exception.ID = uuid.New().String()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(exception).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, exception.StoreID)
+	})
+}
+
+// GetExceptionsByStoreID retrieves the availability exceptions of a store by date.
+func (r *availabilityRepository) GetExceptionsByStoreID(storeID string) ([]models.AvailabilityException, error) {
+	var exceptions []models.AvailabilityException
+	if err := r.db.Where("store_id = ?", storeID).Order("date ASC").Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
+
+// GetExceptionsByDates retrieves the availability exceptions of the given
+// stores falling on any of the given dates.
+func (r *availabilityRepository) GetExceptionsByDates(storeIDs []string, dates []string) ([]models.AvailabilityException, error) {
+	exceptions := []models.AvailabilityException{}
+	if len(storeIDs) == 0 || len(dates) == 0 {
+		return exceptions, nil
+	}
+	tx := r.db.Where("store_id IN ?", storeIDs).
+		Where("date IN ?", dates).
+		Find(&exceptions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return exceptions, nil
+}
+
+// DeleteException removes an availability exception by its ID.
+func (r *availabilityRepository) DeleteException(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpOwnerCatalogVersion(tx, &models.AvailabilityException{}, id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.AvailabilityException{}, "id = ?", id).Error
+	})
+}
+
+// UpsertTimezone creates or replaces the timezone of a store.
+func (r *availabilityRepository) UpsertTimezone(timezone *models.StoreTimezone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "store_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"timezone", "updated_at"}),
+		}).Create(timezone).Error
+		if err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, timezone.StoreID)
+	})
+}
+
+// GetTimezones retrieves the timezones set for the given stores.
+func (r *availabilityRepository) GetTimezones(storeIDs []string) ([]models.StoreTimezone, error) {
+	timezones := []models.StoreTimezone{}
+	if len(storeIDs) == 0 {
+		return timezones, nil
+	}
+	if err := r.db.Where("store_id IN ?", storeIDs).Find(&timezones).Error; err != nil {
+		return nil, err
+	}
+	return timezones, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// CatalogRepository defines the interface for the master product catalog.
+type CatalogRepository interface {
+	Create(product *models.CatalogProduct) error
+	GetByID(id string) (*models.CatalogProduct, error)
+	GetByBarcode(barcode string) (*models.CatalogProduct, error)
+	Search(query string, limit int) ([]models.CatalogProduct, error)
+	Update(product *models.CatalogProduct) error
+	Delete(id string) error
+}
+
+type catalogRepository struct {
+	db *gorm.DB
+}
+
+// NewCatalogRepository creates a new instance of CatalogRepository.
+func NewCatalogRepository(db *gorm.DB) CatalogRepository {
+	return &catalogRepository{db: db}
+}
+
+// Create inserts a new master catalog entry into the database.
+func (r *catalogRepository) Create(product *models.CatalogProduct) error {
+	product.ID = uuid.New().String()
+	return r.db.Create(product).Error
+}
+
+// GetByID retrieves a master catalog entry by its ID.
+func (r *catalogRepository) GetByID(id string) (*models.CatalogProduct, error) {
+	var product models.CatalogProduct
+	if err := r.db.First(&product, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetByBarcode retrieves the master catalog entry of a normalized barcode.
+// It returns nil when the barcode is not in the catalog.
+func (r *catalogRepository) GetByBarcode(barcode string) (*models.CatalogProduct, error) {
+	var products []models.CatalogProduct
+	if err := r.db.Where("barcode = ?", barcode).Limit(1).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, nil
+	}
+	return &products[0], nil
+}
+
+// Search retrieves the master catalog entries whose name or brand contains the query.
+func (r *catalogRepository) Search(query string, limit int) ([]models.CatalogProduct, error) {
+	var products []models.CatalogProduct
+	pattern := "%" + query + "%"
+	tx := r.db.Where("name LIKE ? OR brand LIKE ?", pattern, pattern).
+		Order("name ASC").
+		Limit(limit).
+		Find(&products)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return products, nil
+}
+
+// Update modifies an existing master catalog entry.
+func (r *catalogRepository) Update(product *models.CatalogProduct) error {
+	return r.db.Save(product).Error
+}
+
+// Delete removes a master catalog entry and unlinks the store products linked to it.
+func (r *catalogRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Product{}).
+			Where("master_product_id = ?", id).
+			Updates(map[string]interface{}{"master_product_id": "", "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.CatalogProduct{}, "id = ?", id).Error
+	})
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CatalogVersionRepository defines the interface for reading the catalog
+// versions of stores. The versions are bumped by the repositories writing
+// to the catalog.
+type CatalogVersionRepository interface {
+	GetByStoreID(storeID string) (*models.StoreCatalogVersion, error)
+	GetByProductID(productID string) (*models.StoreCatalogVersion, int64, error)
+}
+
+type catalogVersionRepository struct {
+	db *gorm.DB
+}
+
+// NewCatalogVersionRepository creates a new instance of CatalogVersionRepository.
+func NewCatalogVersionRepository(db *gorm.DB) CatalogVersionRepository {
+	return &catalogVersionRepository{db: db}
+}
+
+// GetByStoreID retrieves the catalog version of a store. A store whose
+// catalog never changed is at version 0.
+func (r *catalogVersionRepository) GetByStoreID(storeID string) (*models.StoreCatalogVersion, error) {
+	var versions []models.StoreCatalogVersion
+	if err := r.db.Where("store_id = ?", storeID).Limit(1).Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return &models.StoreCatalogVersion{StoreID: storeID}, nil
+	}
+	return &versions[0], nil
+}
+
+// GetByProductID retrieves the catalog version of the store of a product,
+// and the version of the product.
+func (r *catalogVersionRepository) GetByProductID(productID string) (*models.StoreCatalogVersion, int64, error) {
+	var product models.Product
+	if err := r.db.Select("store_id", "version").First(&product, "id = ?", productID).Error; err != nil {
+		return nil, 0, err
+	}
+	version, err := r.GetByStoreID(product.StoreID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return version, product.Version, nil
+}
+
+// bumpCatalogVersion records a change to the catalogs of the given stores.
+func bumpCatalogVersion(db *gorm.DB, storeIDs ...string) error {
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, storeID := range storeIDs {
+		if storeID == "" || seen[storeID] {
+			continue
+		}
+		seen[storeID] = true
+
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "store_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"version":    gorm.Expr("store_catalog_versions.version + 1"),
+				"updated_at": now,
+			}),
+		}).Create(&models.StoreCatalogVersion{StoreID: storeID, Version: 1, UpdatedAt: now}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bumpProductCatalogVersion records a change to the catalogs of the stores
+// of the given products.
+func bumpProductCatalogVersion(db *gorm.DB, productIDs ...string) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	var storeIDs []string
+	if err := db.Model(&models.Product{}).Where("id IN ?", productIDs).Distinct().Pluck("store_id", &storeIDs).Error; err != nil {
+		return err
+	}
+	return bumpCatalogVersion(db, storeIDs...)
+}
+
+// bumpImageCatalogVersion records a change to the catalogs of the stores of
+// the products of the given images.
+func bumpImageCatalogVersion(db *gorm.DB, imageIDs ...int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	var productIDs []string
+	if err := db.Model(&models.ProductImage{}).Where("id IN ?", imageIDs).Distinct().Pluck("product_id", &productIDs).Error; err != nil {
+		return err
+	}
+	return bumpProductCatalogVersion(db, productIDs...)
+}
+
+// bumpOwnerCatalogVersion records a change to the catalog of the store
+// owning the row of model with the given id, such as a price rule.
+func bumpOwnerCatalogVersion(db *gorm.DB, model interface{}, id string) error {
+	var storeIDs []string
+	if err := db.Model(model).Where("id = ?", id).Pluck("store_id", &storeIDs).Error; err != nil {
+		return err
+	}
+	return bumpCatalogVersion(db, storeIDs...)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// CategoryRepository defines the interface for the category tree.
+type CategoryRepository interface {
+	Create(category *models.Category) error
+	GetByID(id string) (*models.Category, error)
+	GetByStoreID(storeID string) ([]models.Category, error)
+	GetBySlug(storeID string, slug string) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(id string) error
+	CountChildren(id string) (int64, error)
+	CountProducts(id string) (int64, error)
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+// NewCategoryRepository creates a new instance of CategoryRepository.
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+// Create inserts a new category into the database.
+func (r *categoryRepository) Create(category *models.Category) error {
+	category.ID = uuid.New().String()
+	category.Slug = NormalizeCategory(category.Name)
+	return r.db.Create(category).Error
+}
+
+// GetByID retrieves a category by its ID.
+func (r *categoryRepository) GetByID(id string) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.First(&category, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetByStoreID retrieves the categories of a store together with the global
+// taxonomy, in display order.
+func (r *categoryRepository) GetByStoreID(storeID string) ([]models.Category, error) {
+	var categories []models.Category
+	tx := r.db.Where("store_id = ? OR store_id = ''", storeID).
+		Order("display_order ASC, name ASC").
+		Find(&categories)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return categories, nil
+}
+
+// GetBySlug retrieves the category a store means by a name, preferring the
+// store's own categories over the global ones and root categories over
+// nested ones. It returns nil when there is no such category.
+func (r *categoryRepository) GetBySlug(storeID string, slug string) (*models.Category, error) {
+	var categories []models.Category
+	tx := r.db.Where("slug = ?", slug).
+		Where("store_id = ? OR store_id = ''", storeID).
+		Order("store_id DESC, parent_id ASC").
+		Limit(1).
+		Find(&categories)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if len(categories) == 0 {
+		return nil, nil
+	}
+	return &categories[0], nil
+}
+
+// Update modifies an existing category and renames it on its products.
+func (r *categoryRepository) Update(category *models.Category) error {
+	category.Slug = NormalizeCategory(category.Name)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Product{}).
+			Where("category_id = ?", category.ID).
+			Updates(map[string]interface{}{"category": category.Name, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+
+		// global categories are shared by the stores using them
+		var storeIDs []string
+		err = tx.Model(&models.Product{}).Where("category_id = ?", category.ID).Distinct().Pluck("store_id", &storeIDs).Error
+		if err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, append(storeIDs, category.StoreID)...)
+	})
+}
+
+// Delete removes a category by its ID.
+func (r *categoryRepository) Delete(id string) error {
+	return r.db.Delete(&models.Category{}, "id = ?", id).Error
+}
+
+// CountChildren returns the number of categories directly under a category.
+func (r *categoryRepository) CountChildren(id string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountProducts returns the number of products assigned to a category.
+func (r *categoryRepository) CountProducts(id string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Product{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GeoRepository defines the interface for store coordinates.
+type GeoRepository interface {
+	UpsertStoreLocation(location *models.StoreLocation) error
+	GetStoreLocation(storeID string) (*models.StoreLocation, error)
+	GetStoreLocationsInBox(minLatitude, maxLatitude, minLongitude, maxLongitude float64) ([]models.StoreLocation, error)
+	DeleteStoreLocation(storeID string) error
+}
+
+type geoRepository struct {
+	db *gorm.DB
+}
+
+// NewGeoRepository creates a new instance of GeoRepository.
+func NewGeoRepository(db *gorm.DB) GeoRepository {
+	return &geoRepository{db: db}
+}
+
+// UpsertStoreLocation creates or replaces the coordinates of a store.
+func (r *geoRepository) UpsertStoreLocation(location *models.StoreLocation) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"latitude", "longitude", "updated_at"}),
+	}).Create(location).Error
+}
+
+// GetStoreLocation retrieves the coordinates of a store.
+func (r *geoRepository) GetStoreLocation(storeID string) (*models.StoreLocation, error) {
+	var location models.StoreLocation
+	if err := r.db.First(&location, "store_id = ?", storeID).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// GetStoreLocationsInBox retrieves the stores whose coordinates fall within a
+// bounding box. Plain range conditions keep the query portable between SQLite
+// and MySQL; callers filter the result down to the exact distance.
+func (r *geoRepository) GetStoreLocationsInBox(minLatitude, maxLatitude, minLongitude, maxLongitude float64) ([]models.StoreLocation, error) {
+	locations := []models.StoreLocation{}
+	tx := r.db.
+		Where("latitude BETWEEN ? AND ?", minLatitude, maxLatitude).
+		Where("longitude BETWEEN ? AND ?", minLongitude, maxLongitude).
+		Find(&locations)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return locations, nil
+}
+
+// DeleteStoreLocation removes the coordinates of a store.
+func (r *geoRepository) DeleteStoreLocation(storeID string) error {
+	return r.db.Delete(&models.StoreLocation{}, "store_id = ?", storeID).Error
+}
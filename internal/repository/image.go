@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImageRepository defines the interface for product images and their uploads.
+type ImageRepository interface {
+	CreateImages(images []models.ProductImage) error
+	GetImageByID(id int) (*models.ProductImage, error)
+	GetImagesByProductID(productID string) ([]models.ProductImage, error)
+	UpdateImage(image *models.ProductImage) error
+	DeleteImage(id int) error
+	ReorderImages(productID string, imageIDs []int) error
+	SetPrimaryImage(productID string, imageID int) error
+
+	CreateUploads(uploads []models.ImageUpload) error
+	GetUploadsByProductID(productID string) ([]models.ImageUpload, error)
+	GetRetryableUploads(maxAttempts int, limit int) ([]models.ImageUpload, error)
+	GetPendingImports(limit int) ([]models.ImageUpload, error)
+	SetImagesPending(imageIDs []int) error
+	MarkImageReady(image *models.ProductImage) error
+	MarkImageFailed(upload *models.ImageUpload) error
+	DeleteStaleUploads() error
+
+	TrackImages(urls []string, at time.Time) error
+	UntrackImages(urls []string) error
+	UnreferenceImages(urls []string, at time.Time) error
+	ReferenceImages(urls []string) error
+	GetUnreferencedImages(before time.Time, now time.Time, limit int) ([]models.TrackedImage, error)
+	DeferImageDelete(image *models.TrackedImage) error
+	GetReferencedImageURLs(urls []string) ([]string, error)
+}
+
+type imageRepository struct {
+	db *gorm.DB
+}
+
+// NewImageRepository creates a new instance of ImageRepository.
+func NewImageRepository(db *gorm.DB) ImageRepository {
+	return &imageRepository{db: db}
+}
+
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("product_images.is_primary DESC, product_images.display_order ASC, product_images.id ASC")
+}
+
+// CreateImages inserts new images of a product into the database.
+func (r *imageRepository) CreateImages(images []models.ProductImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&images).Error; err != nil {
+			return err
+		}
+		productIDs := make([]string, len(images))
+		for i, image := range images {
+			productIDs[i] = image.ProductID
+		}
+		return bumpProductCatalogVersion(tx, productIDs...)
+	})
+}
+
+// GetImageByID retrieves a product image by its ID.
+func (r *imageRepository) GetImageByID(id int) (*models.ProductImage, error) {
+	var image models.ProductImage
+	if err := r.db.First(&image, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// GetImagesByProductID retrieves the images of a product, primary image first.
+func (r *imageRepository) GetImagesByProductID(productID string) ([]models.ProductImage, error) {
+	images := []models.ProductImage{}
+	if err := orderImages(r.db.Where("product_id = ?", productID)).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// UpdateImage updates the details of a product image. Its URL, upload status,
+// order and primary flag are left alone.
+func (r *imageRepository) UpdateImage(image *models.ProductImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(image).Select("alt_text", "width", "height", "variant_type", "variant_id").Updates(image).Error; err != nil {
+			return err
+		}
+		return bumpImageCatalogVersion(tx, image.ID)
+	})
+}
+
+// DeleteImage atomically removes a product image and its pending upload.
+func (r *imageRepository) DeleteImage(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpImageCatalogVersion(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.ImageUpload{}, "image_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ProductImage{}, "id = ?", id).Error
+	})
+}
+
+// ReorderImages atomically sets the display order of the images of a product
+// to their position in imageIDs.
+func (r *imageRepository) ReorderImages(productID string, imageIDs []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range imageIDs {
+			err := tx.Model(&models.ProductImage{}).Where("id = ? AND product_id = ?", id, productID).
+				Update("display_order", i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+		return bumpProductCatalogVersion(tx, productID)
+	})
+}
+
+// SetPrimaryImage atomically makes an image the only primary image of its product.
+func (r *imageRepository) SetPrimaryImage(productID string, imageID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).Update("is_primary", false).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.ProductImage{}).Where("id = ? AND product_id = ?", imageID, productID).
+			Update("is_primary", true).Error
+		if err != nil {
+			return err
+		}
+		return bumpProductCatalogVersion(tx, productID)
+	})
+}
+
+// CreateUploads stores the data of images waiting for an upload.
+func (r *imageRepository) CreateUploads(uploads []models.ImageUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+	return r.db.Create(&uploads).Error
+}
+
+// GetUploadsByProductID retrieves the uploads waiting for the images of a product.
+func (r *imageRepository) GetUploadsByProductID(productID string) ([]models.ImageUpload, error) {
+	uploads := []models.ImageUpload{}
+	if err := r.db.Where("product_id = ?", productID).Order("image_id").Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// GetRetryableUploads retrieves up to limit uploads with fewer than
+// maxAttempts attempts, least recently tried first. Imports that were not
+// attempted yet are left to the importer.
+func (r *imageRepository) GetRetryableUploads(maxAttempts int, limit int) ([]models.ImageUpload, error) {
+	uploads := []models.ImageUpload{}
+	tx := r.db.Where("attempts > 0 AND attempts < ?", maxAttempts).Order("updated_at").Limit(limit).Find(&uploads)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return uploads, nil
+}
+
+// GetPendingImports retrieves up to limit imports from remote URLs that
+// were not attempted yet, oldest first.
+func (r *imageRepository) GetPendingImports(limit int) ([]models.ImageUpload, error) {
+	uploads := []models.ImageUpload{}
+	tx := r.db.Where("attempts = 0 AND source_url <> ''").Order("updated_at").Limit(limit).Find(&uploads)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return uploads, nil
+}
+
+// SetImagesPending marks images as waiting for an upload.
+func (r *imageRepository) SetImagesPending(imageIDs []int) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProductImage{}).Where("id IN ?", imageIDs).
+			Updates(map[string]interface{}{"status": models.ImageStatusPending, "error": ""}).Error
+		if err != nil {
+			return err
+		}
+		return bumpImageCatalogVersion(tx, imageIDs...)
+	})
+}
+
+// MarkImageReady atomically sets the rendition URLs and dimensions of an
+// uploaded image and removes its upload.
+func (r *imageRepository) MarkImageReady(image *models.ProductImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProductImage{}).Where("id = ?", image.ID).
+			Updates(map[string]interface{}{
+				"image":         image.Image,
+				"medium_url":    image.MediumURL,
+				"thumbnail_url": image.ThumbnailURL,
+				"width":         image.Width,
+				"height":        image.Height,
+				"status":        models.ImageStatusReady,
+				"error":         "",
+			}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.ImageUpload{}, "image_id = ?", image.ID).Error; err != nil {
+			return err
+		}
+		return bumpImageCatalogVersion(tx, image.ID)
+	})
+}
+
+// MarkImageFailed atomically records a failed attempt of an upload and marks
+// its image as failed.
+func (r *imageRepository) MarkImageFailed(upload *models.ImageUpload) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ImageUpload{}).Where("image_id = ?", upload.ImageID).
+			Updates(map[string]interface{}{"attempts": upload.Attempts, "last_error": upload.LastError, "updated_at": upload.UpdatedAt}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.ProductImage{}).Where("id = ?", upload.ImageID).
+			Updates(map[string]interface{}{"status": models.ImageStatusFailed, "error": upload.LastError}).Error
+		if err != nil {
+			return err
+		}
+		return bumpImageCatalogVersion(tx, upload.ImageID)
+	})
+}
+
+// DeleteStaleUploads removes the uploads of images that were deleted or
+// detached from their product since the upload failed.
+func (r *imageRepository) DeleteStaleUploads() error {
+	return r.db.Exec(`DELETE FROM image_uploads WHERE NOT EXISTS (
+		SELECT 1 FROM product_images WHERE product_images.id = image_uploads.image_id AND product_images.product_id = image_uploads.product_id)`).Error
+}
+
+// TrackImages starts tracking uploaded images, unreferenced from at.
+func (r *imageRepository) TrackImages(urls []string, at time.Time) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	images := make([]models.TrackedImage, len(urls))
+	for i, url := range urls {
+		images[i] = models.TrackedImage{URL: url, UnreferencedAt: &at, CreatedAt: at}
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url"}},
+		DoUpdates: clause.AssignmentColumns([]string{"unreferenced_at"}),
+	}).Create(&images).Error
+}
+
+// UntrackImages stops tracking images deleted from the image service.
+func (r *imageRepository) UntrackImages(urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return r.db.Where("url IN ?", urls).Delete(&models.TrackedImage{}).Error
+}
+
+// UnreferenceImages marks tracked images as detached from their product at
+// at. Images already unreferenced keep the earlier time.
+func (r *imageRepository) UnreferenceImages(urls []string, at time.Time) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.TrackedImage{}).
+		Where("url IN ? AND unreferenced_at IS NULL", urls).
+		Update("unreferenced_at", at).Error
+}
+
+// ReferenceImages marks tracked images as referred to by a product, which
+// also clears the failed attempts to delete them.
+func (r *imageRepository) ReferenceImages(urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.TrackedImage{}).
+		Where("url IN ?", urls).
+		Updates(map[string]interface{}{"unreferenced_at": nil, "delete_attempts": 0, "next_delete_at": nil}).Error
+}
+
+// GetUnreferencedImages retrieves up to limit tracked images unreferenced
+// since before whose delete is due at now, the least attempted and then the
+// oldest first.
+func (r *imageRepository) GetUnreferencedImages(before time.Time, now time.Time, limit int) ([]models.TrackedImage, error) {
+	images := []models.TrackedImage{}
+	err := r.db.Where("unreferenced_at IS NOT NULL AND unreferenced_at <= ?", before).
+		Where("next_delete_at IS NULL OR next_delete_at <= ?", now).
+		Order("delete_attempts ASC, unreferenced_at ASC").Limit(limit).Find(&images).Error
+	return images, err
+}
+
+// DeferImageDelete records a failed attempt to delete a tracked image, with
+// the time of the next one.
+func (r *imageRepository) DeferImageDelete(image *models.TrackedImage) error {
+	return r.db.Model(&models.TrackedImage{}).
+		Where("url = ?", image.URL).
+		Updates(map[string]interface{}{"delete_attempts": image.DeleteAttempts, "next_delete_at": image.NextDeleteAt}).Error
+}
+
+// GetReferencedImageURLs returns those of urls that an image of an existing
+// product refers to, in any of its renditions.
+func (r *imageRepository) GetReferencedImageURLs(urls []string) ([]string, error) {
+	if len(urls) == 0 {
+		return []string{}, nil
+	}
+	referenced := []string{}
+	for _, column := range []string{"image", "medium_url", "thumbnail_url"} {
+		found := []string{}
+		err := r.db.Model(&models.ProductImage{}).
+			Joins("JOIN products ON products.id = product_images.product_id").
+			Where("product_images."+column+" IN ?", urls).
+			Distinct().Pluck("product_images."+column, &found).Error
+		if err != nil {
+			return nil, err
+		}
+		referenced = append(referenced, found...)
+	}
+	return referenced, nil
+}
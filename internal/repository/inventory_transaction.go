@@ -11,6 +11,7 @@ import (
 // InventoryTransactionRepository defines the interface for inventory transaction repository.
 type InventoryTransactionRepository interface {
 	Create(transaction *models.InventoryTransaction) error
+	CreateBatch(transactions []models.InventoryTransaction) error
 	GetByID(id string) (*models.InventoryTransaction, error)
 	Update(transaction *models.InventoryTransaction) error
 	Delete(id string) error
@@ -29,7 +30,28 @@ func NewInventoryTransactionRepository(db *gorm.DB) InventoryTransactionReposito
 // Create inserts a new inventory transaction into the database.
 func (r *inventoryTransactionRepository) Create(transaction *models.InventoryTransaction) error {
 	transaction.ID = uuid.New().String()
-	return r.db.Create(transaction).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+		return bumpProductCatalogVersion(tx, transaction.ProductID)
+	})
+}
+
+// CreateBatch atomically inserts several inventory transactions, such as the
+// rows a sale of a bundle writes against its components.
+func (r *inventoryTransactionRepository) CreateBatch(transactions []models.InventoryTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		productIDs := make([]string, len(transactions))
+		for i := range transactions {
+			transactions[i].ID = uuid.New().String()
+			if err := tx.Create(&transactions[i]).Error; err != nil {
+				return err
+			}
+			productIDs[i] = transactions[i].ProductID
+		}
+		return bumpProductCatalogVersion(tx, productIDs...)
+	})
 }
 
 // GetByID retrieves an inventory transaction by its ID.
@@ -43,12 +65,26 @@ func (r *inventoryTransactionRepository) GetByID(id string) (*models.InventoryTr
 
 // Update modifies an existing inventory transaction.
 func (r *inventoryTransactionRepository) Update(transaction *models.InventoryTransaction) error {
-	return r.db.Save(transaction).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(transaction).Error; err != nil {
+			return err
+		}
+		return bumpProductCatalogVersion(tx, transaction.ProductID)
+	})
 }
 
 // Delete removes an inventory transaction by its ID.
 func (r *inventoryTransactionRepository) Delete(id string) error {
-	return r.db.Delete(&models.InventoryTransaction{}, "id = ?", id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var productIDs []string
+		if err := tx.Model(&models.InventoryTransaction{}).Where("id = ?", id).Pluck("product_id", &productIDs).Error; err != nil {
+			return err
+		}
+		if err := bumpProductCatalogVersion(tx, productIDs...); err != nil {
+			return err
+		}
+		return tx.Delete(&models.InventoryTransaction{}, "id = ?", id).Error
+	})
 }
 
 // GetAllByProductID retrieves all inventory transactions for a given product ID.
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// ModifierRepository defines the interface for modifier groups and their assignment to products.
+type ModifierRepository interface {
+	Create(group *models.ModifierGroup) error
+	GetByID(id string) (*models.ModifierGroup, error)
+	GetByIDs(ids []string) ([]models.ModifierGroup, error)
+	GetAllByStoreID(storeID string) ([]models.ModifierGroup, error)
+	Update(group *models.ModifierGroup) error
+	Delete(id string) error
+	SetProductGroups(productID string, groups []models.ModifierGroup) error
+}
+
+type modifierRepository struct {
+	db *gorm.DB
+}
+
+// NewModifierRepository creates a new instance of ModifierRepository.
+func NewModifierRepository(db *gorm.DB) ModifierRepository {
+	return &modifierRepository{db: db}
+}
+
+func orderModifierGroups(db *gorm.DB) *gorm.DB {
+	return db.Order("modifier_groups.display_order ASC, modifier_groups.name ASC")
+}
+
+func orderModifierOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("modifier_options.display_order ASC, modifier_options.id ASC")
+}
+
+// Create inserts a new modifier group together with its options.
+func (r *modifierRepository) Create(group *models.ModifierGroup) error {
+	group.ID = uuid.New().String()
+	return r.db.Create(group).Error
+}
+
+// GetByID retrieves a modifier group and its options by its ID.
+func (r *modifierRepository) GetByID(id string) (*models.ModifierGroup, error) {
+	var group models.ModifierGroup
+	if err := r.db.Preload("Options", orderModifierOptions).First(&group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByIDs retrieves the modifier groups with the given IDs.
+func (r *modifierRepository) GetByIDs(ids []string) ([]models.ModifierGroup, error) {
+	var groups []models.ModifierGroup
+	if err := r.db.Where("id IN ?", ids).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetAllByStoreID retrieves the modifier groups of a store in display order.
+func (r *modifierRepository) GetAllByStoreID(storeID string) ([]models.ModifierGroup, error) {
+	var groups []models.ModifierGroup
+	tx := orderModifierGroups(r.db.Preload("Options", orderModifierOptions).Where("store_id = ?", storeID)).Find(&groups)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return groups, nil
+}
+
+// Update modifies a modifier group and replaces its options.
+func (r *modifierRepository) Update(group *models.ModifierGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Association("Options").Unscoped().Replace(group.Options); err != nil {
+			return err
+		}
+		if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(group).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, group.StoreID)
+	})
+}
+
+// Delete removes a modifier group, its options and its assignments to products.
+func (r *modifierRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpOwnerCatalogVersion(tx, &models.ModifierGroup{}, id); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM product_modifier_groups WHERE modifier_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("modifier_group_id = ?", id).Delete(&models.ModifierOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ModifierGroup{}, "id = ?", id).Error
+	})
+}
+
+// SetProductGroups replaces the modifier groups offered with a product.
+func (r *modifierRepository) SetProductGroups(productID string, groups []models.ModifierGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Product{ID: productID}).Association("ModifierGroups").Replace(groups); err != nil {
+			return err
+		}
+		return bumpProductCatalogVersion(tx, productID)
+	})
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// PricingRepository defines the interface for price rules and price history.
+type PricingRepository interface {
+	CreateRule(rule *models.PriceRule) error
+	GetRuleByID(id string) (*models.PriceRule, error)
+	GetRulesByStoreID(storeID string) ([]models.PriceRule, error)
+	GetActiveRulesByStoreIDs(storeIDs []string, at time.Time) ([]models.PriceRule, error)
+	UpdateRule(rule *models.PriceRule) error
+	DeleteRule(id string) error
+
+	CreateHistory(entries []models.PriceHistory) error
+	GetHistoryByProductID(productID string) ([]models.PriceHistory, error)
+}
+
+type pricingRepository struct {
+	db *gorm.DB
+}
+
+// NewPricingRepository creates a new instance of PricingRepository.
+func NewPricingRepository(db *gorm.DB) PricingRepository {
+	return &pricingRepository{db: db}
+}
+
+// CreateRule inserts a new price rule into the database.
+func (r *pricingRepository) CreateRule(rule *models.PriceRule) error {
+	rule.ID = uuid.New().String()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, rule.StoreID)
+	})
+}
+
+// GetRuleByID retrieves a price rule by its ID.
+func (r *pricingRepository) GetRuleByID(id string) (*models.PriceRule, error) {
+	var rule models.PriceRule
+	if err := r.db.First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetRulesByStoreID retrieves every price rule of a store, including expired ones.
+func (r *pricingRepository) GetRulesByStoreID(storeID string) ([]models.PriceRule, error) {
+	var rules []models.PriceRule
+	if err := r.db.Where("store_id = ?", storeID).Order("starts_at DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetActiveRulesByStoreIDs retrieves the price rules of the given stores that are in effect at the given time.
+func (r *pricingRepository) GetActiveRulesByStoreIDs(storeIDs []string, at time.Time) ([]models.PriceRule, error) {
+	var rules []models.PriceRule
+	if len(storeIDs) == 0 {
+		return rules, nil
+	}
+
+	tx := r.db.Where("store_id IN ?", storeIDs).
+		Where("starts_at <= ?", at).
+		Where("ends_at IS NULL OR ends_at > ?", at).
+		Find(&rules)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return rules, nil
+}
+
+// UpdateRule modifies an existing price rule.
+func (r *pricingRepository) UpdateRule(rule *models.PriceRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, rule.StoreID)
+	})
+}
+
+// DeleteRule removes a price rule by its ID.
+func (r *pricingRepository) DeleteRule(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpOwnerCatalogVersion(tx, &models.PriceRule{}, id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.PriceRule{}, "id = ?", id).Error
+	})
+}
+
+// CreateHistory appends entries to the price history.
+func (r *pricingRepository) CreateHistory(entries []models.PriceHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.Create(&entries).Error
+}
+
+// GetHistoryByProductID retrieves the price history of a product and its variants, newest first.
+func (r *pricingRepository) GetHistoryByProductID(productID string) ([]models.PriceHistory, error) {
+	var entries []models.PriceHistory
+	if err := r.db.Where("product_id = ?", productID).Order("changed_at DESC, id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
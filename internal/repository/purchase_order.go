@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// PurchaseOrderRepository defines the interface for purchase order repository.
+type PurchaseOrderRepository interface {
+	Create(order *models.PurchaseOrder) error
+	GetByID(id string) (*models.PurchaseOrder, error)
+	GetAllByStoreID(storeID string, status string) ([]models.PurchaseOrder, error)
+	Update(order *models.PurchaseOrder) error
+	UpdateStatus(order *models.PurchaseOrder) error
+	Receive(order *models.PurchaseOrder, receipts []LineReceipt, transactions []models.InventoryTransaction, at time.Time) error
+	Delete(id string) error
+}
+
+// LineReceipt is a quantity received against a line of a purchase order.
+type LineReceipt struct {
+	LineID   int
+	Quantity models.Quantity
+}
+
+// ErrPurchaseOrderNotReceivable refuses a receipt against a purchase order
+// that is no longer ORDERED or PARTIALLY_RECEIVED.
+var ErrPurchaseOrderNotReceivable = errors.New("purchase order is not open for receipts")
+
+// OverReceivedLineError refuses a receipt of more than is outstanding on a
+// line of a purchase order, as another receipt came first.
+type OverReceivedLineError struct {
+	LineID      int
+	Outstanding models.Quantity
+}
+
+func (e *OverReceivedLineError) Error() string {
+	return fmt.Sprintf("line %d has only %s units outstanding", e.LineID, e.Outstanding)
+}
+
+type purchaseOrderRepository struct {
+	db *gorm.DB
+}
+
+// NewPurchaseOrderRepository creates a new instance of PurchaseOrderRepository.
+func NewPurchaseOrderRepository(db *gorm.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: db}
+}
+
+// Create inserts a new purchase order together with its lines.
+func (r *purchaseOrderRepository) Create(order *models.PurchaseOrder) error {
+	order.ID = uuid.New().String()
+	return r.db.Create(order).Error
+}
+
+// GetByID retrieves a purchase order and its lines by its ID.
+func (r *purchaseOrderRepository) GetByID(id string) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	if err := r.db.Preload("Lines").First(&order, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetAllByStoreID retrieves the purchase orders of a store, newest first,
+// optionally restricted to a single status.
+func (r *purchaseOrderRepository) GetAllByStoreID(storeID string, status string) ([]models.PurchaseOrder, error) {
+	var orders []models.PurchaseOrder
+	tx := r.db.Preload("Lines").Where("store_id = ?", storeID)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err := tx.Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Update replaces the header fields and lines of a purchase order.
+func (r *purchaseOrderRepository) Update(order *models.PurchaseOrder) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(order).Association("Lines").Unscoped().Replace(order.Lines); err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(order).Error
+	})
+}
+
+// UpdateStatus persists the status and timestamps of a purchase order.
+func (r *purchaseOrderRepository) UpdateStatus(order *models.PurchaseOrder) error {
+	return r.db.Model(order).Select("status", "ordered_at", "received_at", "updated_at").Updates(order).Error
+}
+
+// Receive atomically adds the receipts to the received quantities of the
+// lines of a purchase order and writes the PURCHASE transactions of the
+// receipt. A line is only ever incremented up to its quantity, so concurrent
+// receipts cannot receive a line twice. The status of the order is derived
+// from its lines as they are after the receipt, and set on order along with
+// its lines.
+func (r *purchaseOrderRepository) Receive(order *models.PurchaseOrder, receipts []LineReceipt, transactions []models.InventoryTransaction, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, receipt := range receipts {
+			result := tx.Model(&models.PurchaseOrderLine{}).
+				Where("id = ? AND purchase_order_id = ? AND received_quantity + ? <= quantity", receipt.LineID, order.ID, receipt.Quantity).
+				Update("received_quantity", gorm.Expr("received_quantity + ?", receipt.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				var line models.PurchaseOrderLine
+				if err := tx.First(&line, "id = ? AND purchase_order_id = ?", receipt.LineID, order.ID).Error; err != nil {
+					return err
+				}
+				return &OverReceivedLineError{LineID: line.ID, Outstanding: line.Quantity - line.ReceivedQuantity}
+			}
+		}
+
+		var lines []models.PurchaseOrderLine
+		if err := tx.Where("purchase_order_id = ?", order.ID).Find(&lines).Error; err != nil {
+			return err
+		}
+		status, receivedAt := models.PurchaseOrderStatusReceived, &at
+		for _, line := range lines {
+			if line.ReceivedQuantity < line.Quantity {
+				status, receivedAt = models.PurchaseOrderStatusPartiallyReceived, nil
+				break
+			}
+		}
+
+		productIDs := make([]string, len(transactions))
+		for i := range transactions {
+			transactions[i].ID = uuid.New().String()
+			if err := tx.Create(&transactions[i]).Error; err != nil {
+				return err
+			}
+			productIDs[i] = transactions[i].ProductID
+		}
+		if err := bumpProductCatalogVersion(tx, productIDs...); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.PurchaseOrder{}).
+			Where("id = ? AND status IN ?", order.ID, []string{models.PurchaseOrderStatusOrdered, models.PurchaseOrderStatusPartiallyReceived}).
+			Updates(map[string]interface{}{"status": status, "received_at": receivedAt, "updated_at": at})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPurchaseOrderNotReceivable
+		}
+
+		order.Lines, order.Status, order.ReceivedAt, order.UpdatedAt = lines, status, receivedAt, at
+		return nil
+	})
+}
+
+// Delete removes a purchase order and its lines by its ID.
+func (r *purchaseOrderRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("purchase_order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.PurchaseOrder{}, "id = ?", id).Error
+	})
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SalesAggregate is the net quantity sold of a product or variant over a period.
+type SalesAggregate struct {
+	ProductID   string          `json:"product_id"`
+	VariantType string          `json:"variant_type"`
+	VariantID   int             `json:"variant_id"`
+	Quantity    models.Quantity `json:"quantity"`
+}
+
+// PurchaseCost is the unit price of the latest PURCHASE transaction of a product or variant.
+type PurchaseCost struct {
+	ProductID   string       `json:"product_id"`
+	VariantType string       `json:"variant_type"`
+	VariantID   int          `json:"variant_id"`
+	Price       models.Money `json:"price"`
+}
+
+// ReplenishmentRepository defines the interface for replenishment settings and ledger aggregates.
+type ReplenishmentRepository interface {
+	UpsertSetting(setting *models.ReplenishmentSetting) error
+	GetSettingsByStoreID(storeID string) ([]models.ReplenishmentSetting, error)
+	GetSalesByStoreID(storeID string, since time.Time) ([]SalesAggregate, error)
+	GetLastPurchaseCostsByStoreID(storeID string) ([]PurchaseCost, error)
+}
+
+type replenishmentRepository struct {
+	db *gorm.DB
+}
+
+// NewReplenishmentRepository creates a new instance of ReplenishmentRepository.
+func NewReplenishmentRepository(db *gorm.DB) ReplenishmentRepository {
+	return &replenishmentRepository{db: db}
+}
+
+// UpsertSetting creates the setting or overwrites the one stored for the same product and variant.
+func (r *replenishmentRepository) UpsertSetting(setting *models.ReplenishmentSetting) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "product_id"}, {Name: "variant_type"}, {Name: "variant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"store_id", "lead_time_days", "safety_stock_days", "review_period_days", "min_order_quantity", "updated_at",
+		}),
+	}).Create(setting).Error
+}
+
+// GetSettingsByStoreID retrieves every replenishment setting of a store.
+func (r *replenishmentRepository) GetSettingsByStoreID(storeID string) ([]models.ReplenishmentSetting, error) {
+	var settings []models.ReplenishmentSetting
+	if err := r.db.Where("store_id = ?", storeID).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetSalesByStoreID sums SALE transactions net of RETURN transactions per
+// product and variant of a store, counting only transactions created since the given time.
+func (r *replenishmentRepository) GetSalesByStoreID(storeID string, since time.Time) ([]SalesAggregate, error) {
+	var sales []SalesAggregate
+	tx := r.db.Model(&models.InventoryTransaction{}).
+		Select("inventory_transactions.product_id, inventory_transactions.variant_type, inventory_transactions.variant_id, "+
+			"SUM(CASE WHEN inventory_transactions.transaction_type = ? THEN ABS(inventory_transactions.quantity) ELSE -ABS(inventory_transactions.quantity) END) AS quantity",
+			models.TransactionTypeSale).
+		Joins("JOIN products ON products.id = inventory_transactions.product_id").
+		Where("products.store_id = ?", storeID).
+		Where("inventory_transactions.transaction_type IN ?", []string{models.TransactionTypeSale, models.TransactionTypeReturn}).
+		Where("inventory_transactions.created_at >= ?", since).
+		Group("inventory_transactions.product_id, inventory_transactions.variant_type, inventory_transactions.variant_id").
+		Scan(&sales)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return sales, nil
+}
+
+// GetLastPurchaseCostsByStoreID returns the most recent PURCHASE price per product and variant of a store.
+func (r *replenishmentRepository) GetLastPurchaseCostsByStoreID(storeID string) ([]PurchaseCost, error) {
+	var transactions []models.InventoryTransaction
+	tx := r.db.Model(&models.InventoryTransaction{}).
+		Select("inventory_transactions.*").
+		Joins("JOIN products ON products.id = inventory_transactions.product_id").
+		Where("products.store_id = ?", storeID).
+		Where("inventory_transactions.transaction_type = ?", models.TransactionTypePurchase).
+		Order("inventory_transactions.created_at DESC").
+		Find(&transactions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	seen := map[PurchaseCost]bool{}
+	costs := []PurchaseCost{}
+	for _, transaction := range transactions {
+		key := PurchaseCost{ProductID: transaction.ProductID, VariantType: transaction.VariantType, VariantID: transaction.VariantID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		key.Price = transaction.Price
+		costs = append(costs, key)
+	}
+	return costs, nil
+}
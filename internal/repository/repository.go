@@ -1,22 +1,45 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/tanush-128/openzo_backend/product/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository interface {
 	CreateProduct(Product models.Product) (models.Product, error)
 	GetProductByID(id string) (models.Product, error)
 	GetProductsByStoreID(id string) ([]models.Product, error)
-	GetPostByPincode(pincode string) ([]ProductWithStore, error)
+	GetProductsByCategoryIDs(storeID string, categoryIDs []string) ([]models.Product, error)
+	GetProductsByIDs(ids []string) ([]models.Product, error)
+	ListStoreProducts(query ProductListQuery) ([]models.Product, error)
+	ListStoreProductPrices(query ProductListQuery) ([]models.Product, error)
+	GetPostCandidatesByPincode(pincode string, at time.Time, limit int) ([]PostCandidate, error)
+	GetPostsByIDs(ids []string) ([]ProductWithStore, error)
+	GetListingsByStoreIDs(storeIDs []string, posts bool, at time.Time, after *ListingKey, limit int) ([]ProductWithStore, error)
+	GetOffersByPincodes(barcodes []string, masterProductID string, pincodes []string) ([]ProductWithStore, error)
 	UpdateProduct(Product models.Product) (models.Product, error)
-	UpdateDisplayOrder(id string, displayOrder int) error
-	ChangeProductQuantity(id string, quantity int) error
+	UpdateDisplayOrder(id string, displayOrder int, version int64) error
+	ChangeProductQuantity(id string, quantity models.Quantity, version int64) (int64, error)
 	BatchUpdateDisplayOrder(updates []models.Product) error
-	DeleteProduct(id string) error
+	DeleteProduct(id string, version int64) error
+}
+
+// StaleProductError refuses a write to a product based on a version that is
+// no longer current, as another write came first.
+type StaleProductError struct {
+	ProductID string
+	// Version is the current version of the product.
+	Version int64
+}
+
+func (e *StaleProductError) Error() string {
+	return fmt.Sprintf("product %s was changed by someone else and is now at version %d", e.ProductID, e.Version)
 }
 
 type productRepository struct {
@@ -30,20 +53,26 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 
 func (r *productRepository) CreateProduct(product models.Product) (models.Product, error) {
 	product.ID = uuid.New().String()
+	product.Version = 1
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Set default display order if not provided
+		if product.DisplayOrder == 0 {
+			var maxDisplayOrder int
+			tx.Model(&models.Product{}).
+				Where("store_id = ?", product.StoreID).
+				Select("COALESCE(MAX(display_order), 0)").
+				Row().Scan(&maxDisplayOrder)
+			product.DisplayOrder = maxDisplayOrder + 1
+		}
 
-	// Set default display order if not provided
-	if product.DisplayOrder == 0 {
-		var maxDisplayOrder int
-		r.db.Model(&models.Product{}).
-			Where("store_id = ?", product.StoreID).
-			Select("COALESCE(MAX(display_order), 0)").
-			Row().Scan(&maxDisplayOrder)
-		product.DisplayOrder = maxDisplayOrder + 1
-	}
-
-	tx := r.db.Create(&product)
-	if tx.Error != nil {
-		return models.Product{}, tx.Error
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		return bumpCatalogVersion(tx, product.StoreID)
+	})
+	if err != nil {
+		return models.Product{}, err
 	}
 
 	return product, nil
@@ -51,7 +80,9 @@ func (r *productRepository) CreateProduct(product models.Product) (models.Produc
 
 func (r *productRepository) GetProductByID(id string) (models.Product, error) {
 	var Product models.Product
-	tx := r.db.Preload("Images").Preload("SizeVariants").Preload("ColorVariants").Where("id = ?", id).First(&Product)
+	tx := r.db.Preload("Images", orderImages).Preload("SizeVariants").Preload("ColorVariants").Preload("Attributes").
+		Preload("ModifierGroups", orderModifierGroups).Preload("ModifierGroups.Options", orderModifierOptions).Preload("ComboItems").
+		Where("id = ?", id).First(&Product)
 	if tx.Error != nil {
 		return models.Product{}, tx.Error
 	}
@@ -60,25 +91,308 @@ func (r *productRepository) GetProductByID(id string) (models.Product, error) {
 }
 
 func (r *productRepository) GetProductsByStoreID(storeID string) ([]models.Product, error) {
+	return r.findStoreProducts(r.db.Where("products.store_id = ?", storeID))
+}
+
+// GetProductsByCategoryIDs retrieves the products of a store assigned to any of the given categories.
+func (r *productRepository) GetProductsByCategoryIDs(storeID string, categoryIDs []string) ([]models.Product, error) {
+	return r.findStoreProducts(r.db.Where("products.store_id = ? AND products.category_id IN ?", storeID, categoryIDs))
+}
+
+// GetProductsByIDs retrieves the given products with their stock.
+func (r *productRepository) GetProductsByIDs(ids []string) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+	return r.findStoreProducts(r.db.Where("products.id IN ?", ids))
+}
+
+// Sort keys of a store product listing. Display order is the order of the
+// store's menu, and stock is the sum of the inventory transactions of a
+// product, not of its variants. Ordering by price is left to the service, as
+// the effective price of a product depends on the price rules in effect.
+const (
+	ProductSortDisplayOrder = "display_order"
+	ProductSortName         = "name"
+	ProductSortNewest       = "newest"
+	ProductSortStock        = "stock"
+)
+
+// Stock states a store product listing can be narrowed to. Low stock
+// products are in stock, at or below their critical quantity.
+const (
+	StockStateInStock    = "in_stock"
+	StockStateLowStock   = "low_stock"
+	StockStateOutOfStock = "out_of_stock"
+)
+
+// Relations a store product listing loads on request.
+const (
+	ProductIncludeVariants       = "variants"
+	ProductIncludeImages         = "images"
+	ProductIncludeTransactions   = "transactions"
+	ProductIncludeAttributes     = "attributes"
+	ProductIncludeModifierGroups = "modifier_groups"
+)
+
+// AttributeFilter narrows a product listing to products whose attribute Key
+// equals Value, or, for NUMBER attributes, lies between Min and Max.
+type AttributeFilter struct {
+	Key   string
+	Value string
+	Min   *float64
+	Max   *float64
+}
+
+// ProductListQuery selects a window of the products of a store. Products
+// created after CreatedBefore are left out, so that the pages of a listing
+// read at different times line up. IDs, when set, narrows the listing to
+// those products.
+type ProductListQuery struct {
+	StoreID       string
+	IDs           []string
+	CategoryIDs   []string
+	Stock         string
+	Attributes    []AttributeFilter
+	Sort          string
+	Descending    bool
+	CreatedBefore time.Time
+	Offset        int
+	Limit         int
+	Include       []string
+}
+
+// productStockColumn is the stock of a product, the sum of its inventory
+// transactions. Transactions against its variants are not counted.
+const productStockColumn = "COALESCE((SELECT SUM(inventory_transactions.quantity) FROM inventory_transactions WHERE inventory_transactions.product_id = products.id AND COALESCE(inventory_transactions.variant_type, '') = ''), 0)"
+
+// variantStockExists tells whether any variant of a product has a stock
+// satisfying the condition, given on SUM(inventory_transactions.quantity).
+const variantStockExists = "EXISTS (SELECT 1 FROM inventory_transactions WHERE inventory_transactions.product_id = products.id " +
+	"AND COALESCE(inventory_transactions.variant_type, '') <> '' " +
+	"GROUP BY inventory_transactions.variant_type, inventory_transactions.variant_id HAVING %s)"
+
+// productInStockCondition holds for products with stock of their own or of
+// any of their variants, productLowStockCondition for those of them whose
+// stock, or the stock of a variant, is down to the critical quantity.
+var (
+	productInStockCondition = "(" + productStockColumn + " > 0 OR " +
+		fmt.Sprintf(variantStockExists, "SUM(inventory_transactions.quantity) > 0") + ")"
+	productLowStockCondition = "((" + productStockColumn + " > 0 AND " + productStockColumn + " <= products.critical_quantity) OR " +
+		fmt.Sprintf(variantStockExists, "SUM(inventory_transactions.quantity) > 0 AND SUM(inventory_transactions.quantity) <= products.critical_quantity") + ")"
+)
+
+type sortColumn struct {
+	expression string
+	descending bool
+}
+
+var productSortColumns = map[string][]sortColumn{
+	ProductSortDisplayOrder: {{expression: "categories.id IS NULL"}, {expression: "categories.display_order"}, {expression: "categories.name"}, {expression: "products.display_order"}},
+	ProductSortName:         {{expression: "products.name"}},
+	ProductSortNewest:       {{expression: "products.created_at", descending: true}},
+	ProductSortStock:        {{expression: productStockColumn}},
+}
+
+// ListStoreProducts retrieves a window of the products of a store with the
+// requested relations and their stock. Combo items are always loaded, as the
+// availability of bundles depends on them. Products tied on the sort key are
+// ordered by id so that windows do not overlap.
+func (r *productRepository) ListStoreProducts(query ProductListQuery) ([]models.Product, error) {
+	tx := r.storeProductsQuery(query).Select("products.*")
+
+	for _, column := range productSortColumns[query.Sort] {
+		tx = tx.Order(column.expression + sortDirection(column.descending != query.Descending))
+	}
+	tx = tx.Order("products.id" + sortDirection(query.Descending))
+
+	for _, include := range query.Include {
+		switch include {
+		case ProductIncludeVariants:
+			tx = tx.Preload("SizeVariants").Preload("ColorVariants")
+		case ProductIncludeImages:
+			tx = tx.Preload("Images", orderImages)
+		case ProductIncludeTransactions:
+			tx = tx.Preload("InventoryTransactions")
+		case ProductIncludeAttributes:
+			tx = tx.Preload("Attributes")
+		case ProductIncludeModifierGroups:
+			tx = tx.Preload("ModifierGroups", orderModifierGroups).Preload("ModifierGroups.Options", orderModifierOptions)
+		}
+	}
+
+	var products []models.Product
+	tx = tx.Preload("ComboItems").Offset(query.Offset).Limit(query.Limit).Find(&products)
+	if tx.Error != nil {
+		return []models.Product{}, tx.Error
+	}
+
+	if err := r.setStock(products); err != nil {
+		return []models.Product{}, err
+	}
+	return products, nil
+}
+
+// ListStoreProductPrices retrieves every product of a listing, whatever its
+// window, with only what its price depends on: its id, store, category, MRP
+// and discount price.
+func (r *productRepository) ListStoreProductPrices(query ProductListQuery) ([]models.Product, error) {
 	var products []models.Product
-	tx := r.db.Preload("Images").
+	tx := r.storeProductsQuery(query).
+		Select("products.id, products.store_id, products.category, " +
+			"products.mrp_amount, products.mrp_currency, products.discount_price_amount, products.discount_price_currency").
+		Find(&products)
+	if tx.Error != nil {
+		return []models.Product{}, tx.Error
+	}
+	return products, nil
+}
+
+// storeProductsQuery selects the products of a listing, filtered but neither
+// ordered nor windowed.
+func (r *productRepository) storeProductsQuery(query ProductListQuery) *gorm.DB {
+	tx := r.db.Model(&models.Product{}).
+		Joins("LEFT JOIN categories ON categories.id = products.category_id").
+		Where("products.store_id = ? AND products.created_at <= ?", query.StoreID, query.CreatedBefore)
+
+	if len(query.IDs) > 0 {
+		tx = tx.Where("products.id IN ?", query.IDs)
+	}
+	if len(query.CategoryIDs) > 0 {
+		tx = tx.Where("products.category_id IN ?", query.CategoryIDs)
+	}
+
+	switch query.Stock {
+	case StockStateInStock:
+		tx = tx.Where("products.out_of_stock = ? AND "+productInStockCondition, false)
+	case StockStateLowStock:
+		tx = tx.Where("products.out_of_stock = ? AND "+productLowStockCondition, false)
+	case StockStateOutOfStock:
+		tx = tx.Where("products.out_of_stock = ? OR NOT "+productInStockCondition, true)
+	}
+
+	for _, filter := range query.Attributes {
+		attributes := r.db.Table("product_attributes").Select("1").
+			Where("product_attributes.product_id = products.id AND product_attributes.key = ?", filter.Key)
+		if filter.Value != "" {
+			attributes = attributes.Where("LOWER(product_attributes.value) = LOWER(?)", filter.Value)
+		}
+		if filter.Min != nil {
+			attributes = attributes.Where("product_attributes.number_value >= ?", *filter.Min)
+		}
+		if filter.Max != nil {
+			attributes = attributes.Where("product_attributes.number_value <= ?", *filter.Max)
+		}
+		tx = tx.Where("EXISTS (?)", attributes)
+	}
+	return tx
+}
+
+func sortDirection(descending bool) string {
+	if descending {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// productStock is the stock of a product, or of one of its variants when
+// VariantType is set, summed from its inventory transactions.
+type productStock struct {
+	ProductID   string
+	VariantType string
+	VariantID   int
+	Stock       models.Quantity
+}
+
+// setStock sets the quantity of products and of their variants to the sum of
+// their inventory transactions, without loading the transactions.
+func (r *productRepository) setStock(products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	var stocks []productStock
+	tx := r.db.Model(&models.InventoryTransaction{}).
+		Select("product_id, COALESCE(variant_type, '') AS variant_type, COALESCE(variant_id, 0) AS variant_id, SUM(quantity) AS stock").
+		Where("product_id IN ?", ids).
+		Group("product_id, COALESCE(variant_type, ''), COALESCE(variant_id, 0)").
+		Scan(&stocks)
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	applyStock(products, stocks)
+	return nil
+}
+
+// applyStock sets the quantity of products and of their variants to their
+// stock. Transactions against a variant move the stock of that variant only,
+// not the stock of the product as a whole.
+func applyStock(products []models.Product, stocks []productStock) {
+	type variantKey struct {
+		productID   string
+		variantType string
+		variantID   int
+	}
+	stockByKey := make(map[variantKey]models.Quantity, len(stocks))
+	for _, stock := range stocks {
+		key := variantKey{stock.ProductID, stock.VariantType, stock.VariantID}
+		if stock.VariantType == "" {
+			key.variantID = 0
+		}
+		stockByKey[key] += stock.Stock
+	}
+
+	for i := range products {
+		product := &products[i]
+		product.Quantity = stockByKey[variantKey{product.ID, "", 0}]
+		for j := range product.SizeVariants {
+			product.SizeVariants[j].Quantity = stockByKey[variantKey{product.ID, models.VariantTypeSize, product.SizeVariants[j].ID}]
+		}
+		for j := range product.ColorVariants {
+			product.ColorVariants[j].Quantity = stockByKey[variantKey{product.ID, models.VariantTypeColor, product.ColorVariants[j].ID}]
+		}
+	}
+}
+
+// findStoreProducts loads the products matched by query grouped by category,
+// in the display order of the categories and then of the products. Products
+// without a category come last.
+func (r *productRepository) findStoreProducts(query *gorm.DB) ([]models.Product, error) {
+	var products []models.Product
+	tx := query.Preload("Images", orderImages).
 		Preload("SizeVariants").
 		Preload("ColorVariants").
+		Preload("Attributes").
+		Preload("ModifierGroups", orderModifierGroups).
+		Preload("ModifierGroups.Options", orderModifierOptions).
+		Preload("ComboItems").
 		Preload("InventoryTransactions").
-		Where("store_id = ?", storeID).
-		Order("category ASC, display_order ASC").
+		Select("products.*").
+		Joins("LEFT JOIN categories ON categories.id = products.category_id").
+		Order("categories.id IS NULL, categories.display_order ASC, categories.name ASC, products.display_order ASC").
 		Find(&products)
 	if tx.Error != nil {
 		return []models.Product{}, tx.Error
 	}
 
-	for i, product := range products {
-		var totalQuantity int
+	stocks := []productStock{}
+	for _, product := range products {
 		for _, transaction := range product.InventoryTransactions {
-			totalQuantity += transaction.Quantity
+			stocks = append(stocks, productStock{
+				ProductID:   transaction.ProductID,
+				VariantType: transaction.VariantType,
+				VariantID:   transaction.VariantID,
+				Stock:       transaction.Quantity,
+			})
 		}
-		products[i].Quantity = totalQuantity
 	}
+	applyStock(products, stocks)
 
 	// 	type InventoryTransaction struct {
 	// 	ID        string `json:"id" gorm:"primaryKey"`
@@ -139,8 +453,24 @@ type ProductWithStore struct {
 	StoreBasicDetails
 }
 
-func (r *productRepository) GetPostByPincode(pincode string) ([]ProductWithStore, error) {
-	var products []ProductWithStore
+// storeDetailsColumns selects the StoreBasicDetails of the joined row of the
+// local store_details replica.
+const storeDetailsColumns = "store_details.id as storee_id, store_details.name as store_name, store_details.image as store_image, store_details.address as store_address, store_details.category as store_category, store_details.sub_category as store_sub_category, store_details.description as store_description, store_details.rating as store_rating, store_details.review_count as store_review_count"
+
+// PostCandidate is the part of a post and its store needed to rank a feed.
+type PostCandidate struct {
+	ID               string
+	StoreID          string
+	CreatedAt        time.Time
+	Pinned           bool
+	StoreRating      float64
+	StoreReviewCount int
+}
+
+// GetPostCandidatesByPincode retrieves up to limit of the newest posts of
+// stores in a pincode that are live at the given time.
+func (r *productRepository) GetPostCandidatesByPincode(pincode string, at time.Time, limit int) ([]PostCandidate, error) {
+	var candidates []PostCandidate
 
 	// product of type == post is a Post
 	// product has field store_id which is the id of the store, store has field pincode
@@ -148,12 +478,38 @@ func (r *productRepository) GetPostByPincode(pincode string) ([]ProductWithStore
 
 	tx := r.db.
 		Model(&models.Product{}).
-		Select("products.*, stores.id as storee_id, stores.name as store_name, stores.image as store_image, stores.address as store_address, stores.category as store_category, stores.sub_category as store_sub_category, stores.description as store_description, stores.rating as store_rating, stores.review_count as store_review_count").
-		Preload("Images").
+		Select("products.id, products.store_id, products.created_at, products.pinned, store_details.rating as store_rating, store_details.review_count as store_review_count").
 		Where("products.type = ?", "post").
-		Joins("JOIN stores ON products.store_id = stores.id").
-		Where("stores.pincode = ? ", pincode).
+		Where("products.created_at <= ?", at).
+		Where("products.expires_at IS NULL OR products.expires_at > ?", at).
+		Joins("JOIN store_details ON products.store_id = store_details.id").
+		Where("store_details.pincode = ? ", pincode).
 		Order("products.created_at DESC").
+		Order("products.id").
+		Limit(limit).
+		Scan(&candidates)
+
+	if tx.Error != nil {
+		return []PostCandidate{}, tx.Error
+	}
+
+	return candidates, nil
+}
+
+// GetPostsByIDs retrieves posts with their images and store details. The
+// order of the result is unspecified.
+func (r *productRepository) GetPostsByIDs(ids []string) ([]ProductWithStore, error) {
+	var products []ProductWithStore
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	tx := r.db.
+		Model(&models.Product{}).
+		Select("products.*, "+storeDetailsColumns).
+		Preload("Images", orderImages).
+		Joins("LEFT JOIN store_details ON products.store_id = store_details.id").
+		Where("products.id IN ?", ids).
 		Find(&products)
 
 	if tx.Error != nil {
@@ -163,98 +519,277 @@ func (r *productRepository) GetPostByPincode(pincode string) ([]ProductWithStore
 	return products, nil
 }
 
-func (r *productRepository) DeleteProduct(id string) error {
+// ListingKey is the place of a listing within its store, newest first and
+// then by ID.
+type ListingKey struct {
+	StoreID   string
+	CreatedAt time.Time
+	ID        string
+}
 
-	tx := r.db.Where("id = ?", id).Delete(&models.ProductImage{})
-	if tx.Error != nil {
-		return tx.Error
+// GetListingsByStoreIDs retrieves, with their store details, up to limit of
+// either the posts of the given stores that are live at the given time, or
+// their products. Listings come in the order of their stores in storeIDs,
+// and within a store newest first. Listings of the store of after up to and
+// including after are left out.
+func (r *productRepository) GetListingsByStoreIDs(storeIDs []string, posts bool, at time.Time, after *ListingKey, limit int) ([]ProductWithStore, error) {
+	var products []ProductWithStore
+	if len(storeIDs) == 0 {
+		return products, nil
 	}
 
-	tx = r.db.Where("product_id = ?", id).Delete(&models.SizeVariant{})
-	if tx.Error != nil {
-		return tx.Error
+	tx := r.db.
+		Model(&models.Product{}).
+		Select("products.*, "+storeDetailsColumns).
+		Preload("Images", orderImages).
+		Joins("LEFT JOIN store_details ON products.store_id = store_details.id").
+		Where("products.store_id IN ?", storeIDs)
+	if posts {
+		tx = tx.
+			Where("products.type = ?", "post").
+			Where("products.created_at <= ?", at).
+			Where("products.expires_at IS NULL OR products.expires_at > ?", at)
+	} else {
+		tx = tx.
+			Preload("SizeVariants").
+			Preload("ColorVariants").
+			Preload("ComboItems").
+			Where("products.type IS NULL OR products.type <> ?", "post")
+	}
+	if after != nil {
+		tx = tx.Where("NOT (products.store_id = ? AND (products.created_at > ? OR (products.created_at = ? AND products.id >= ?)))",
+			after.StoreID, after.CreatedAt, after.CreatedAt, after.ID)
 	}
 
-	tx = r.db.Where("product_id = ?", id).Delete(&models.ColorVariant{})
+	order := "CASE products.store_id"
+	vars := []interface{}{}
+	for i, storeID := range storeIDs {
+		order += " WHEN ? THEN ?"
+		vars = append(vars, storeID, i)
+	}
+	order += " END, products.created_at DESC, products.id DESC"
+	tx = tx.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: vars, WithoutParentheses: true}}).
+		Limit(limit).
+		Find(&products)
+
 	if tx.Error != nil {
-		return tx.Error
+		return []ProductWithStore{}, tx.Error
 	}
 
-	var product models.Product
-	if err := r.db.Where("id = ?", id).First(&product).Error; err != nil {
-		return err
+	return products, nil
+}
+
+// GetOffersByPincodes retrieves the products of stores in the given pincodes
+// that carry one of the barcodes or are linked to the master product and have
+// stock, of their own or of a variant. Bundles are left to the stock of
+// their components.
+func (r *productRepository) GetOffersByPincodes(barcodes []string, masterProductID string, pincodes []string) ([]ProductWithStore, error) {
+	var products []ProductWithStore
+
+	match := r.db.Where("products.barcode IN ?", barcodes)
+	if masterProductID != "" {
+		match = match.Or("products.master_product_id = ?", masterProductID)
 	}
 
-	tx = r.db.Where("id = ?", id).Delete(&models.Product{})
+	tx := r.db.
+		Model(&models.Product{}).
+		Select("products.*, "+storeDetailsColumns).
+		Preload("Images", orderImages).
+		Preload("SizeVariants").
+		Preload("ColorVariants").
+		Preload("ComboItems").
+		Where(match).
+		Where("products.type IS NULL OR products.type <> ?", "post").
+		Where("products.type = ? OR "+productInStockCondition, models.ProductTypeBundle).
+		Joins("JOIN store_details ON products.store_id = store_details.id").
+		Where("store_details.pincode IN ?", pincodes).
+		Find(&products)
+
 	if tx.Error != nil {
-		return tx.Error
+		return []ProductWithStore{}, tx.Error
 	}
 
-	// Adjust the display order of remaining products
-	r.db.Exec("UPDATE products SET display_order = display_order - 1 WHERE store_id = ? AND display_order > ?", product.StoreID, product.DisplayOrder)
-	return nil
+	return products, nil
 }
 
+func (r *productRepository) DeleteProduct(id string, version int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := claimProductVersion(tx, id, version); err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.ProductImage{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.SizeVariant{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.ColorVariant{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.ProductAttribute{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("combo_product_id = ?", id).Delete(&models.ComboItem{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.ImageUpload{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Product{ID: id}).Association("ModifierGroups").Clear(); err != nil {
+			return err
+		}
+
+		var product models.Product
+		if err := tx.Where("id = ?", id).First(&product).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", id).Delete(&models.Product{}).Error; err != nil {
+			return err
+		}
+
+		// Adjust the display order of remaining products
+		tx.Exec("UPDATE products SET display_order = display_order - 1 WHERE store_id = ? AND display_order > ?", product.StoreID, product.DisplayOrder)
+		return bumpCatalogVersion(tx, product.StoreID)
+	})
+}
+
+// UpdateProduct saves a product and replaces its relations, provided
+// Product.Version is still the current version of the product. The product
+// returned has its new version.
 func (r *productRepository) UpdateProduct(Product models.Product) (models.Product, error) {
+	err := r.db.Transaction(func(db *gorm.DB) error {
+		version, err := claimProductVersion(db, Product.ID, Product.Version)
+		if err != nil {
+			return err
+		}
+		Product.Version = version
 
-	// update all images
+		// update all images
 
-	tx := r.db.Model(&Product).Association("Images").Replace(Product.Images)
-	if tx != nil {
-		return models.Product{}, tx
-	}
+		tx := db.Model(&Product).Association("Images").Replace(Product.Images)
+		if tx != nil {
+			return tx
+		}
 
-	tx = r.db.Model(&Product).Association("SizeVariants").Replace(Product.SizeVariants)
-	if tx != nil {
-		return models.Product{}, tx
-	}
+		tx = db.Model(&Product).Association("SizeVariants").Replace(Product.SizeVariants)
+		if tx != nil {
+			return tx
+		}
 
-	tx = r.db.Model(&Product).Association("ColorVariants").Replace(Product.ColorVariants)
-	if tx != nil {
-		return models.Product{}, tx
-	}
+		tx = db.Model(&Product).Association("ColorVariants").Replace(Product.ColorVariants)
+		if tx != nil {
+			return tx
+		}
+
+		tx = db.Model(&Product).Association("Attributes").Unscoped().Replace(Product.Attributes)
+		if tx != nil {
+			return tx
+		}
+
+		tx = db.Model(&Product).Association("ComboItems").Unscoped().Replace(Product.ComboItems)
+		if tx != nil {
+			return tx
+		}
 
-	tx1 := r.db.Session(&gorm.Session{FullSaveAssociations: true}).Save(&Product)
-	if tx1.Error != nil {
-		return models.Product{}, tx1.Error
+		// modifier groups are shared between products and assigned through SetModifierGroups
+		tx1 := db.Session(&gorm.Session{FullSaveAssociations: true}).Omit("ModifierGroups").Save(&Product)
+		if tx1.Error != nil {
+			return tx1.Error
+		}
+		return bumpProductCatalogVersion(db, Product.ID)
+	})
+	if err != nil {
+		return models.Product{}, err
 	}
 
 	return Product, nil
 }
 
-func (r *productRepository) UpdateDisplayOrder(id string, displayOrder int) error {
-	tx := r.db.Model(&models.Product{}).
-		Where("id = ?", id).
-		Update("display_order", displayOrder)
+func (r *productRepository) UpdateDisplayOrder(id string, displayOrder int, version int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := claimProductVersion(tx, id, version); err != nil {
+			return err
+		}
+		err := tx.Model(&models.Product{}).
+			Where("id = ?", id).
+			Update("display_order", displayOrder).Error
+		if err != nil {
+			return err
+		}
 
-	return tx.Error
+		return bumpProductCatalogVersion(tx, id)
+	})
 }
 
+// BatchUpdateDisplayOrder sets the display order of several products at once.
+// Each product is checked against its own Version, and none is updated if
+// one of them is stale.
 func (r *productRepository) BatchUpdateDisplayOrder(updates []models.Product) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		for _, product := range updates {
+		ids := make([]string, len(updates))
+		for i, product := range updates {
+			if _, err := claimProductVersion(tx, product.ID, product.Version); err != nil {
+				return err
+			}
 			if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("display_order", product.DisplayOrder).Error; err != nil {
 				return err
 			}
+			ids[i] = product.ID
 		}
-		return nil
+		return bumpProductCatalogVersion(tx, ids...)
 	})
 }
 
-func (r *productRepository) ChangeProductQuantity(id string, quantity int) error {
-	var Product models.Product
-	tx := r.db.Where("id = ?", id).First(&Product)
-	if tx.Error != nil {
-		return tx.Error
+// ChangeProductQuantity sets the stock quantity of a product and returns its
+// new version.
+func (r *productRepository) ChangeProductQuantity(id string, quantity models.Quantity, version int64) (int64, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		version, err = claimProductVersion(tx, id, version)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", id).Update("quantity", quantity).Error; err != nil {
+			return err
+		}
+
+		return bumpProductCatalogVersion(tx, id)
+	})
+	if err != nil {
+		return 0, err
 	}
+	return version, nil
+}
 
-	Product.Quantity = quantity
-	tx = r.db.Save(&Product)
-	if tx.Error != nil {
-		return tx.Error
+// claimProductVersion advances the version of a product within a write to
+// it, provided the write is based on the current version, and returns the
+// new version. Writes based on version 0 are not checked.
+func claimProductVersion(tx *gorm.DB, id string, version int64) (int64, error) {
+	query := tx.Model(&models.Product{}).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Update("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return 0, result.Error
 	}
 
-	return nil
+	var product models.Product
+	if err := tx.Select("version").Where("id = ?", id).First(&product).Error; err != nil {
+		return 0, err
+	}
+	if result.RowsAffected == 0 {
+		return 0, &StaleProductError{ProductID: id, Version: product.Version}
+	}
+	return product.Version, nil
 }
 
 // Implement other repository methods (GetProductByID, GetProductByEmail, UpdateProduct, etc.) with proper error handling
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoreRepository defines the interface for the local replica of store details.
+type StoreRepository interface {
+	UpsertStore(store *models.StoreDetails) error
+	GetStore(id string) (*models.StoreDetails, error)
+	DeleteStore(id string) error
+	GetUnreplicatedStoreIDs(now time.Time, limit int) ([]string, error)
+	RecordBackfillMiss(storeID string, cause error, retryAt func(attempts int) time.Time) error
+}
+
+type storeRepository struct {
+	db *gorm.DB
+}
+
+// NewStoreRepository creates a new instance of StoreRepository.
+func NewStoreRepository(db *gorm.DB) StoreRepository {
+	return &storeRepository{db: db}
+}
+
+// UpsertStore creates or replaces the details of a store, and forgets the
+// failures to backfill it.
+func (r *storeRepository) UpsertStore(store *models.StoreDetails) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "image", "address", "category", "sub_category",
+				"description", "rating", "review_count", "pincode", "updated_at"}),
+		}).Create(store).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.StoreBackfillMiss{}, "store_id = ?", store.ID).Error
+	})
+}
+
+// GetStore retrieves the details of a store by its ID.
+func (r *storeRepository) GetStore(id string) (*models.StoreDetails, error) {
+	var store models.StoreDetails
+	if err := r.db.First(&store, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// DeleteStore removes the details of a store.
+func (r *storeRepository) DeleteStore(id string) error {
+	return r.db.Delete(&models.StoreDetails{}, "id = ?", id).Error
+}
+
+// GetUnreplicatedStoreIDs retrieves up to limit IDs of stores that have
+// products but no details in the replica. Stores that failed to backfill are
+// left out until their retry is due at now.
+func (r *storeRepository) GetUnreplicatedStoreIDs(now time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.Product{}).
+		Distinct("products.store_id").
+		Where("products.store_id <> ''").
+		Where("NOT EXISTS (SELECT 1 FROM store_details WHERE store_details.id = products.store_id)").
+		Where("NOT EXISTS (SELECT 1 FROM store_backfill_misses WHERE store_backfill_misses.store_id = products.store_id AND store_backfill_misses.retry_at > ?)", now).
+		Order("products.store_id").
+		Limit(limit).
+		Pluck("products.store_id", &ids).Error
+	return ids, err
+}
+
+// RecordBackfillMiss atomically counts a failure to backfill a store, and
+// defers the store to the time retryAt gives for the number of failures.
+func (r *storeRepository) RecordBackfillMiss(storeID string, cause error, retryAt func(attempts int) time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var misses []models.StoreBackfillMiss
+		if err := tx.Where("store_id = ?", storeID).Limit(1).Find(&misses).Error; err != nil {
+			return err
+		}
+		miss := models.StoreBackfillMiss{StoreID: storeID}
+		if len(misses) > 0 {
+			miss = misses[0]
+		}
+		miss.Attempts++
+		miss.Error = cause.Error()
+		miss.RetryAt = retryAt(miss.Attempts)
+		return tx.Save(&miss).Error
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+)
+
+// SupplierRepository defines the interface for supplier repository.
+type SupplierRepository interface {
+	Create(supplier *models.Supplier) error
+	GetByID(id string) (*models.Supplier, error)
+	GetAllByStoreID(storeID string) ([]models.Supplier, error)
+	Update(supplier *models.Supplier) error
+	Delete(id string) error
+}
+
+type supplierRepository struct {
+	db *gorm.DB
+}
+
+// NewSupplierRepository creates a new instance of SupplierRepository.
+func NewSupplierRepository(db *gorm.DB) SupplierRepository {
+	return &supplierRepository{db: db}
+}
+
+// Create inserts a new supplier into the database.
+func (r *supplierRepository) Create(supplier *models.Supplier) error {
+	supplier.ID = uuid.New().String()
+	return r.db.Create(supplier).Error
+}
+
+// GetByID retrieves a supplier by its ID.
+func (r *supplierRepository) GetByID(id string) (*models.Supplier, error) {
+	var supplier models.Supplier
+	if err := r.db.First(&supplier, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+// GetAllByStoreID retrieves all suppliers of a store.
+func (r *supplierRepository) GetAllByStoreID(storeID string) ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+	if err := r.db.Where("store_id = ?", storeID).Order("name ASC").Find(&suppliers).Error; err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+// Update modifies an existing supplier.
+func (r *supplierRepository) Update(supplier *models.Supplier) error {
+	return r.db.Save(supplier).Error
+}
+
+// Delete removes a supplier by its ID.
+func (r *supplierRepository) Delete(id string) error {
+	return r.db.Delete(&models.Supplier{}, "id = ?", id).Error
+}
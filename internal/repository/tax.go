@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaxRepository defines the interface for category tax defaults and store tax profiles.
+type TaxRepository interface {
+	UpsertCategoryDefault(taxDefault *models.CategoryTaxDefault) error
+	GetCategoryDefaults(storeID string) ([]models.CategoryTaxDefault, error)
+	GetCategoryDefault(storeID string, category string) (*models.CategoryTaxDefault, error)
+	DeleteCategoryDefault(id int) error
+
+	UpsertStoreProfile(profile *models.StoreTaxProfile) error
+	GetStoreProfile(storeID string) (*models.StoreTaxProfile, error)
+}
+
+type taxRepository struct {
+	db *gorm.DB
+}
+
+// NewTaxRepository creates a new instance of TaxRepository.
+func NewTaxRepository(db *gorm.DB) TaxRepository {
+	return &taxRepository{db: db}
+}
+
+// NormalizeCategory is the form categories are stored and matched in, so that
+// "Snacks" and "snacks " share a tax default.
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// UpsertCategoryDefault creates the default or overwrites the one stored for the same store and category.
+func (r *taxRepository) UpsertCategoryDefault(taxDefault *models.CategoryTaxDefault) error {
+	taxDefault.Category = NormalizeCategory(taxDefault.Category)
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}, {Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hsn_code", "tax_class", "tax_inclusive", "updated_at"}),
+	}).Create(taxDefault).Error
+}
+
+// GetCategoryDefaults retrieves the defaults of a store together with the global ones.
+func (r *taxRepository) GetCategoryDefaults(storeID string) ([]models.CategoryTaxDefault, error) {
+	var defaults []models.CategoryTaxDefault
+	if err := r.db.Where("store_id = ? OR store_id = ''", storeID).Order("category ASC, store_id DESC").Find(&defaults).Error; err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
+
+// GetCategoryDefault retrieves the default of a category, preferring the
+// store's own default over the global one. It returns nil when neither exists.
+func (r *taxRepository) GetCategoryDefault(storeID string, category string) (*models.CategoryTaxDefault, error) {
+	var defaults []models.CategoryTaxDefault
+	tx := r.db.Where("category = ?", NormalizeCategory(category)).
+		Where("store_id = ? OR store_id = ''", storeID).
+		Order("store_id DESC").
+		Limit(1).
+		Find(&defaults)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if len(defaults) == 0 {
+		return nil, nil
+	}
+	return &defaults[0], nil
+}
+
+// DeleteCategoryDefault removes a category tax default by its ID.
+func (r *taxRepository) DeleteCategoryDefault(id int) error {
+	return r.db.Delete(&models.CategoryTaxDefault{}, "id = ?", id).Error
+}
+
+// UpsertStoreProfile creates or overwrites the tax profile of a store.
+func (r *taxRepository) UpsertStoreProfile(profile *models.StoreTaxProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// GetStoreProfile retrieves the tax profile of a store. It returns nil when the store has none.
+func (r *taxRepository) GetStoreProfile(storeID string) (*models.StoreTaxProfile, error) {
+	var profiles []models.StoreTaxProfile
+	if err := r.db.Where("store_id = ?", storeID).Limit(1).Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	return &profiles[0], nil
+}
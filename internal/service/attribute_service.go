@@ -0,0 +1,304 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// maxCategoryDepth bounds the walk up the category tree in case of corrupt parent links.
+const maxCategoryDepth = 32
+
+// AttributeFilter narrows a product listing to products whose attribute Key
+// equals Value, or, for NUMBER attributes, lies between Min and Max.
+type AttributeFilter = repository.AttributeFilter
+
+// AttributeService defines the interface for the category attribute service.
+type AttributeService interface {
+	CreateAttributeDefinition(ctx *gin.Context, definition *models.AttributeDefinition) (*models.AttributeDefinition, error)
+	GetAttributeDefinitions(ctx *gin.Context, categoryID string) ([]models.AttributeDefinition, error)
+	UpdateAttributeDefinition(ctx *gin.Context, definition *models.AttributeDefinition) (*models.AttributeDefinition, error)
+	DeleteAttributeDefinition(ctx *gin.Context, id int) error
+}
+
+type attributeService struct {
+	repo               repository.AttributeRepository
+	categoryRepository repository.CategoryRepository
+}
+
+// NewAttributeService creates a new instance of AttributeService.
+func NewAttributeService(repo repository.AttributeRepository, categoryRepository repository.CategoryRepository) AttributeService {
+	return &attributeService{repo: repo, categoryRepository: categoryRepository}
+}
+
+// CreateAttributeDefinition validates and stores a new attribute of a category.
+func (s *attributeService) CreateAttributeDefinition(ctx *gin.Context, definition *models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	if _, err := s.categoryRepository.GetByID(definition.CategoryID); err != nil {
+		return nil, fmt.Errorf("category %s: %w", definition.CategoryID, err)
+	}
+
+	definition.Key = normalizeAttributeKey(definition.Key)
+	if !attributeKeyPattern.MatchString(definition.Key) {
+		return nil, fieldErrorf("key", "key must start with a letter and contain only letters, digits and underscores")
+	}
+	if err := validateAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// GetAttributeDefinitions retrieves the attributes products of a category
+// can have, those inherited from its ancestors first.
+func (s *attributeService) GetAttributeDefinitions(ctx *gin.Context, categoryID string) ([]models.AttributeDefinition, error) {
+	return categoryAttributeDefinitions(s.repo, s.categoryRepository, categoryID)
+}
+
+// UpdateAttributeDefinition updates the name, options, display order and
+// required flag of an attribute. Its category, key and type cannot be
+// changed, as existing product values depend on them.
+func (s *attributeService) UpdateAttributeDefinition(ctx *gin.Context, definition *models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	existing, err := s.repo.GetByID(definition.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	definition.CategoryID = existing.CategoryID
+	definition.Key = existing.Key
+	definition.Type = existing.Type
+	definition.CreatedAt = existing.CreatedAt
+	if err := validateAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	definition.UpdatedAt = time.Now()
+	if err := s.repo.Update(definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// DeleteAttributeDefinition deletes an attribute and the values products hold for it.
+func (s *attributeService) DeleteAttributeDefinition(ctx *gin.Context, id int) error {
+	return s.repo.Delete(id)
+}
+
+func validateAttributeDefinition(definition *models.AttributeDefinition) error {
+	definition.Name = strings.TrimSpace(definition.Name)
+	if definition.Name == "" {
+		return fieldErrorf("name", "name is required")
+	}
+
+	switch definition.Type {
+	case models.AttributeTypeText, models.AttributeTypeNumber, models.AttributeTypeBoolean:
+		definition.Options = nil
+	case models.AttributeTypeEnum:
+		options := []string{}
+		for _, option := range definition.Options {
+			if option = strings.TrimSpace(option); option != "" {
+				options = append(options, option)
+			}
+		}
+		if len(options) == 0 {
+			return validationf("enum attributes need at least one option")
+		}
+		definition.Options = options
+	default:
+		return fieldErrorf("type", "type must be one of %s, %s, %s or %s",
+			models.AttributeTypeText, models.AttributeTypeNumber, models.AttributeTypeEnum, models.AttributeTypeBoolean)
+	}
+	return nil
+}
+
+func normalizeAttributeKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+}
+
+// categoryLineage returns the IDs of the ancestors of a category, root first, followed by the category itself.
+func categoryLineage(repo repository.CategoryRepository, categoryID string) ([]string, error) {
+	lineage := []string{}
+	for id := categoryID; id != "" && len(lineage) < maxCategoryDepth; {
+		category, err := repo.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("category %s: %w", id, err)
+		}
+		lineage = append([]string{category.ID}, lineage...)
+		id = category.ParentID
+	}
+	return lineage, nil
+}
+
+// categoryAttributeDefinitions returns the attribute definitions of a category and its ancestors.
+func categoryAttributeDefinitions(repo repository.AttributeRepository, categoryRepository repository.CategoryRepository, categoryID string) ([]models.AttributeDefinition, error) {
+	lineage, err := categoryLineage(categoryRepository, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := repo.GetByCategoryIDs(lineage)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := map[string]int{}
+	for i, id := range lineage {
+		depth[id] = i
+	}
+	ordered := make([]models.AttributeDefinition, 0, len(definitions))
+	for level := range lineage {
+		for _, definition := range definitions {
+			if depth[definition.CategoryID] == level {
+				ordered = append(ordered, definition)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// applyProductAttributes validates the attributes of a product against the
+// definitions of its category and normalizes their values. Attributes with
+// an empty value are dropped, and every required attribute must be present.
+func applyProductAttributes(repo repository.AttributeRepository, categoryRepository repository.CategoryRepository, product *models.Product) error {
+	if product.CategoryID == "" {
+		if len(product.Attributes) > 0 {
+			return validationf("attributes need the product to have a category")
+		}
+		return nil
+	}
+
+	definitionList, err := categoryAttributeDefinitions(repo, categoryRepository, product.CategoryID)
+	if err != nil {
+		return err
+	}
+	definitions := map[string]models.AttributeDefinition{}
+	for _, definition := range definitionList {
+		// a subcategory attribute overrides an inherited one with the same key
+		definitions[definition.Key] = definition
+	}
+
+	attributes := []models.ProductAttribute{}
+	seen := map[string]bool{}
+	for _, attribute := range product.Attributes {
+		key := normalizeAttributeKey(attribute.Key)
+		definition, ok := definitions[key]
+		if !ok {
+			return validationf("attribute %s is not defined for category %s", key, product.Category)
+		}
+		if seen[key] {
+			return validationf("attribute %s is set more than once", key)
+		}
+
+		value, number, err := normalizeAttributeValue(definition, attribute.Value)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		seen[key] = true
+		attributes = append(attributes, models.ProductAttribute{
+			ProductID:   product.ID,
+			AttributeID: definition.ID,
+			Key:         key,
+			Value:       value,
+			NumberValue: number,
+		})
+	}
+
+	for _, definition := range definitions {
+		if definition.Required && !seen[definition.Key] {
+			return validationf("attribute %s is required", definition.Key)
+		}
+	}
+
+	product.Attributes = attributes
+	return nil
+}
+
+func normalizeAttributeValue(definition models.AttributeDefinition, raw string) (string, *float64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil, nil
+	}
+
+	switch definition.Type {
+	case models.AttributeTypeNumber:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return "", nil, validationf("attribute %s must be a number", definition.Key)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), &number, nil
+	case models.AttributeTypeBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", nil, validationf("attribute %s must be true or false", definition.Key)
+		}
+		return strconv.FormatBool(b), nil, nil
+	case models.AttributeTypeEnum:
+		for _, option := range definition.Options {
+			if strings.EqualFold(option, raw) {
+				return option, nil, nil
+			}
+		}
+		return "", nil, validationf("attribute %s must be one of %s", definition.Key, strings.Join(definition.Options, ", "))
+	default:
+		return raw, nil, nil
+	}
+}
+
+// filterProductsByAttributes keeps the products matching every filter.
+func filterProductsByAttributes(products []models.Product, filters []AttributeFilter) []models.Product {
+	if len(filters) == 0 {
+		return products
+	}
+
+	filtered := []models.Product{}
+	for _, product := range products {
+		matches := true
+		for _, filter := range filters {
+			if !productMatchesAttributeFilter(product, filter) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, product)
+		}
+	}
+	return filtered
+}
+
+func productMatchesAttributeFilter(product models.Product, filter AttributeFilter) bool {
+	for _, attribute := range product.Attributes {
+		if attribute.Key != normalizeAttributeKey(filter.Key) {
+			continue
+		}
+		if filter.Value != "" && !strings.EqualFold(attribute.Value, strings.TrimSpace(filter.Value)) {
+			return false
+		}
+		if filter.Min != nil || filter.Max != nil {
+			if attribute.NumberValue == nil {
+				return false
+			}
+			if filter.Min != nil && *attribute.NumberValue < *filter.Min {
+				return false
+			}
+			if filter.Max != nil && *attribute.NumberValue > *filter.Max {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
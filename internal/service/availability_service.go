@@ -0,0 +1,515 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+const (
+	scheduleTimeLayout  = "15:04"
+	exceptionDateLayout = "2006-01-02"
+)
+
+// AvailabilityService defines the interface for managing availability
+// schedules, holiday exceptions and store timezones.
+type AvailabilityService interface {
+	CreateSchedule(ctx *gin.Context, schedule *models.AvailabilitySchedule) (*models.AvailabilitySchedule, error)
+	GetScheduleByID(ctx *gin.Context, id string) (*models.AvailabilitySchedule, error)
+	GetSchedulesByStoreID(ctx *gin.Context, storeID string) ([]models.AvailabilitySchedule, error)
+	UpdateSchedule(ctx *gin.Context, schedule *models.AvailabilitySchedule) (*models.AvailabilitySchedule, error)
+	DeleteSchedule(ctx *gin.Context, id string) error
+	CreateException(ctx *gin.Context, exception *models.AvailabilityException) (*models.AvailabilityException, error)
+	GetExceptionsByStoreID(ctx *gin.Context, storeID string) ([]models.AvailabilityException, error)
+	DeleteException(ctx *gin.Context, id string) error
+	SetStoreTimezone(ctx *gin.Context, timezone *models.StoreTimezone) (*models.StoreTimezone, error)
+	GetStoreTimezone(ctx *gin.Context, storeID string) (*models.StoreTimezone, error)
+}
+
+type availabilityService struct {
+	repo               repository.AvailabilityRepository
+	productRepository  repository.ProductRepository
+	categoryRepository repository.CategoryRepository
+}
+
+// NewAvailabilityService creates a new instance of AvailabilityService.
+func NewAvailabilityService(repo repository.AvailabilityRepository, productRepository repository.ProductRepository, categoryRepository repository.CategoryRepository) AvailabilityService {
+	return &availabilityService{repo: repo, productRepository: productRepository, categoryRepository: categoryRepository}
+}
+
+// CreateSchedule validates and stores a new availability schedule.
+func (s *availabilityService) CreateSchedule(ctx *gin.Context, schedule *models.AvailabilitySchedule) (*models.AvailabilitySchedule, error) {
+	if err := s.validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetScheduleByID retrieves an availability schedule by its ID.
+func (s *availabilityService) GetScheduleByID(ctx *gin.Context, id string) (*models.AvailabilitySchedule, error) {
+	return s.repo.GetScheduleByID(id)
+}
+
+// GetSchedulesByStoreID retrieves every availability schedule of a store.
+func (s *availabilityService) GetSchedulesByStoreID(ctx *gin.Context, storeID string) ([]models.AvailabilitySchedule, error) {
+	return s.repo.GetSchedulesByStoreIDs([]string{storeID})
+}
+
+// UpdateSchedule updates an existing availability schedule. The store of a
+// schedule cannot be changed.
+func (s *availabilityService) UpdateSchedule(ctx *gin.Context, schedule *models.AvailabilitySchedule) (*models.AvailabilitySchedule, error) {
+	existing, err := s.repo.GetScheduleByID(schedule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.StoreID = existing.StoreID
+	schedule.CreatedAt = existing.CreatedAt
+	if err := s.validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	schedule.UpdatedAt = time.Now()
+	if err := s.repo.UpdateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule deletes an availability schedule by its ID.
+func (s *availabilityService) DeleteSchedule(ctx *gin.Context, id string) error {
+	return s.repo.DeleteSchedule(id)
+}
+
+// CreateException validates and stores a new availability exception.
+func (s *availabilityService) CreateException(ctx *gin.Context, exception *models.AvailabilityException) (*models.AvailabilityException, error) {
+	if exception.StoreID == "" {
+		return nil, fieldErrorf("store_id", "store_id is required")
+	}
+	if _, err := time.Parse(exceptionDateLayout, exception.Date); err != nil {
+		return nil, fieldErrorf("date", "date must be in YYYY-MM-DD format")
+	}
+	if exception.ProductID != "" && exception.CategoryID != "" {
+		return nil, validationf("an exception covers either a product or a category")
+	}
+	if err := s.validateTarget(exception.StoreID, exception.ProductID, exception.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateException(exception); err != nil {
+		return nil, err
+	}
+	return exception, nil
+}
+
+// GetExceptionsByStoreID retrieves the availability exceptions of a store.
+func (s *availabilityService) GetExceptionsByStoreID(ctx *gin.Context, storeID string) ([]models.AvailabilityException, error) {
+	return s.repo.GetExceptionsByStoreID(storeID)
+}
+
+// DeleteException deletes an availability exception by its ID.
+func (s *availabilityService) DeleteException(ctx *gin.Context, id string) error {
+	return s.repo.DeleteException(id)
+}
+
+// SetStoreTimezone sets the IANA timezone the schedules of a store are evaluated in.
+func (s *availabilityService) SetStoreTimezone(ctx *gin.Context, timezone *models.StoreTimezone) (*models.StoreTimezone, error) {
+	timezone.Timezone = strings.TrimSpace(timezone.Timezone)
+	if timezone.Timezone == "" {
+		return nil, fieldErrorf("timezone", "timezone is required")
+	}
+	if _, err := time.LoadLocation(timezone.Timezone); err != nil {
+		return nil, validationf("unknown timezone %s", timezone.Timezone)
+	}
+
+	timezone.UpdatedAt = time.Now()
+	if err := s.repo.UpsertTimezone(timezone); err != nil {
+		return nil, err
+	}
+	return timezone, nil
+}
+
+// GetStoreTimezone retrieves the timezone of a store, the default one when
+// the store has not set any.
+func (s *availabilityService) GetStoreTimezone(ctx *gin.Context, storeID string) (*models.StoreTimezone, error) {
+	timezones, err := s.repo.GetTimezones([]string{storeID})
+	if err != nil {
+		return nil, err
+	}
+	if len(timezones) == 0 {
+		return &models.StoreTimezone{StoreID: storeID, Timezone: models.DefaultStoreTimezone}, nil
+	}
+	return &timezones[0], nil
+}
+
+func (s *availabilityService) validateSchedule(schedule *models.AvailabilitySchedule) error {
+	if schedule.StoreID == "" {
+		return fieldErrorf("store_id", "store_id is required")
+	}
+	if (schedule.ProductID == "") == (schedule.CategoryID == "") {
+		return validationf("a schedule covers either a product or a category")
+	}
+	if err := s.validateTarget(schedule.StoreID, schedule.ProductID, schedule.CategoryID); err != nil {
+		return err
+	}
+
+	if _, err := time.Parse(scheduleTimeLayout, schedule.StartTime); err != nil {
+		return fieldErrorf("start_time", "start_time must be in HH:MM format")
+	}
+	if _, err := time.Parse(scheduleTimeLayout, schedule.EndTime); err != nil {
+		return fieldErrorf("end_time", "end_time must be in HH:MM format")
+	}
+
+	seen := map[int]bool{}
+	days := []int{}
+	for _, day := range schedule.Days {
+		if day < int(time.Sunday) || day > int(time.Saturday) {
+			return fieldErrorf("days", "days must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Ints(days)
+	schedule.Days = days
+	return nil
+}
+
+// validateTarget checks that the product or category a schedule or exception
+// covers is available to its store.
+func (s *availabilityService) validateTarget(storeID string, productID string, categoryID string) error {
+	if productID != "" {
+		product, err := s.productRepository.GetProductByID(productID)
+		if err != nil {
+			return err
+		}
+		if product.StoreID != storeID {
+			return validationf("product does not belong to this store")
+		}
+	}
+	if categoryID != "" {
+		category, err := s.categoryRepository.GetByID(categoryID)
+		if err != nil {
+			return fmt.Errorf("category %s: %w", categoryID, err)
+		}
+		if category.StoreID != "" && category.StoreID != storeID {
+			return validationf("category %s belongs to another store", categoryID)
+		}
+	}
+	return nil
+}
+
+// AvailabilityResolver marks products as available or not at a given time
+// from their stock, availability schedules and exceptions. Customer facing
+// reads go through it, so items disappear outside their hours on their own.
+type AvailabilityResolver interface {
+	Resolve(products []models.Product, at time.Time) error
+	ResolveProduct(product *models.Product, at time.Time) error
+}
+
+type availabilityResolver struct {
+	repo               repository.AvailabilityRepository
+	productRepository  repository.ProductRepository
+	categoryRepository repository.CategoryRepository
+}
+
+// NewAvailabilityResolver creates a new instance of AvailabilityResolver.
+func NewAvailabilityResolver(repo repository.AvailabilityRepository, productRepository repository.ProductRepository, categoryRepository repository.CategoryRepository) AvailabilityResolver {
+	return &availabilityResolver{repo: repo, productRepository: productRepository, categoryRepository: categoryRepository}
+}
+
+// Resolve sets Available and UnavailableReason on the given products in
+// place. Bundles also get their stock from the stock of their components.
+func (r *availabilityResolver) Resolve(products []models.Product, at time.Time) error {
+	if err := r.resolveBundleStock(products); err != nil {
+		return err
+	}
+
+	storeIDs := []string{}
+	seen := map[string]bool{}
+	for _, product := range products {
+		if !seen[product.StoreID] {
+			seen[product.StoreID] = true
+			storeIDs = append(storeIDs, product.StoreID)
+		}
+	}
+
+	timezones, err := r.repo.GetTimezones(storeIDs)
+	if err != nil {
+		return err
+	}
+	locations := map[string]*time.Location{}
+	for _, timezone := range timezones {
+		if location, err := time.LoadLocation(timezone.Timezone); err == nil {
+			locations[timezone.StoreID] = location
+		}
+	}
+	defaultLocation, err := time.LoadLocation(models.DefaultStoreTimezone)
+	if err != nil {
+		return err
+	}
+
+	localTimes := map[string]time.Time{}
+	dates := []string{}
+	seenDates := map[string]bool{}
+	for _, storeID := range storeIDs {
+		location, ok := locations[storeID]
+		if !ok {
+			location = defaultLocation
+		}
+		local := at.In(location)
+		localTimes[storeID] = local
+		if date := local.Format(exceptionDateLayout); !seenDates[date] {
+			seenDates[date] = true
+			dates = append(dates, date)
+		}
+	}
+
+	schedules, err := r.repo.GetSchedulesByStoreIDs(storeIDs)
+	if err != nil {
+		return err
+	}
+	exceptions, err := r.repo.GetExceptionsByDates(storeIDs, dates)
+	if err != nil {
+		return err
+	}
+
+	// category lineages are only needed for stores with category wide rules
+	categoryStores := map[string]bool{}
+	for _, schedule := range schedules {
+		if schedule.CategoryID != "" {
+			categoryStores[schedule.StoreID] = true
+		}
+	}
+	for _, exception := range exceptions {
+		if exception.CategoryID != "" {
+			categoryStores[exception.StoreID] = true
+		}
+	}
+	parents := map[string]map[string]string{}
+	for storeID := range categoryStores {
+		categories, err := r.categoryRepository.GetByStoreID(storeID)
+		if err != nil {
+			return err
+		}
+		parents[storeID] = map[string]string{}
+		for _, category := range categories {
+			parents[storeID][category.ID] = category.ParentID
+		}
+	}
+
+	for i := range products {
+		product := &products[i]
+		local := localTimes[product.StoreID]
+		lineage := categoryAncestry(parents[product.StoreID], product.CategoryID)
+		product.Available, product.UnavailableReason = productAvailability(*product, lineage, local, schedules, exceptions)
+	}
+	return nil
+}
+
+// ResolveProduct sets Available and UnavailableReason on a single product.
+func (r *availabilityResolver) ResolveProduct(product *models.Product, at time.Time) error {
+	products := []models.Product{*product}
+	if err := r.Resolve(products, at); err != nil {
+		return err
+	}
+	*product = products[0]
+	return nil
+}
+
+// resolveBundleStock sets the stock of bundles to the number of complete kits
+// the stock of their components allows. A bundle that cannot be put together
+// is out of stock.
+func (r *availabilityResolver) resolveBundleStock(products []models.Product) error {
+	ids := []string{}
+	for _, product := range products {
+		if product.Type != models.ProductTypeBundle {
+			continue
+		}
+		for _, item := range product.ComboItems {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	components, err := r.productRepository.GetProductsByIDs(ids)
+	if err != nil {
+		return err
+	}
+	byID := map[string]models.Product{}
+	for _, component := range components {
+		byID[component.ID] = component
+	}
+
+	for i := range products {
+		if products[i].Type != models.ProductTypeBundle {
+			continue
+		}
+		products[i].Quantity = bundleQuantity(products[i], byID)
+		if products[i].Quantity == 0 {
+			products[i].OutOfStock = true
+		}
+	}
+	return nil
+}
+
+// bundleQuantity returns how many whole bundles the stock of the components allows.
+func bundleQuantity(bundle models.Product, components map[string]models.Product) models.Quantity {
+	if len(bundle.ComboItems) == 0 {
+		return 0
+	}
+
+	kits := int64(-1)
+	for _, item := range bundle.ComboItems {
+		component, ok := components[item.ProductID]
+		if !ok || component.OutOfStock || item.Quantity <= 0 {
+			return 0
+		}
+		count := int64(variantStock(component, item.VariantType, item.VariantID)) / int64(item.Quantity)
+		if count < 0 {
+			count = 0
+		}
+		if kits < 0 || count < kits {
+			kits = count
+		}
+	}
+	return models.NewQuantity(kits)
+}
+
+// variantStock returns the stock of a product, or of one of its variants, as
+// loaded with their inventory transactions. The component rows written by the
+// sale of a bundle lower the stock of the variant they name.
+func variantStock(product models.Product, variantType string, variantID int) models.Quantity {
+	switch variantType {
+	case models.VariantTypeSize:
+		for _, variant := range product.SizeVariants {
+			if variant.ID == variantID {
+				return variant.Quantity
+			}
+		}
+		return 0
+	case models.VariantTypeColor:
+		for _, variant := range product.ColorVariants {
+			if variant.ID == variantID {
+				return variant.Quantity
+			}
+		}
+		return 0
+	}
+	return product.Quantity
+}
+
+// categoryAncestry returns a category followed by its ancestors, nearest first.
+func categoryAncestry(parents map[string]string, categoryID string) []string {
+	lineage := []string{}
+	for id := categoryID; id != "" && len(lineage) < maxCategoryDepth; id = parents[id] {
+		lineage = append(lineage, id)
+	}
+	return lineage
+}
+
+// productAvailability decides whether a product can be ordered at the local
+// time of its store. The schedules of the product are used when it has any,
+// otherwise those of its nearest category that has some.
+func productAvailability(product models.Product, lineage []string, local time.Time,
+	schedules []models.AvailabilitySchedule, exceptions []models.AvailabilityException,
+) (bool, string) {
+	if product.OutOfStock {
+		return false, models.UnavailableOutOfStock
+	}
+
+	inLineage := map[string]bool{}
+	for _, id := range lineage {
+		inLineage[id] = true
+	}
+
+	date := local.Format(exceptionDateLayout)
+	for _, exception := range exceptions {
+		if exception.StoreID != product.StoreID || exception.Date != date {
+			continue
+		}
+		storeWide := exception.ProductID == "" && exception.CategoryID == ""
+		if storeWide || exception.ProductID == product.ID || inLineage[exception.CategoryID] {
+			return false, models.UnavailableHoliday
+		}
+	}
+
+	applicable := []models.AvailabilitySchedule{}
+	for _, schedule := range schedules {
+		if schedule.StoreID == product.StoreID && schedule.ProductID == product.ID {
+			applicable = append(applicable, schedule)
+		}
+	}
+	for _, categoryID := range lineage {
+		if len(applicable) > 0 {
+			break
+		}
+		for _, schedule := range schedules {
+			if schedule.StoreID == product.StoreID && schedule.CategoryID == categoryID {
+				applicable = append(applicable, schedule)
+			}
+		}
+	}
+
+	if len(applicable) == 0 {
+		return true, ""
+	}
+	for _, schedule := range applicable {
+		if scheduleOpen(schedule, local) {
+			return true, ""
+		}
+	}
+	return false, models.UnavailableOutsideHours
+}
+
+// scheduleOpen reports whether the window of a schedule contains the given
+// local time. A window running past midnight belongs to the day it opens on.
+func scheduleOpen(schedule models.AvailabilitySchedule, local time.Time) bool {
+	start, err := time.Parse(scheduleTimeLayout, schedule.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(scheduleTimeLayout, schedule.EndTime)
+	if err != nil {
+		return false
+	}
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	minute := local.Hour()*60 + local.Minute()
+
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+	switch {
+	case startMinute == endMinute:
+		return scheduleOnDay(schedule, today)
+	case startMinute < endMinute:
+		return scheduleOnDay(schedule, today) && minute >= startMinute && minute < endMinute
+	default:
+		return (scheduleOnDay(schedule, today) && minute >= startMinute) ||
+			(scheduleOnDay(schedule, yesterday) && minute < endMinute)
+	}
+}
+
+func scheduleOnDay(schedule models.AvailabilitySchedule, day time.Weekday) bool {
+	if len(schedule.Days) == 0 {
+		return true
+	}
+	for _, d := range schedule.Days {
+		if d == int(day) {
+			return true
+		}
+	}
+	return false
+}
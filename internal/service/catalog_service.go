@@ -0,0 +1,258 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+const (
+	defaultCatalogSearchLimit = 20
+	maxCatalogSearchLimit     = 100
+)
+
+// CatalogService defines the interface for the master product catalog.
+type CatalogService interface {
+	CreateCatalogProduct(ctx *gin.Context, product *models.CatalogProduct) (*models.CatalogProduct, error)
+	GetCatalogProductByID(ctx *gin.Context, id string) (*models.CatalogProduct, error)
+	GetCatalogProductByBarcode(ctx *gin.Context, barcode string) (*models.CatalogProduct, error)
+	SearchCatalog(ctx *gin.Context, query string, limit int) ([]models.CatalogProduct, error)
+	UpdateCatalogProduct(ctx *gin.Context, product *models.CatalogProduct) (*models.CatalogProduct, error)
+	DeleteCatalogProduct(ctx *gin.Context, id string) error
+}
+
+type catalogService struct {
+	repo                repository.CatalogRepository
+	categoryRepository  repository.CategoryRepository
+	attributeRepository repository.AttributeRepository
+}
+
+// NewCatalogService creates a new instance of CatalogService.
+func NewCatalogService(repo repository.CatalogRepository, categoryRepository repository.CategoryRepository, attributeRepository repository.AttributeRepository) CatalogService {
+	return &catalogService{repo: repo, categoryRepository: categoryRepository, attributeRepository: attributeRepository}
+}
+
+// CreateCatalogProduct validates and adds a product to the master catalog.
+// Every barcode can be in the catalog only once.
+func (s *catalogService) CreateCatalogProduct(ctx *gin.Context, product *models.CatalogProduct) (*models.CatalogProduct, error) {
+	if err := s.validateCatalogProduct(product); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByBarcode(product.Barcode)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, conflictf("barcode %s is already in the catalog as %s", product.Barcode, existing.ID)
+	}
+
+	if err := s.repo.Create(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// GetCatalogProductByID retrieves a master catalog entry by its ID.
+func (s *catalogService) GetCatalogProductByID(ctx *gin.Context, id string) (*models.CatalogProduct, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetCatalogProductByBarcode looks up a scanned barcode in the master catalog,
+// so that a store can list the product with its fields pre-filled.
+func (s *catalogService) GetCatalogProductByBarcode(ctx *gin.Context, barcode string) (*models.CatalogProduct, error) {
+	gtin, err := models.NormalizeGTIN(barcode)
+	if err != nil {
+		return nil, invalidf("%v", err)
+	}
+
+	product, err := s.repo.GetByBarcode(gtin)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, notFoundf("barcode %s is not in the catalog", barcode)
+	}
+	return product, nil
+}
+
+// SearchCatalog retrieves the master catalog entries matching a name or brand.
+func (s *catalogService) SearchCatalog(ctx *gin.Context, query string, limit int) ([]models.CatalogProduct, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, invalidf("query is required")
+	}
+	if limit <= 0 {
+		limit = defaultCatalogSearchLimit
+	}
+	if limit > maxCatalogSearchLimit {
+		limit = maxCatalogSearchLimit
+	}
+	return s.repo.Search(query, limit)
+}
+
+// UpdateCatalogProduct updates a master catalog entry. Store products linked
+// to it keep their own copy of the fields.
+func (s *catalogService) UpdateCatalogProduct(ctx *gin.Context, product *models.CatalogProduct) (*models.CatalogProduct, error) {
+	existing, err := s.repo.GetByID(product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	product.CreatedAt = existing.CreatedAt
+	if err := s.validateCatalogProduct(product); err != nil {
+		return nil, err
+	}
+	if product.Barcode != existing.Barcode {
+		other, err := s.repo.GetByBarcode(product.Barcode)
+		if err != nil {
+			return nil, err
+		}
+		if other != nil {
+			return nil, conflictf("barcode %s is already in the catalog as %s", product.Barcode, other.ID)
+		}
+	}
+
+	product.UpdatedAt = time.Now()
+	if err := s.repo.Update(product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// DeleteCatalogProduct removes an entry from the master catalog. Store
+// products linked to it are kept and unlinked.
+func (s *catalogService) DeleteCatalogProduct(ctx *gin.Context, id string) error {
+	return s.repo.Delete(id)
+}
+
+func (s *catalogService) validateCatalogProduct(product *models.CatalogProduct) error {
+	gtin, err := models.NormalizeGTIN(product.Barcode)
+	if err != nil {
+		return fieldErrorf("barcode", "%v", err)
+	}
+	product.Barcode = gtin
+
+	product.Name = strings.TrimSpace(product.Name)
+	if product.Name == "" {
+		return fieldErrorf("name", "name is required")
+	}
+	if product.MRP.Amount < 0 {
+		return fieldErrorf("mrp", "mrp must not be negative")
+	}
+	if err := ValidateTaxClass(product.TaxClass); err != nil {
+		return err
+	}
+	unit, err := normalizeQuantityUnit(product.QuantityUnit)
+	if err != nil {
+		return err
+	}
+	product.QuantityUnit = unit
+
+	images := []string{}
+	for _, image := range product.Images {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	product.Images = images
+
+	// the catalog only uses the global taxonomy, as it is shared by every store
+	draft := models.Product{CategoryID: product.CategoryID, Category: product.Category, Attributes: product.Attributes}
+	if draft.CategoryID != "" {
+		category, err := s.categoryRepository.GetByID(draft.CategoryID)
+		if err != nil {
+			return fmt.Errorf("category %s: %w", draft.CategoryID, err)
+		}
+		if category.StoreID != "" {
+			return validationf("category %s is a store category", category.ID)
+		}
+		draft.Category = category.Name
+	} else if name := strings.TrimSpace(draft.Category); name != "" {
+		category, err := s.categoryRepository.GetBySlug("", repository.NormalizeCategory(name))
+		if err != nil {
+			return err
+		}
+		draft.Category = name
+		if category != nil {
+			draft.CategoryID, draft.Category = category.ID, category.Name
+		}
+	}
+	if err := applyProductAttributes(s.attributeRepository, s.categoryRepository, &draft); err != nil {
+		return err
+	}
+	product.CategoryID = draft.CategoryID
+	product.Category = draft.Category
+	product.Attributes = draft.Attributes
+	return nil
+}
+
+// linkMasterProduct links a store product to its master catalog entry: the
+// one named by MasterProductID, or else the one with the same barcode. A
+// product linked by ID without a barcode takes the barcode of the entry.
+// Barcodes that are not GTINs, such as store internal codes, are left unlinked.
+func linkMasterProduct(repo repository.CatalogRepository, product *models.Product) (*models.CatalogProduct, error) {
+	if product.MasterProductID != "" {
+		master, err := repo.GetByID(product.MasterProductID)
+		if err != nil {
+			return nil, fmt.Errorf("master product %s: %w", product.MasterProductID, err)
+		}
+		if strings.TrimSpace(product.Barcode) == "" {
+			product.Barcode = master.Barcode
+		} else if gtin, err := models.NormalizeGTIN(product.Barcode); err != nil || gtin != master.Barcode {
+			return nil, validationf("barcode %s does not match master product %s", product.Barcode, master.ID)
+		}
+		return master, nil
+	}
+
+	gtin, err := models.NormalizeGTIN(product.Barcode)
+	if err != nil {
+		return nil, nil
+	}
+	master, err := repo.GetByBarcode(gtin)
+	if err != nil || master == nil {
+		return nil, err
+	}
+	product.MasterProductID = master.ID
+	return master, nil
+}
+
+// fillFromMasterProduct copies the fields of a master catalog entry that a new
+// store product leaves empty. Attributes are only copied together with the
+// category they belong to.
+func fillFromMasterProduct(product *models.Product, master *models.CatalogProduct) {
+	if strings.TrimSpace(product.Name) == "" {
+		product.Name = master.Name
+	}
+	if product.Brand == "" {
+		product.Brand = master.Brand
+	}
+	if product.Description == "" {
+		product.Description = master.Description
+	}
+	if product.QuantityUnit == "" {
+		product.QuantityUnit = master.QuantityUnit
+	}
+	if product.MRP.Amount == 0 {
+		product.MRP = master.MRP
+	}
+	if product.HSNCode == "" {
+		product.HSNCode = master.HSNCode
+	}
+	if product.TaxClass == "" {
+		product.TaxClass = master.TaxClass
+	}
+	if product.CategoryID == "" && strings.TrimSpace(product.Category) == "" {
+		product.CategoryID = master.CategoryID
+		product.Category = master.Category
+		if len(product.Attributes) == 0 {
+			for _, attribute := range master.Attributes {
+				product.Attributes = append(product.Attributes, models.ProductAttribute{Key: attribute.Key, Value: attribute.Value})
+			}
+		}
+	}
+}
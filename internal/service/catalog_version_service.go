@@ -0,0 +1,170 @@
+package service
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// CatalogState identifies what the products of a store read as. Version
+// counts the changes to the catalog of the store. ModifiedAt is the time of
+// the last change, or of the last start or end of a price rule, availability
+// window or holiday of the store when that came later, since those change
+// prices and availability without a write. ModifiedAt is zero for a store
+// that never changed. ProductVersion is the version of the product a state
+// was read for, zero for the state of a whole store.
+type CatalogState struct {
+	StoreID        string
+	Version        int64
+	ModifiedAt     time.Time
+	ProductVersion int64
+}
+
+// CatalogVersionService defines the interface for reading the catalog state
+// of stores, to validate cached product reads.
+type CatalogVersionService interface {
+	GetStoreCatalogState(ctx *gin.Context, storeID string) (*CatalogState, error)
+	GetProductCatalogState(ctx *gin.Context, productID string) (*CatalogState, error)
+}
+
+type catalogVersionService struct {
+	repo                   repository.CatalogVersionRepository
+	pricingRepository      repository.PricingRepository
+	availabilityRepository repository.AvailabilityRepository
+}
+
+// NewCatalogVersionService creates a new instance of CatalogVersionService.
+func NewCatalogVersionService(repo repository.CatalogVersionRepository, pricingRepository repository.PricingRepository, availabilityRepository repository.AvailabilityRepository) CatalogVersionService {
+	return &catalogVersionService{repo: repo, pricingRepository: pricingRepository, availabilityRepository: availabilityRepository}
+}
+
+// GetStoreCatalogState retrieves the current catalog state of a store.
+func (s *catalogVersionService) GetStoreCatalogState(ctx *gin.Context, storeID string) (*CatalogState, error) {
+	version, err := s.repo.GetByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.catalogState(version, time.Now())
+}
+
+// GetProductCatalogState retrieves the current catalog state of the store
+// of a product, along with the version of the product.
+func (s *catalogVersionService) GetProductCatalogState(ctx *gin.Context, productID string) (*CatalogState, error) {
+	version, productVersion, err := s.repo.GetByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.catalogState(version, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	state.ProductVersion = productVersion
+	return state, nil
+}
+
+func (s *catalogVersionService) catalogState(version *models.StoreCatalogVersion, now time.Time) (*CatalogState, error) {
+	state := &CatalogState{StoreID: version.StoreID, Version: version.Version, ModifiedAt: version.UpdatedAt}
+	passed := func(at time.Time) {
+		if !at.After(now) && at.After(state.ModifiedAt) {
+			state.ModifiedAt = at
+		}
+	}
+
+	rules, err := s.pricingRepository.GetRulesByStoreID(version.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		passed(rule.StartsAt)
+		if rule.EndsAt != nil {
+			passed(*rule.EndsAt)
+		}
+	}
+
+	location, err := s.storeLocation(version.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	local := now.In(location)
+
+	schedules, err := s.availabilityRepository.GetSchedulesByStoreIDs([]string{version.StoreID})
+	if err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		for _, boundary := range scheduleBoundaries(schedule, local) {
+			passed(boundary)
+		}
+	}
+
+	exceptions, err := s.availabilityRepository.GetExceptionsByStoreID(version.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		date, err := time.ParseInLocation(exceptionDateLayout, exception.Date, location)
+		if err != nil {
+			continue
+		}
+		passed(date)
+		passed(date.AddDate(0, 0, 1))
+	}
+
+	return state, nil
+}
+
+// storeLocation returns the timezone availability schedules of a store are
+// evaluated in.
+func (s *catalogVersionService) storeLocation(storeID string) (*time.Location, error) {
+	timezones, err := s.availabilityRepository.GetTimezones([]string{storeID})
+	if err != nil {
+		return nil, err
+	}
+	for _, timezone := range timezones {
+		if location, err := time.LoadLocation(timezone.Timezone); err == nil {
+			return location, nil
+		}
+	}
+	return time.LoadLocation(models.DefaultStoreTimezone)
+}
+
+// scheduleBoundaries returns the times the window of a schedule opens and
+// closes on the days of the week up to the given local time. Looking a week
+// back covers schedules open on a single day of the week, including windows
+// that run past midnight.
+func scheduleBoundaries(schedule models.AvailabilitySchedule, local time.Time) []time.Time {
+	start, err := time.Parse(scheduleTimeLayout, schedule.StartTime)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(scheduleTimeLayout, schedule.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	boundaries := []time.Time{}
+	for days := -7; days <= 0; days++ {
+		day := local.AddDate(0, 0, days)
+		if !scheduleOnDay(schedule, day.Weekday()) {
+			continue
+		}
+		at := func(clock time.Time) time.Time {
+			return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, local.Location())
+		}
+
+		opens, closes := at(start), at(end)
+		switch {
+		case start.Equal(end):
+			// open the whole day
+			opens = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, local.Location())
+			closes = opens.AddDate(0, 0, 1)
+		case end.Before(start):
+			// running past midnight
+			closes = closes.AddDate(0, 0, 1)
+		}
+		boundaries = append(boundaries, opens, closes)
+	}
+	return boundaries
+}
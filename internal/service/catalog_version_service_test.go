@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestCatalogStateBoundaries checks that the catalog state of a store moves
+// on when a price rule, an availability window or a holiday starts or ends,
+// and stays put in between.
+func TestCatalogStateBoundaries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:catalog_state?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&models.StoreCatalogVersion{}, &models.PriceRule{}, &models.AvailabilitySchedule{},
+		&models.AvailabilityException{}, &models.StoreTimezone{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Monday 2 March 2026 in the store's timezone
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, kolkata)
+	}
+	ruleEnds, later := at(2, 11, 0), at(10, 0, 0)
+	for _, row := range []interface{}{
+		&models.StoreTimezone{StoreID: "s1", Timezone: "Asia/Kolkata"},
+		&models.PriceRule{ID: "r1", StoreID: "s1", Scope: models.PriceRuleScopeStore, DiscountType: models.DiscountTypePercentage,
+			Value: 10, StartsAt: at(2, 9, 0), EndsAt: &ruleEnds},
+		&models.PriceRule{ID: "r2", StoreID: "s1", Scope: models.PriceRuleScopeStore, DiscountType: models.DiscountTypePercentage,
+			Value: 10, StartsAt: later},
+		&models.PriceRule{ID: "r3", StoreID: "s2", Scope: models.PriceRuleScopeStore, DiscountType: models.DiscountTypePercentage,
+			Value: 10, StartsAt: at(2, 10, 0)},
+		&models.AvailabilitySchedule{ID: "w1", StoreID: "s1", Days: []int{int(time.Monday)}, StartTime: "14:00", EndTime: "16:00"},
+		&models.AvailabilityException{ID: "h1", StoreID: "s1", Date: "2026-03-03", Name: "Holi"},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &catalogVersionService{
+		repo:                   repository.NewCatalogVersionRepository(db),
+		pricingRepository:      repository.NewPricingRepository(db),
+		availabilityRepository: repository.NewAvailabilityRepository(db),
+	}
+	version := &models.StoreCatalogVersion{StoreID: "s1", Version: 3, UpdatedAt: at(2, 6, 0)}
+
+	for _, tt := range []struct {
+		now, modifiedAt time.Time
+	}{
+		{at(2, 7, 0), at(2, 6, 0)},
+		{at(2, 9, 30), at(2, 9, 0)},   // the price rule started
+		{at(2, 11, 30), at(2, 11, 0)}, // and ended
+		{at(2, 14, 30), at(2, 14, 0)}, // the window opened
+		{at(2, 15, 59), at(2, 14, 0)},
+		{at(2, 16, 30), at(2, 16, 0)}, // and closed
+		{at(3, 1, 0), at(3, 0, 0)},    // the holiday began
+		{at(4, 1, 0), at(4, 0, 0)},    // and ended
+		{at(9, 13, 0), at(4, 0, 0)},
+		{at(9, 14, 0), at(9, 14, 0)}, // the window opened a week later
+	} {
+		state, err := s.catalogState(version, tt.now.UTC())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !state.ModifiedAt.Equal(tt.modifiedAt) || state.Version != 3 {
+			t.Errorf("at %s: modified at %s, version %d, want %s", tt.now, state.ModifiedAt.In(kolkata), state.Version, tt.modifiedAt)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// CategoryService defines the interface for the category tree service.
+type CategoryService interface {
+	CreateCategory(ctx *gin.Context, category *models.Category) (*models.Category, error)
+	GetCategoryByID(ctx *gin.Context, id string) (*models.Category, error)
+	GetCategoryTree(ctx *gin.Context, storeID string) ([]models.Category, error)
+	UpdateCategory(ctx *gin.Context, category *models.Category) (*models.Category, error)
+	DeleteCategory(ctx *gin.Context, id string) error
+	GetProductsByCategory(ctx *gin.Context, storeID string, categoryID string, filters []AttributeFilter) ([]models.Product, error)
+}
+
+type categoryService struct {
+	repo                 repository.CategoryRepository
+	productRepository    repository.ProductRepository
+	priceResolver        PriceResolver
+	availabilityResolver AvailabilityResolver
+}
+
+// NewCategoryService creates a new instance of CategoryService.
+func NewCategoryService(repo repository.CategoryRepository, productRepository repository.ProductRepository, priceResolver PriceResolver, availabilityResolver AvailabilityResolver) CategoryService {
+	return &categoryService{repo: repo, productRepository: productRepository, priceResolver: priceResolver, availabilityResolver: availabilityResolver}
+}
+
+// CreateCategory creates a category. A category without a store_id is added
+// to the global taxonomy.
+func (s *categoryService) CreateCategory(ctx *gin.Context, category *models.Category) (*models.Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return nil, fieldErrorf("name", "name is required")
+	}
+	if err := s.validateParent(category); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// GetCategoryByID retrieves a category by its ID.
+func (s *categoryService) GetCategoryByID(ctx *gin.Context, id string) (*models.Category, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetCategoryTree retrieves the global taxonomy and the custom categories of
+// a store as a tree, each level in display order.
+func (s *categoryService) GetCategoryTree(ctx *gin.Context, storeID string) ([]models.Category, error) {
+	categories, err := s.repo.GetByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(categories), nil
+}
+
+// UpdateCategory updates the name, icon, display order and parent of a
+// category. The store of a category cannot be changed.
+func (s *categoryService) UpdateCategory(ctx *gin.Context, category *models.Category) (*models.Category, error) {
+	existing, err := s.repo.GetByID(category.ID)
+	if err != nil {
+		return nil, err
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return nil, fieldErrorf("name", "name is required")
+	}
+
+	category.StoreID = existing.StoreID
+	category.CreatedAt = existing.CreatedAt
+	if err := s.validateParent(category); err != nil {
+		return nil, err
+	}
+
+	if category.ParentID != "" {
+		categories, err := s.repo.GetByStoreID(category.StoreID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range descendantCategoryIDs(categories, category.ID) {
+			if id == category.ParentID {
+				return nil, validationf("a category cannot be moved under itself or one of its subcategories")
+			}
+		}
+	}
+
+	category.UpdatedAt = time.Now()
+	if err := s.repo.Update(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// validateParent checks that the parent of a category exists and is visible
+// to its store. Global categories can only be nested under global categories.
+func (s *categoryService) validateParent(category *models.Category) error {
+	if category.ParentID == "" {
+		return nil
+	}
+
+	parent, err := s.repo.GetByID(category.ParentID)
+	if err != nil {
+		return fmt.Errorf("parent category %s: %w", category.ParentID, err)
+	}
+	if parent.StoreID != "" && parent.StoreID != category.StoreID {
+		return validationf("parent category %s belongs to another store", parent.ID)
+	}
+	return nil
+}
+
+// DeleteCategory deletes a category that has neither subcategories nor products.
+func (s *categoryService) DeleteCategory(ctx *gin.Context, id string) error {
+	children, err := s.repo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return conflictf("category %s still has %d subcategories", id, children)
+	}
+
+	products, err := s.repo.CountProducts(id)
+	if err != nil {
+		return err
+	}
+	if products > 0 {
+		return conflictf("category %s still has %d products", id, products)
+	}
+
+	return s.repo.Delete(id)
+}
+
+// GetProductsByCategory retrieves the products of a store in a category and
+// all of its subcategories that match the attribute filters.
+func (s *categoryService) GetProductsByCategory(ctx *gin.Context, storeID string, categoryID string, filters []AttributeFilter) ([]models.Product, error) {
+	categories, err := s.repo.GetByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := descendantCategoryIDs(categories, categoryID)
+	if len(categoryIDs) == 0 {
+		return nil, validationf("category %s is not available to store %s", categoryID, storeID)
+	}
+
+	products, err := s.productRepository.GetProductsByCategoryIDs(storeID, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	products = filterProductsByAttributes(products, filters)
+	if err := s.priceResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// buildCategoryTree nests categories under their parents. Categories whose
+// parent is not in the list are returned as roots.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	known := map[string]bool{}
+	children := map[string][]models.Category{}
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+	for _, category := range categories {
+		parentID := category.ParentID
+		if !known[parentID] {
+			parentID = ""
+		}
+		children[parentID] = append(children[parentID], category)
+	}
+
+	var attach func(parentID string) []models.Category
+	attach = func(parentID string) []models.Category {
+		nodes := children[parentID]
+		for i := range nodes {
+			nodes[i].Children = attach(nodes[i].ID)
+		}
+		return nodes
+	}
+
+	tree := attach("")
+	if tree == nil {
+		tree = []models.Category{}
+	}
+	return tree
+}
+
+// descendantCategoryIDs returns the ID of a category followed by the IDs of
+// all categories below it. It returns nothing when the category is not in the list.
+func descendantCategoryIDs(categories []models.Category, id string) []string {
+	found := false
+	children := map[string][]string{}
+	for _, category := range categories {
+		if category.ID == id {
+			found = true
+		}
+		children[category.ParentID] = append(children[category.ParentID], category.ID)
+	}
+	if !found {
+		return nil
+	}
+
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// assignProductCategory links a product to its category. A product naming a
+// category_id must use a category available to its store. A product naming
+// only a category is matched to an existing category by slug, so "Snacks" and
+// "snacks " land in the same one, and a custom category of the store is
+// created when there is none. The category name is copied to the product.
+func assignProductCategory(repo repository.CategoryRepository, product *models.Product) error {
+	if product.CategoryID != "" {
+		category, err := repo.GetByID(product.CategoryID)
+		if err != nil {
+			return fmt.Errorf("category %s: %w", product.CategoryID, err)
+		}
+		if category.StoreID != "" && category.StoreID != product.StoreID {
+			return validationf("category %s belongs to another store", category.ID)
+		}
+		product.Category = category.Name
+		return nil
+	}
+
+	name := strings.TrimSpace(product.Category)
+	if name == "" {
+		product.Category = ""
+		return nil
+	}
+
+	category, err := repo.GetBySlug(product.StoreID, repository.NormalizeCategory(name))
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		category = &models.Category{StoreID: product.StoreID, Name: name}
+		if err := repo.Create(category); err != nil {
+			return err
+		}
+	}
+	product.CategoryID = category.ID
+	product.Category = category.Name
+	return nil
+}
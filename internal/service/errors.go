@@ -0,0 +1,54 @@
+package service
+
+import "fmt"
+
+// Kinds of the errors returned by the services. The API maps each kind to a
+// status code; an error of no kind is an internal error, except for records
+// not found by the repositories.
+const (
+	// ErrorKindInvalid is a request that can't be understood, such as a
+	// malformed cursor.
+	ErrorKindInvalid = "invalid_argument"
+	// ErrorKindNotFound is a request for something that doesn't exist.
+	ErrorKindNotFound = "not_found"
+	// ErrorKindConflict is a request that conflicts with the current state,
+	// such as editing a purchase order that was already sent.
+	ErrorKindConflict = "conflict"
+	// ErrorKindValidation is a well-formed request that breaks a rule.
+	ErrorKindValidation = "validation_failed"
+)
+
+// Error is an error of a given kind, optionally about one field of the
+// request. Current is what a conflict is with, for clients to merge their
+// changes into, such as the current state of a product edited concurrently.
+type Error struct {
+	Kind    string
+	Field   string
+	Message string
+	Current interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalidf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFoundf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func conflictf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func validationf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+// fieldErrorf is a validation error about one field of the request.
+func fieldErrorf(field, format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindValidation, Field: field, Message: fmt.Sprintf(format, args...)}
+}
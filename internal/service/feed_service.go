@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// Feed modes. Recent orders posts by creation time, ranked mixes recency with
+// the rating and review count of the store.
+const (
+	FeedModeRecent = "recent"
+	FeedModeRanked = "ranked"
+)
+
+const (
+	defaultFeedLimit       = 20
+	maxFeedLimit           = 100
+	defaultFeedPerStoreCap = 3
+
+	// maxFeedCandidates bounds the posts considered for one feed snapshot.
+	maxFeedCandidates = 1000
+
+	// feedHalfLifeHours is the age, in hours, at which recency counts for
+	// half in the ranked feed.
+	feedHalfLifeHours = 48
+	// feedReviewSaturation is the review count at which the reviews of a
+	// store count fully in the ranked feed.
+	feedReviewSaturation = 100
+)
+
+// FeedQuery asks for a page of the post feed of a pincode. A cursor from a
+// previous page carries the mode and per-store cap of its feed, which take
+// precedence over the ones of the query.
+type FeedQuery struct {
+	Pincode     string
+	Mode        string
+	Limit       int
+	Cursor      string
+	PerStoreCap int
+}
+
+// FeedPage is a page of the post feed. NextCursor is empty on the last page.
+type FeedPage struct {
+	Posts      []repository.ProductWithStore `json:"posts"`
+	NextCursor string                        `json:"next_cursor,omitempty"`
+}
+
+// feedCursor is the position in a feed. The feed is ranked as of At, so that
+// posts created while paging do not shift the pages that follow, and the
+// next page starts after the key of the last post served, so that posts
+// deleted while paging do not either. The exception is a served post deleted
+// from a store over the per-store cap, which moves a later post of the store
+// into an earlier round that is skipped.
+type feedCursor struct {
+	Mode        string    `json:"mode"`
+	PerStoreCap int       `json:"per_store"`
+	At          time.Time `json:"at"`
+	After       *feedKey  `json:"after,omitempty"`
+}
+
+// feedKey is the place of a post in a ranked feed. Posts are ordered by
+// round, pinned first, then by score and finally by ID.
+type feedKey struct {
+	Round  int     `json:"round"`
+	Pinned bool    `json:"pinned"`
+	Score  float64 `json:"score"`
+	ID     string  `json:"id"`
+}
+
+// before tells whether the post at k comes before the post at other.
+func (k feedKey) before(other feedKey) bool {
+	if k.Round != other.Round {
+		return k.Round < other.Round
+	}
+	if k.Pinned != other.Pinned {
+		return k.Pinned
+	}
+	if k.Score != other.Score {
+		return k.Score > other.Score
+	}
+	return k.ID < other.ID
+}
+
+// GetFeed retrieves a page of the post feed of a pincode. Expired posts are
+// left out. Within each round of the feed a store contributes at most
+// PerStoreCap posts, its pinned posts first, and pinned posts lead the round.
+func (s *productService) GetFeed(ctx *gin.Context, query FeedQuery) (*FeedPage, error) {
+	if strings.TrimSpace(query.Pincode) == "" {
+		return nil, invalidf("pincode is required")
+	}
+	cursor, err := newFeedCursor(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	if limit > maxFeedLimit {
+		limit = maxFeedLimit
+	}
+
+	candidates, err := s.ProductRepository.GetPostCandidatesByPincode(query.Pincode, cursor.At, maxFeedCandidates)
+	if err != nil {
+		return nil, err
+	}
+	keys := rankFeed(candidates, cursor.Mode, cursor.PerStoreCap, cursor.At)
+
+	page := &FeedPage{Posts: []repository.ProductWithStore{}}
+	start := 0
+	if cursor.After != nil {
+		start = sort.Search(len(keys), func(i int) bool { return cursor.After.before(keys[i]) })
+	}
+	if start >= len(candidates) {
+		return page, nil
+	}
+	end := start + limit
+	if end > len(candidates) {
+		end = len(candidates)
+	}
+	window := candidates[start:end]
+
+	ids := make([]string, len(window))
+	for i, candidate := range window {
+		ids[i] = candidate.ID
+	}
+	posts, err := s.ProductRepository.GetPostsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]repository.ProductWithStore{}
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	products := []models.Product{}
+	for _, id := range ids {
+		// a post deleted since the snapshot is skipped
+		if post, ok := byID[id]; ok {
+			page.Posts = append(page.Posts, post)
+			products = append(products, post.Product)
+		}
+	}
+	if err := s.priceResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+	for i := range page.Posts {
+		page.Posts[i].Product = products[i]
+	}
+
+	if end < len(candidates) {
+		next := *cursor
+		next.After = &keys[end-1]
+		page.NextCursor = next.encode()
+	}
+	return page, nil
+}
+
+// newFeedCursor returns the cursor of a query, or the start of a new feed
+// snapshot taken at now when the query has no cursor.
+func newFeedCursor(query FeedQuery, now time.Time) (*feedCursor, error) {
+	if query.Cursor != "" {
+		return decodeFeedCursor(query.Cursor)
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(query.Mode))
+	if mode == "" {
+		mode = FeedModeRecent
+	}
+	if mode != FeedModeRecent && mode != FeedModeRanked {
+		return nil, invalidf("unknown feed mode %q", query.Mode)
+	}
+	perStoreCap := query.PerStoreCap
+	if perStoreCap <= 0 {
+		perStoreCap = defaultFeedPerStoreCap
+	}
+	return &feedCursor{Mode: mode, PerStoreCap: perStoreCap, At: now.UTC()}, nil
+}
+
+func decodeFeedCursor(value string) (*feedCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalidf("invalid feed cursor")
+	}
+	var cursor feedCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalidf("invalid feed cursor")
+	}
+	if (cursor.Mode != FeedModeRecent && cursor.Mode != FeedModeRanked) || cursor.PerStoreCap <= 0 || cursor.At.IsZero() {
+		return nil, invalidf("invalid feed cursor")
+	}
+	return &cursor, nil
+}
+
+func (c feedCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// rankFeed orders the candidates of a feed and returns their keys in the
+// same order. Each store's posts are ordered pinned first and then by score,
+// and split into rounds of perStoreCap posts. The feed lists the first round
+// of every store, then the second, and so on; within a round pinned posts
+// lead, followed by the rest by score.
+func rankFeed(candidates []repository.PostCandidate, mode string, perStoreCap int, at time.Time) []feedKey {
+	keys := make(map[string]feedKey, len(candidates))
+	for _, candidate := range candidates {
+		keys[candidate.ID] = feedKey{Pinned: candidate.Pinned, Score: feedScore(candidate, mode, at), ID: candidate.ID}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return keys[candidates[i].ID].before(keys[candidates[j].ID]) })
+	seen := map[string]int{}
+	for _, candidate := range candidates {
+		key := keys[candidate.ID]
+		key.Round = seen[candidate.StoreID] / perStoreCap
+		keys[candidate.ID] = key
+		seen[candidate.StoreID]++
+	}
+	sort.Slice(candidates, func(i, j int) bool { return keys[candidates[i].ID].before(keys[candidates[j].ID]) })
+
+	ordered := make([]feedKey, len(candidates))
+	for i, candidate := range candidates {
+		ordered[i] = keys[candidate.ID]
+	}
+	return ordered
+}
+
+// feedScore is the score of a post in a feed mode. Recent scores by creation
+// time. Ranked decays with the age of the post, and boosts posts of well
+// rated stores by up to the store rating out of five, weighed by how many
+// reviews back the rating.
+func feedScore(candidate repository.PostCandidate, mode string, at time.Time) float64 {
+	if mode != FeedModeRanked {
+		return float64(candidate.CreatedAt.UnixNano())
+	}
+
+	age := at.Sub(candidate.CreatedAt).Hours()
+	if age < 0 {
+		age = 0
+	}
+	recency := math.Exp2(-age / feedHalfLifeHours)
+
+	rating := math.Max(0, math.Min(candidate.StoreRating, 5)) / 5
+	reviews := math.Min(math.Log1p(float64(max(candidate.StoreReviewCount, 0)))/math.Log1p(feedReviewSaturation), 1)
+	return recency * (1 + rating*reviews)
+}
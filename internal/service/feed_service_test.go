@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestGetFeedPages pages through a feed with pages of every size and checks
+// that each paging serves the whole feed of the snapshot once and in order,
+// across the per-store cap and a pinned post, while posts created after the
+// snapshot wait for the next one.
+func TestGetFeedPages(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:feed?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&models.Product{}, &models.ProductImage{}, &models.SizeVariant{}, &models.ColorVariant{},
+		&models.StoreDetails{}, &models.PriceRule{}, &models.Category{}, &models.AvailabilitySchedule{},
+		&models.AvailabilityException{}, &models.StoreTimezone{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	expired := base
+	rows := []interface{}{
+		&models.StoreDetails{ID: "s1", Name: "Bakery", Pincode: "560001", Rating: 5, ReviewCount: 40},
+		&models.StoreDetails{ID: "s2", Name: "Grocer", Pincode: "560001", Rating: 3},
+		&models.StoreDetails{ID: "s3", Name: "Florist", Pincode: "560001"},
+		&models.StoreDetails{ID: "s4", Name: "Elsewhere", Pincode: "560002"},
+		&models.Product{ID: "x1", StoreID: "s1", Name: "expired", Type: "post", CreatedAt: base.Add(58 * time.Minute), ExpiresAt: &expired},
+		&models.Product{ID: "x2", StoreID: "s4", Name: "other pincode", Type: "post", CreatedAt: base.Add(58 * time.Minute)},
+		&models.Product{ID: "x3", StoreID: "s1", Name: "not a post", CreatedAt: base.Add(58 * time.Minute)},
+	}
+	for id, minutes := range map[string]int{"a1": 50, "a2": 40, "a3": 30, "a4": 20, "a5": 10, "b1": 45, "b2": 35, "b3": 25, "c1": 55} {
+		storeID := map[byte]string{'a': "s1", 'b': "s2", 'c': "s3"}[id[0]]
+		rows = append(rows, &models.Product{ID: id, StoreID: storeID, Name: id, Type: "post",
+			CreatedAt: base.Add(time.Duration(minutes) * time.Minute), Pinned: id == "a5"})
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	productRepository := repository.NewProductRepository(db)
+	s := &productService{
+		ProductRepository: productRepository,
+		priceResolver:     NewPriceResolver(repository.NewPricingRepository(db)),
+		availabilityResolver: NewAvailabilityResolver(repository.NewAvailabilityRepository(db), productRepository,
+			repository.NewCategoryRepository(db)),
+	}
+
+	// two posts a store per round: the pinned a5 leads the first round
+	// although it is the oldest, and the third post of s1 and of s2 wait
+	// for the second round
+	want := "a5 c1 a1 b1 b2 a2 a3 b3 a4"
+	for limit := 1; limit <= 10; limit++ {
+		query := FeedQuery{Pincode: "560001", Limit: limit, PerStoreCap: 2}
+		var served []string
+		for pages := 0; ; pages++ {
+			if pages > 10 {
+				t.Fatalf("limit %d: served %v without an end", limit, served)
+			}
+			page, err := s.GetFeed(nil, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Posts) > limit {
+				t.Errorf("limit %d: page of %d posts", limit, len(page.Posts))
+			}
+			for _, post := range page.Posts {
+				served = append(served, post.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+
+			// posts created after the first page are not part of its
+			// snapshot, pinned or not
+			if pages == 0 {
+				for _, post := range []models.Product{
+					{ID: fmt.Sprintf("n%d", limit), StoreID: "s3", Name: "new", Type: "post", CreatedAt: time.Now()},
+					{ID: fmt.Sprintf("p%d", limit), StoreID: "s2", Name: "new pinned", Type: "post", CreatedAt: time.Now(), Pinned: true},
+				} {
+					if err := db.Create(&post).Error; err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			query.Cursor = page.NextCursor
+		}
+		if got := strings.Join(served, " "); got != want {
+			t.Errorf("limit %d: served %s, want %s", limit, got, want)
+		}
+		if err := db.Where("id IN ?", []string{fmt.Sprintf("n%d", limit), fmt.Sprintf("p%d", limit)}).Delete(&models.Product{}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new snapshot has them
+	if err := db.Create(&models.Product{ID: "p0", StoreID: "s2", Name: "new pinned", Type: "post", CreatedAt: time.Now(), Pinned: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	page, err := s.GetFeed(nil, FeedQuery{Pincode: "560001", Limit: 1, PerStoreCap: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != "p0" {
+		t.Errorf("new snapshot starts with %+v", page.Posts)
+	}
+}
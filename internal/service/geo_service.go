@@ -0,0 +1,302 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+const (
+	defaultNearbyRadiusKm = 5
+	maxNearbyRadiusKm     = 50
+	defaultNearbyLimit    = 50
+	maxNearbyLimit        = 200
+	// nearbyStoreBatch is the number of stores, nearest first, whose
+	// listings are fetched at a time.
+	nearbyStoreBatch = 50
+
+	earthRadiusKm = 6371.0
+	// kmPerDegreeLatitude is the length of one degree of latitude.
+	kmPerDegreeLatitude = 111.32
+)
+
+// NearbyQuery asks for the products or posts of stores within RadiusKm of a
+// point. Cursor continues from a previous page.
+type NearbyQuery struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+	Limit     int
+	Cursor    string
+}
+
+// NearbyPage is a page of a distance search. NextCursor is empty on the last
+// page.
+type NearbyPage struct {
+	Listings   []NearbyListing
+	NextCursor string
+}
+
+// nearbyCursor is the last listing of a page of a distance search. Stores
+// are ordered by distance and then by ID.
+type nearbyCursor struct {
+	DistanceKm float64   `json:"distance_km"`
+	StoreID    string    `json:"store_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ID         string    `json:"id"`
+}
+
+// nearbyStore is a store within the radius of a distance search.
+type nearbyStore struct {
+	ID         string
+	DistanceKm float64
+}
+
+// NearbyListing is a product or post with the distance of its store from the
+// point searched around.
+type NearbyListing struct {
+	repository.ProductWithStore
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// GeoService defines the interface for discovering products and posts by distance.
+type GeoService interface {
+	SetStoreLocation(ctx *gin.Context, location *models.StoreLocation) (*models.StoreLocation, error)
+	GetStoreLocation(ctx *gin.Context, storeID string) (*models.StoreLocation, error)
+	DeleteStoreLocation(ctx *gin.Context, storeID string) error
+	GetNearbyProducts(ctx *gin.Context, query NearbyQuery) (*NearbyPage, error)
+	GetNearbyPosts(ctx *gin.Context, query NearbyQuery) (*NearbyPage, error)
+}
+
+type geoService struct {
+	repo                 repository.GeoRepository
+	productRepository    repository.ProductRepository
+	priceResolver        PriceResolver
+	availabilityResolver AvailabilityResolver
+}
+
+// NewGeoService creates a new instance of GeoService.
+func NewGeoService(repo repository.GeoRepository, productRepository repository.ProductRepository,
+	priceResolver PriceResolver, availabilityResolver AvailabilityResolver,
+) GeoService {
+	return &geoService{
+		repo:                 repo,
+		productRepository:    productRepository,
+		priceResolver:        priceResolver,
+		availabilityResolver: availabilityResolver,
+	}
+}
+
+// SetStoreLocation creates or replaces the coordinates of a store.
+func (s *geoService) SetStoreLocation(ctx *gin.Context, location *models.StoreLocation) (*models.StoreLocation, error) {
+	if location.StoreID == "" {
+		return nil, fieldErrorf("store_id", "store_id is required")
+	}
+	if err := validateCoordinates(location.Latitude, location.Longitude); err != nil {
+		return nil, err
+	}
+	location.UpdatedAt = time.Now()
+	if err := s.repo.UpsertStoreLocation(location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+// GetStoreLocation retrieves the coordinates of a store.
+func (s *geoService) GetStoreLocation(ctx *gin.Context, storeID string) (*models.StoreLocation, error) {
+	return s.repo.GetStoreLocation(storeID)
+}
+
+// DeleteStoreLocation removes the coordinates of a store, taking its products
+// and posts out of distance searches.
+func (s *geoService) DeleteStoreLocation(ctx *gin.Context, storeID string) error {
+	return s.repo.DeleteStoreLocation(storeID)
+}
+
+// GetNearbyProducts retrieves the products of stores within the radius,
+// nearest store first.
+func (s *geoService) GetNearbyProducts(ctx *gin.Context, query NearbyQuery) (*NearbyPage, error) {
+	return s.nearby(query, false)
+}
+
+// GetNearbyPosts retrieves the live posts of stores within the radius,
+// nearest store first.
+func (s *geoService) GetNearbyPosts(ctx *gin.Context, query NearbyQuery) (*NearbyPage, error) {
+	return s.nearby(query, true)
+}
+
+// nearby lists a page of the products or posts of the stores within the
+// radius of a query. Listings of the same store keep the newest first. The
+// listings are fetched a batch of stores at a time, nearest first, until the
+// page is full.
+func (s *geoService) nearby(query NearbyQuery, posts bool) (*NearbyPage, error) {
+	if err := validateCoordinates(query.Latitude, query.Longitude); err != nil {
+		return nil, err
+	}
+	var cursor *nearbyCursor
+	if query.Cursor != "" {
+		var err error
+		if cursor, err = decodeNearbyCursor(query.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	radius := query.RadiusKm
+	if radius <= 0 {
+		radius = defaultNearbyRadiusKm
+	}
+	if radius > maxNearbyRadiusKm {
+		radius = maxNearbyRadiusKm
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultNearbyLimit
+	}
+	if limit > maxNearbyLimit {
+		limit = maxNearbyLimit
+	}
+
+	minLatitude, maxLatitude, minLongitude, maxLongitude := boundingBox(query.Latitude, query.Longitude, radius)
+	locations, err := s.repo.GetStoreLocationsInBox(minLatitude, maxLatitude, minLongitude, maxLongitude)
+	if err != nil {
+		return nil, err
+	}
+	distances := map[string]float64{}
+	stores := []nearbyStore{}
+	for _, location := range locations {
+		distance := haversineKm(query.Latitude, query.Longitude, location.Latitude, location.Longitude)
+		if distance <= radius {
+			distances[location.StoreID] = distance
+			stores = append(stores, nearbyStore{ID: location.StoreID, DistanceKm: distance})
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i].before(stores[j]) })
+
+	var after *repository.ListingKey
+	if cursor != nil {
+		// the store of the cursor is continued, the stores before it are done
+		last := nearbyStore{ID: cursor.StoreID, DistanceKm: cursor.DistanceKm}
+		start := sort.Search(len(stores), func(i int) bool { return !stores[i].before(last) })
+		stores = stores[start:]
+		after = &repository.ListingKey{StoreID: cursor.StoreID, CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+	}
+
+	now := time.Now()
+	listings := []repository.ProductWithStore{}
+	for len(stores) > 0 && len(listings) <= limit {
+		batch := stores[:min(nearbyStoreBatch, len(stores))]
+		stores = stores[len(batch):]
+		storeIDs := make([]string, len(batch))
+		for i, store := range batch {
+			storeIDs[i] = store.ID
+		}
+		found, err := s.productRepository.GetListingsByStoreIDs(storeIDs, posts, now, after, limit+1-len(listings))
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, found...)
+	}
+
+	page := &NearbyPage{}
+	if len(listings) > limit {
+		listings = listings[:limit]
+		last := listings[limit-1]
+		page.NextCursor = nearbyCursor{
+			DistanceKm: distances[last.StoreID],
+			StoreID:    last.StoreID,
+			CreatedAt:  last.CreatedAt,
+			ID:         last.ID,
+		}.encode()
+	}
+
+	products := make([]models.Product, len(listings))
+	for i := range listings {
+		products[i] = listings[i].Product
+	}
+	if err := s.priceResolver.Resolve(products, now); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(products, now); err != nil {
+		return nil, err
+	}
+
+	page.Listings = make([]NearbyListing, len(listings))
+	for i := range listings {
+		listings[i].Product = products[i]
+		page.Listings[i] = NearbyListing{
+			ProductWithStore: listings[i],
+			DistanceKm:       math.Round(distances[listings[i].StoreID]*1000) / 1000,
+		}
+	}
+	return page, nil
+}
+
+// before tells whether s is searched before other: nearest first, then by ID.
+func (s nearbyStore) before(other nearbyStore) bool {
+	if s.DistanceKm != other.DistanceKm {
+		return s.DistanceKm < other.DistanceKm
+	}
+	return s.ID < other.ID
+}
+
+func decodeNearbyCursor(value string) (*nearbyCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalidf("invalid nearby cursor")
+	}
+	var cursor nearbyCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.StoreID == "" || cursor.ID == "" {
+		return nil, invalidf("invalid nearby cursor")
+	}
+	return &cursor, nil
+}
+
+func (c nearbyCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func validateCoordinates(latitude, longitude float64) error {
+	if math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+		return fieldErrorf("latitude", "latitude must be between -90 and 90")
+	}
+	if math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+		return fieldErrorf("longitude", "longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// boundingBox returns the latitude and longitude range enclosing the circle of
+// radiusKm around a point. Near the poles, or when the circle crosses the
+// antimeridian, the box spans every longitude.
+func boundingBox(latitude, longitude, radiusKm float64) (minLatitude, maxLatitude, minLongitude, maxLongitude float64) {
+	deltaLatitude := radiusKm / kmPerDegreeLatitude
+	minLatitude = math.Max(latitude-deltaLatitude, -90)
+	maxLatitude = math.Min(latitude+deltaLatitude, 90)
+
+	cos := math.Cos(latitude * math.Pi / 180)
+	if cos < 0.01 {
+		return minLatitude, maxLatitude, -180, 180
+	}
+	deltaLongitude := radiusKm / (kmPerDegreeLatitude * cos)
+	minLongitude, maxLongitude = longitude-deltaLongitude, longitude+deltaLongitude
+	if minLongitude < -180 || maxLongitude > 180 {
+		return minLatitude, maxLatitude, -180, 180
+	}
+	return minLatitude, maxLatitude, minLongitude, maxLongitude
+}
+
+// haversineKm is the great-circle distance between two points, in kilometres.
+func haversineKm(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := math.Pi / 180
+	deltaLatitude := (latitude2 - latitude1) * toRadians
+	deltaLongitude := (longitude2 - longitude1) * toRadians
+	a := math.Sin(deltaLatitude/2)*math.Sin(deltaLatitude/2) +
+		math.Cos(latitude1*toRadians)*math.Cos(latitude2*toRadians)*math.Sin(deltaLongitude/2)*math.Sin(deltaLongitude/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(math.Min(a, 1)))
+}
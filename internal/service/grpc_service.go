@@ -2,19 +2,33 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net"
+	"strconv"
+	"time"
 
 	"github.com/tanush-128/openzo_backend/product/config"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
 	"github.com/tanush-128/openzo_backend/product/internal/pb"
 	"github.com/tanush-128/openzo_backend/product/internal/repository"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// productVersionTrailer is the trailer carrying the current version of a
+// product when a change based on another version is refused.
+const productVersionTrailer = "product-version"
+
 type Server struct {
 	pb.ProductServiceServer
 	ProductRepository repository.ProductRepository
+	PriceResolver     PriceResolver
+	TaxCalculator     TaxCalculator
 }
 
 func GrpcServer(
@@ -39,12 +53,97 @@ func GrpcServer(
 
 func (s *Server) ChangeProductQuantity(ctx context.Context, req *pb.ChangeProductQuantityRequest) (*pb.ChangeProductQuantityResponse, error) {
 	// Implement your business logic here
-	err := s.ProductRepository.ChangeProductQuantity(req.GetProductId(), int(req.GetQuantity()))
+	quantity := models.NewQuantity(int64(req.GetQuantity()))
+	if req.GetDecimalQuantity() != 0 {
+		quantity = models.QuantityFromFloat(req.GetDecimalQuantity())
+	}
+	version, err := s.ProductRepository.ChangeProductQuantity(req.GetProductId(), quantity, req.GetVersion())
+	var stale *repository.StaleProductError
+	if errors.As(err, &stale) {
+		// the current version is also sent as a trailer, for clients to
+		// merge with without parsing the message
+		grpc.SetTrailer(ctx, metadata.Pairs(productVersionTrailer, strconv.FormatInt(stale.Version, 10)))
+		return nil, status.Error(codes.Aborted, stale.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &pb.ChangeProductQuantityResponse{
-		Status: "success",
+		Status:  "success",
+		Version: version,
 	}, nil
 
 }
+
+func (s *Server) GetProductPrice(ctx context.Context, req *pb.GetProductPriceRequest) (*pb.GetProductPriceResponse, error) {
+	product, err := s.ProductRepository.GetProductByID(req.GetProductId())
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := ResolveProductPrice(s.PriceResolver, product, req.GetVariantType(), int(req.GetVariantId()), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetProductPriceResponse{
+		ProductId:           price.ProductID,
+		VariantType:         price.VariantType,
+		VariantId:           int32(price.VariantID),
+		Mrp:                 majorInt32(price.MRP),
+		DiscountPrice:       majorInt32(price.DiscountPrice),
+		ListPrice:           majorInt32(price.ListPrice),
+		EffectivePrice:      majorInt32(price.EffectivePrice),
+		PriceRuleId:         price.AppliedPriceRuleID,
+		Currency:            price.EffectivePrice.Currency,
+		MrpMinor:            price.MRP.Amount,
+		DiscountPriceMinor:  price.DiscountPrice.Amount,
+		ListPriceMinor:      price.ListPrice.Amount,
+		EffectivePriceMinor: price.EffectivePrice.Amount,
+	}, nil
+}
+
+func (s *Server) GetProductTax(ctx context.Context, req *pb.GetProductTaxRequest) (*pb.GetProductTaxResponse, error) {
+	quantity := models.NewQuantity(int64(req.GetQuantity()))
+	if req.GetDecimalQuantity() != 0 {
+		quantity = models.QuantityFromFloat(req.GetDecimalQuantity())
+	}
+
+	breakdown, err := s.TaxCalculator.Calculate(TaxRequest{
+		ProductID:        req.GetProductId(),
+		VariantType:      req.GetVariantType(),
+		VariantID:        int(req.GetVariantId()),
+		Quantity:         quantity,
+		OriginState:      req.GetOriginState(),
+		DestinationState: req.GetDestinationState(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetProductTaxResponse{
+		ProductId:       breakdown.ProductID,
+		HsnCode:         breakdown.HSNCode,
+		TaxClass:        breakdown.TaxClass,
+		RatePercent:     breakdown.RatePercent,
+		TaxInclusive:    breakdown.TaxInclusive,
+		InterState:      breakdown.InterState,
+		Quantity:        int32(math.Round(breakdown.Quantity.Float64())),
+		DecimalQuantity: breakdown.Quantity.Float64(),
+		UnitPrice:       majorInt32(breakdown.UnitPrice),
+		UnitPriceMinor:  breakdown.UnitPrice.Amount,
+		Currency:        breakdown.UnitPrice.Currency,
+		TaxableValue:    breakdown.TaxableValue.Major(),
+		Cgst:            breakdown.CGST.Major(),
+		Sgst:            breakdown.SGST.Major(),
+		Igst:            breakdown.IGST.Major(),
+		TotalTax:        breakdown.TotalTax.Major(),
+		TotalAmount:     breakdown.TotalAmount.Major(),
+	}, nil
+}
+
+// majorInt32 rounds an amount to whole major units for the deprecated integer
+// price fields, which predate amounts in minor units.
+func majorInt32(m models.Money) int32 {
+	return int32(math.Round(m.Major()))
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+)
+
+const (
+	// maxImageImportURLs bounds the image URLs imported by one request.
+	maxImageImportURLs = 20
+	imageFetchTimeout  = 30 * time.Second
+)
+
+// ImageFetcher downloads the images imported from remote URLs.
+type ImageFetcher struct {
+	client *http.Client
+}
+
+// NewImageFetcher creates an ImageFetcher using client. With a nil client, it
+// uses one that only connects to public addresses, so that an imported URL
+// can't reach into the internal network.
+func NewImageFetcher(client *http.Client) *ImageFetcher {
+	if client == nil {
+		dialer := &net.Dialer{Timeout: 10 * time.Second, Control: denyPrivateAddresses}
+		client = &http.Client{
+			Timeout:   imageFetchTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+		}
+	}
+	return &ImageFetcher{client: client}
+}
+
+// Fetch downloads the image at rawURL. The response must be an image, or
+// untyped data, of at most utils.MaxImageBytes.
+func (f *ImageFetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	if err := validateImageURL(rawURL); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, imageFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: %s", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !(strings.HasPrefix(mediaType, "image/") || mediaType == "application/octet-stream") {
+			return nil, fmt.Errorf("%s is not an image", contentType)
+		}
+	}
+	if resp.ContentLength > utils.MaxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d MB", utils.MaxImageBytes>>20)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, utils.MaxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	if len(data) > utils.MaxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d MB", utils.MaxImageBytes>>20)
+	}
+	return data, nil
+}
+
+// validateImageURL checks that an image to import has an absolute http or
+// https URL.
+func validateImageURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return validationf("invalid image URL %q", rawURL)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationf("image URL %q must be an http or https URL", rawURL)
+	}
+	return nil
+}
+
+// denyPrivateAddresses refuses connections to loopback, private and other
+// non-public addresses. It runs after name resolution, so it also covers
+// names resolving to such addresses and redirects to them.
+func denyPrivateAddresses(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errors.New("image URL must point to a public address")
+	}
+	return nil
+}
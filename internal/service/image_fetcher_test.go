@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+	"google.golang.org/grpc"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageFetcherFetch(t *testing.T) {
+	picture := testPNG(t, 4, 3)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(picture)
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(picture)
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/missing.png", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, utils.MaxImageBytes+1))
+	})
+	mux.HandleFunc("/streamed.png", func(w http.ResponseWriter, r *http.Request) {
+		// no Content-Length, so the size is only known while reading
+		w.Header().Set("Content-Type", "image/png")
+		chunk := make([]byte, 1<<20)
+		for written := 0; written <= utils.MaxImageBytes; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/slow.png", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := NewImageFetcher(&http.Client{Timeout: 200 * time.Millisecond})
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{"/image.png", ""},
+		{"/untyped", ""},
+		{"/page.html", "is not an image"},
+		{"/missing.png", "404 Not Found"},
+		{"/large.png", "larger than"},
+		{"/streamed.png", "larger than"},
+		{"/slow.png", "failed to fetch image"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			data, err := fetcher.Fetch(context.Background(), server.URL+test.path)
+			if test.wantErr == "" {
+				if err != nil || !bytes.Equal(data, picture) {
+					t.Fatalf("got %d bytes, %v", len(data), err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageFetcherRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the fetcher reached a loopback address")
+	}))
+	defer server.Close()
+
+	_, err := NewImageFetcher(nil).Fetch(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "public address") {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := NewImageFetcher(nil).Fetch(context.Background(), "ftp://example.com/a.png"); err == nil {
+		t.Fatal("accepted an ftp URL")
+	}
+}
+
+// uploadedImages is an image service that hands out a URL per upload.
+type uploadedImages struct {
+	pb.ImageServiceClient
+	mu      sync.Mutex
+	uploads int
+}
+
+func (c *uploadedImages) UploadImage(ctx context.Context, in *pb.ImageMessage, opts ...grpc.CallOption) (*pb.ImageURL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads++
+	return &pb.ImageURL{Url: fmt.Sprintf("https://images.example.com/%d.jpg", c.uploads)}, nil
+}
+
+func TestImportPendingImages(t *testing.T) {
+	picture := testPNG(t, 40, 30)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/image.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(picture)
+	}))
+	defer server.Close()
+
+	db, err := gorm.Open(sqlite.Open("file:import?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&models.Product{}, &models.InventoryTransaction{}, &models.ProductImage{}, &models.ImageUpload{},
+		&models.SizeVariant{}, &models.ColorVariant{}, &models.Category{}, &models.ProductAttribute{}, &models.ModifierGroup{},
+		&models.ModifierOption{}, &models.ComboItem{}, &models.StoreCatalogVersion{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	imageRepository := repository.NewImageRepository(db)
+	productRepository := repository.NewProductRepository(db)
+	client := &uploadedImages{}
+	s := NewImageService(imageRepository, productRepository, client, NewImageFetcher(server.Client()), producer)
+
+	product, err := productRepository.CreateProduct(models.Product{StoreID: "s1", Name: "Tea"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := []models.ProductImage{
+		{ProductID: product.ID, SourceURL: server.URL + "/image.png", Status: models.ImageStatusPending},
+		{ProductID: product.ID, SourceURL: server.URL + "/gone.png", Status: models.ImageStatusPending, DisplayOrder: 1},
+	}
+	if err := imageRepository.CreateImages(images); err != nil {
+		t.Fatal(err)
+	}
+	uploads := make([]models.ImageUpload, len(images))
+	for i, image := range images {
+		uploads[i] = models.ImageUpload{ImageID: image.ID, ProductID: product.ID, SourceURL: image.SourceURL, UpdatedAt: time.Now()}
+	}
+	if err := imageRepository.CreateUploads(uploads); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ImportPendingImages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := imageRepository.GetImageByID(images[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Status != models.ImageStatusReady || imported.Image == "" || imported.ThumbnailURL == "" ||
+		imported.Width != 40 || imported.Height != 30 {
+		t.Fatalf("imported image: %+v", imported)
+	}
+	if client.uploads != 3 {
+		t.Fatalf("got %d uploads, want the 3 renditions of one image", client.uploads)
+	}
+
+	failed, err := imageRepository.GetImageByID(images[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != models.ImageStatusFailed || !strings.Contains(failed.Error, "404") {
+		t.Fatalf("failed image: %+v", failed)
+	}
+	left, err := imageRepository.GetUploadsByProductID(product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ImageID != images[1].ID || left[0].Attempts != 1 {
+		t.Fatalf("uploads left: %+v", left)
+	}
+
+	// failed imports are left to the retrier
+	if pending, err := imageRepository.GetPendingImports(10); err != nil || len(pending) != 0 {
+		t.Fatal(pending, err)
+	}
+}
@@ -0,0 +1,725 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"github.com/tanush-128/openzo_backend/product/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxParallelImageUploads bounds the uploads to the image service running
+	// at once for one request.
+	maxParallelImageUploads = 4
+	imageUploadTimeout      = 30 * time.Second
+	imageDeleteTimeout      = 10 * time.Second
+
+	// maxImageUploadAttempts is the number of attempts after which the
+	// retrier gives up on an upload. It can still be retried by hand.
+	maxImageUploadAttempts = 5
+	imageRetryBatchSize    = 50
+
+	// imageSweepGracePeriod is how long an image stays unreferenced before
+	// the sweeper deletes it from the image service. It covers uploads whose
+	// product is still being saved.
+	imageSweepGracePeriod = 24 * time.Hour
+	imageSweepBatchSize   = 100
+)
+
+type imageUploadResult struct {
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	Width        int
+	Height       int
+	Err          error
+}
+
+// apply sets the URLs and dimensions of an uploaded image, or the error of
+// a failed upload.
+func (r imageUploadResult) apply(image *models.ProductImage) {
+	if r.Err != nil {
+		image.Status, image.Error = models.ImageStatusFailed, r.Err.Error()
+		return
+	}
+	image.Image, image.MediumURL, image.ThumbnailURL = r.URL, r.MediumURL, r.ThumbnailURL
+	image.Width, image.Height = r.Width, r.Height
+	image.Status, image.Error = models.ImageStatusReady, ""
+}
+
+// uploadImages uploads the renditions of images to the image service, at
+// most maxParallelImageUploads images at a time. The results are in the
+// order of the images, and a failed upload does not stop the others.
+func uploadImages(ctx context.Context, client pb.ImageServiceClient, images []*utils.ImageRenditions) []imageUploadResult {
+	results := make([]imageUploadResult, len(images))
+	slots := make(chan struct{}, maxParallelImageUploads)
+	var wg sync.WaitGroup
+	for i, renditions := range images {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, renditions *utils.ImageRenditions) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = uploadRenditions(ctx, client, renditions)
+		}(i, renditions)
+	}
+	wg.Wait()
+	return results
+}
+
+// uploadRenditions uploads the renditions of an image. The image counts as
+// uploaded only once all of them are, so the renditions of a partly uploaded
+// image are deleted again.
+func uploadRenditions(ctx context.Context, client pb.ImageServiceClient, renditions *utils.ImageRenditions) imageUploadResult {
+	urls := []string{}
+	for _, data := range [][]byte{renditions.Full, renditions.Medium, renditions.Thumbnail} {
+		uploadCtx, cancel := context.WithTimeout(ctx, imageUploadTimeout)
+		imageURL, err := client.UploadImage(uploadCtx, &pb.ImageMessage{ImageData: data})
+		cancel()
+		if err == nil && imageURL.GetUrl() == "" {
+			err = errors.New("image service returned no URL")
+		}
+		if err != nil {
+			deleteImages(ctx, client, urls)
+			return imageUploadResult{Err: err}
+		}
+		urls = append(urls, imageURL.GetUrl())
+	}
+	return imageUploadResult{
+		URL:          urls[0],
+		MediumURL:    urls[1],
+		ThumbnailURL: urls[2],
+		Width:        renditions.Width,
+		Height:       renditions.Height,
+	}
+}
+
+// deleteImages removes uploaded images from the image service. It compensates
+// the uploads of a product write that failed, so failures are only logged.
+// An image service without DeleteImage is not asked again; the images stay
+// tracked as unreferenced.
+func deleteImages(ctx context.Context, client pb.ImageServiceClient, urls []string) {
+	for _, url := range urls {
+		deleteCtx, cancel := context.WithTimeout(ctx, imageDeleteTimeout)
+		_, err := client.DeleteImage(deleteCtx, &pb.DeleteImageRequest{Url: url})
+		cancel()
+		if status.Code(err) == codes.Unimplemented {
+			log.Printf("image service can't delete images, leaving %d images: %v", len(urls), err)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to delete image %s: %v", url, err)
+		}
+	}
+}
+
+// uploadFormImages validates and normalizes the image files of a form, and
+// uploads them in parallel, in form order, numbering them from displayOrder.
+// An invalid image fails the whole form before anything is uploaded. An image
+// that fails to upload is returned with status failed, and its data by index,
+// to be kept for a retry once the images are saved.
+func uploadFormImages(ctx context.Context, client pb.ImageServiceClient, files []*multipart.FileHeader, displayOrder int) ([]models.ProductImage, map[int][]byte, error) {
+	data := make([][]byte, len(files))
+	renditions := make([]*utils.ImageRenditions, len(files))
+	for i, file := range files {
+		if err := utils.ValidateImageFile(file); err != nil {
+			return nil, nil, validationf("%v", err)
+		}
+		imageBytes, err := utils.FileHeaderToBytes(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if renditions[i], err = utils.NormalizeImage(imageBytes); err != nil {
+			return nil, nil, validationf("image %s: %v", file.Filename, err)
+		}
+		data[i] = imageBytes
+	}
+
+	images := make([]models.ProductImage, len(files))
+	failed := map[int][]byte{}
+	for i, result := range uploadImages(ctx, client, renditions) {
+		images[i].DisplayOrder = displayOrder + i
+		result.apply(&images[i])
+		if result.Err != nil {
+			log.Printf("failed to upload image %s: %v", files[i].Filename, result.Err)
+			failed[i] = data[i]
+		}
+	}
+	return images, failed, nil
+}
+
+// keepFailedUploads keeps the data of the saved images of a form that failed
+// to upload, for the retrier. The images are already saved at this point, so
+// a failure is logged rather than returned.
+func keepFailedUploads(repo repository.ImageRepository, productID string, images []models.ProductImage, failed map[int][]byte) {
+	uploads := []models.ImageUpload{}
+	for i, data := range failed {
+		uploads = append(uploads, models.ImageUpload{
+			ImageID:   images[i].ID,
+			ProductID: productID,
+			Data:      data,
+			Attempts:  1,
+			LastError: images[i].Error,
+			UpdatedAt: time.Now(),
+		})
+	}
+	if err := repo.CreateUploads(uploads); err != nil {
+		log.Printf("failed to keep failed image uploads of product %s: %v", productID, err)
+	}
+}
+
+// importImages validates the URLs of images to import and returns them as
+// pending images, numbered from displayOrder.
+func importImages(urls []string, displayOrder int) ([]models.ProductImage, error) {
+	if len(urls) > maxImageImportURLs {
+		return nil, validationf("at most %d image URLs can be imported at once", maxImageImportURLs)
+	}
+	images := make([]models.ProductImage, len(urls))
+	for i, url := range urls {
+		url = strings.TrimSpace(url)
+		if err := validateImageURL(url); err != nil {
+			return nil, err
+		}
+		images[i] = models.ProductImage{
+			SourceURL:    url,
+			Status:       models.ImageStatusPending,
+			DisplayOrder: displayOrder + i,
+		}
+	}
+	return images, nil
+}
+
+// keepImports queues saved images imported from remote URLs for the
+// importer.
+func keepImports(repo repository.ImageRepository, productID string, images []models.ProductImage) {
+	uploads := make([]models.ImageUpload, len(images))
+	for i, image := range images {
+		uploads[i] = models.ImageUpload{
+			ImageID:   image.ID,
+			ProductID: productID,
+			SourceURL: image.SourceURL,
+			UpdatedAt: time.Now(),
+		}
+	}
+	if err := repo.CreateUploads(uploads); err != nil {
+		log.Printf("failed to queue image imports of product %s: %v", productID, err)
+	}
+}
+
+// uploadedImageURLs returns the URLs of the renditions of the images that
+// were uploaded.
+func uploadedImageURLs(images []models.ProductImage) []string {
+	urls := []string{}
+	for _, image := range images {
+		for _, url := range []string{image.Image, image.MediumURL, image.ThumbnailURL} {
+			if url != "" {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
+
+// detachedImageURLs returns the URLs of the renditions of the images in
+// before that are no longer in after.
+func detachedImageURLs(before, after []models.ProductImage) []string {
+	kept := map[string]bool{}
+	for _, url := range uploadedImageURLs(after) {
+		kept[url] = true
+	}
+	detached := []string{}
+	for _, url := range uploadedImageURLs(before) {
+		if !kept[url] {
+			detached = append(detached, url)
+		}
+	}
+	return detached
+}
+
+// imageRetryBackoff is the time to wait before the next attempt of an upload
+// that failed the given number of times.
+func imageRetryBackoff(attempts int) time.Duration {
+	if attempts > 6 {
+		attempts = 6
+	}
+	return time.Minute << attempts
+}
+
+// imageDeleteBackoff is the time to wait before the next attempt to delete
+// an unreferenced image that failed to delete the given number of times.
+func imageDeleteBackoff(attempts int) time.Duration {
+	if attempts > 7 {
+		attempts = 7
+	}
+	return time.Hour << attempts
+}
+
+// ImageService defines the interface for managing the images of products,
+// importing them from remote URLs and retrying the image uploads that failed.
+type ImageService interface {
+	AddImages(ctx *gin.Context, productID string, files []*multipart.FileHeader, details models.ProductImage) ([]models.ProductImage, error)
+	ImportImages(ctx *gin.Context, productID string, urls []string, details models.ProductImage) ([]models.ProductImage, error)
+	GetProductImages(ctx *gin.Context, productID string) ([]models.ProductImage, error)
+	UpdateImage(ctx *gin.Context, image *models.ProductImage) (*models.ProductImage, error)
+	DeleteImage(ctx *gin.Context, id int) error
+	ReorderImages(ctx *gin.Context, productID string, imageIDs []int) ([]models.ProductImage, error)
+	SetPrimaryImage(ctx *gin.Context, productID string, imageID int) ([]models.ProductImage, error)
+	RetryProductImages(ctx *gin.Context, productID string) ([]models.ProductImage, error)
+	RetryFailedUploads(ctx context.Context) error
+	ImportPendingImages(ctx context.Context) error
+	SweepUnreferencedImages(ctx context.Context) error
+}
+
+type imageService struct {
+	repo              repository.ImageRepository
+	productRepository repository.ProductRepository
+	imageClient       pb.ImageServiceClient
+	fetcher           *ImageFetcher
+	kafkaProducer     *kafka.Producer
+}
+
+// NewImageService creates a new instance of ImageService.
+func NewImageService(repo repository.ImageRepository, productRepository repository.ProductRepository,
+	imageClient pb.ImageServiceClient, fetcher *ImageFetcher, kafkaProducer *kafka.Producer,
+) ImageService {
+	return &imageService{
+		repo:              repo,
+		productRepository: productRepository,
+		imageClient:       imageClient,
+		fetcher:           fetcher,
+		kafkaProducer:     kafkaProducer,
+	}
+}
+
+// AddImages uploads images to a product, after its existing images. Every
+// image gets the alt text and variant of details.
+func (s *imageService) AddImages(ctx *gin.Context, productID string, files []*multipart.FileHeader, details models.ProductImage) ([]models.ProductImage, error) {
+	if len(files) == 0 {
+		return nil, validationf("at least one image is required")
+	}
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %s: %w", productID, err)
+	}
+	if !hasVariant(product, details.VariantType, details.VariantID) {
+		return nil, validationf("product %s has no %s variant %d", productID, details.VariantType, details.VariantID)
+	}
+
+	displayOrder := 1
+	for _, image := range product.Images {
+		displayOrder = max(displayOrder, image.DisplayOrder+1)
+	}
+	images, failedUploads, err := uploadFormImages(ctx, s.imageClient, files, displayOrder)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		images[i].ProductID = productID
+		images[i].AltText = strings.TrimSpace(details.AltText)
+		images[i].VariantType, images[i].VariantID = details.VariantType, details.VariantID
+	}
+	if err := s.repo.CreateImages(images); err != nil {
+		deleteImages(ctx, s.imageClient, uploadedImageURLs(images))
+		return nil, err
+	}
+	keepFailedUploads(s.repo, productID, images, failedUploads)
+
+	s.publishProduct(productID)
+	return images, nil
+}
+
+// ImportImages adds images to a product from remote URLs, after its existing
+// images. The images are pending until the importer has fetched and uploaded
+// them, and fail one by one when it can't. Every image gets the alt text and
+// variant of details.
+func (s *imageService) ImportImages(ctx *gin.Context, productID string, urls []string, details models.ProductImage) ([]models.ProductImage, error) {
+	if len(urls) == 0 {
+		return nil, validationf("at least one image URL is required")
+	}
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product %s: %w", productID, err)
+	}
+	if !hasVariant(product, details.VariantType, details.VariantID) {
+		return nil, validationf("product %s has no %s variant %d", productID, details.VariantType, details.VariantID)
+	}
+
+	displayOrder := 1
+	for _, image := range product.Images {
+		displayOrder = max(displayOrder, image.DisplayOrder+1)
+	}
+	images, err := importImages(urls, displayOrder)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		images[i].ProductID = productID
+		images[i].AltText = strings.TrimSpace(details.AltText)
+		images[i].VariantType, images[i].VariantID = details.VariantType, details.VariantID
+	}
+	if err := s.repo.CreateImages(images); err != nil {
+		return nil, err
+	}
+	keepImports(s.repo, productID, images)
+
+	s.publishProduct(productID)
+	return images, nil
+}
+
+// GetProductImages retrieves the images of a product, primary image first.
+func (s *imageService) GetProductImages(ctx *gin.Context, productID string) ([]models.ProductImage, error) {
+	return s.repo.GetImagesByProductID(productID)
+}
+
+// UpdateImage updates the alt text, dimensions and variant of an image.
+func (s *imageService) UpdateImage(ctx *gin.Context, image *models.ProductImage) (*models.ProductImage, error) {
+	existing, err := s.repo.GetImageByID(image.ID)
+	if err != nil {
+		return nil, err
+	}
+	if image.Width < 0 || image.Height < 0 {
+		return nil, validationf("dimensions must not be negative")
+	}
+	product, err := s.productRepository.GetProductByID(existing.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVariant(product, image.VariantType, image.VariantID) {
+		return nil, validationf("product %s has no %s variant %d", product.ID, image.VariantType, image.VariantID)
+	}
+
+	existing.AltText = strings.TrimSpace(image.AltText)
+	existing.Width, existing.Height = image.Width, image.Height
+	existing.VariantType, existing.VariantID = image.VariantType, image.VariantID
+	if err := s.repo.UpdateImage(existing); err != nil {
+		return nil, err
+	}
+
+	s.publishProduct(existing.ProductID)
+	return existing, nil
+}
+
+// DeleteImage removes an image from its product. It is deleted from the image
+// service by the sweeper.
+func (s *imageService) DeleteImage(ctx *gin.Context, id int) error {
+	image, err := s.repo.GetImageByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteImage(id); err != nil {
+		return err
+	}
+	if err := s.repo.UnreferenceImages(uploadedImageURLs([]models.ProductImage{*image}), time.Now()); err != nil {
+		log.Printf("failed to unreference image %d: %v", id, err)
+	}
+
+	s.publishProduct(image.ProductID)
+	return nil
+}
+
+// ReorderImages puts the images of a product in the order of imageIDs, which
+// must list every image of the product once.
+func (s *imageService) ReorderImages(ctx *gin.Context, productID string, imageIDs []int) ([]models.ProductImage, error) {
+	images, err := s.repo.GetImagesByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIDs) != len(images) {
+		return nil, fieldErrorf("image_ids", "image_ids must list all %d images of the product", len(images))
+	}
+	remaining := map[int]bool{}
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, id := range imageIDs {
+		if !remaining[id] {
+			return nil, validationf("image %d is not an image of product %s or is listed twice", id, productID)
+		}
+		delete(remaining, id)
+	}
+
+	if err := s.repo.ReorderImages(productID, imageIDs); err != nil {
+		return nil, err
+	}
+	s.publishProduct(productID)
+	return s.repo.GetImagesByProductID(productID)
+}
+
+// SetPrimaryImage makes an image the cover of its product.
+func (s *imageService) SetPrimaryImage(ctx *gin.Context, productID string, imageID int) ([]models.ProductImage, error) {
+	image, err := s.repo.GetImageByID(imageID)
+	if err != nil {
+		return nil, err
+	}
+	if image.ProductID != productID {
+		return nil, validationf("image %d is not an image of product %s", imageID, productID)
+	}
+
+	if err := s.repo.SetPrimaryImage(productID, imageID); err != nil {
+		return nil, err
+	}
+	s.publishProduct(productID)
+	return s.repo.GetImagesByProductID(productID)
+}
+
+// publishProduct publishes the current state of a product to the products
+// topic after its images changed.
+func (s *imageService) publishProduct(productID string) {
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		log.Printf("failed to load product %s after its images changed: %v", productID, err)
+		return
+	}
+	go writeProductToKafka(s.kafkaProducer, product)
+}
+
+// RetryProductImages retries the failed image uploads of a product right
+// away, however often they failed before, and returns the images of the
+// product.
+func (s *imageService) RetryProductImages(ctx *gin.Context, productID string) ([]models.ProductImage, error) {
+	if _, err := s.productRepository.GetProductByID(productID); err != nil {
+		return nil, fmt.Errorf("product %s: %w", productID, err)
+	}
+	if err := s.repo.DeleteStaleUploads(); err != nil {
+		return nil, err
+	}
+	uploads, err := s.repo.GetUploadsByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.retry(ctx, uploads); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	return product.Images, nil
+}
+
+// RetryFailedUploads retries a batch of the failed image uploads whose
+// backoff has passed. It is run periodically by RunImageUploadRetrier.
+func (s *imageService) RetryFailedUploads(ctx context.Context) error {
+	if err := s.repo.DeleteStaleUploads(); err != nil {
+		return err
+	}
+	uploads, err := s.repo.GetRetryableUploads(maxImageUploadAttempts, imageRetryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	due := []models.ImageUpload{}
+	for _, upload := range uploads {
+		if !upload.UpdatedAt.Add(imageRetryBackoff(upload.Attempts)).After(now) {
+			due = append(due, upload)
+		}
+	}
+	return s.retry(ctx, due)
+}
+
+// retry uploads the data of the given uploads again. Products that gained an
+// image are published to the products topic.
+func (s *imageService) retry(ctx context.Context, uploads []models.ImageUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+	ids := make([]int, len(uploads))
+	for i, upload := range uploads {
+		ids[i] = upload.ImageID
+	}
+	if err := s.repo.SetImagesPending(ids); err != nil {
+		return err
+	}
+
+	// uploads kept from before normalization are normalized now; an image
+	// that can't be fetched or normalized fails like any other upload error
+	results := make([]imageUploadResult, len(uploads))
+	normalized := []*utils.ImageRenditions{}
+	indexes := []int{}
+	renditions, errs := s.normalizeUploads(ctx, uploads)
+	for i := range uploads {
+		if errs[i] != nil {
+			results[i].Err = errs[i]
+			continue
+		}
+		normalized = append(normalized, renditions[i])
+		indexes = append(indexes, i)
+	}
+	for i, result := range uploadImages(ctx, s.imageClient, normalized) {
+		results[indexes[i]] = result
+	}
+
+	updated := map[string]bool{}
+	for i, result := range results {
+		upload := uploads[i]
+		if result.Err == nil {
+			image := models.ProductImage{ID: upload.ImageID}
+			result.apply(&image)
+			if err := s.repo.MarkImageReady(&image); err != nil {
+				return err
+			}
+			updated[upload.ProductID] = true
+			continue
+		}
+
+		upload.Attempts++
+		upload.LastError = result.Err.Error()
+		upload.UpdatedAt = time.Now()
+		if err := s.repo.MarkImageFailed(&upload); err != nil {
+			return err
+		}
+	}
+
+	for productID := range updated {
+		s.publishProduct(productID)
+	}
+	return nil
+}
+
+// normalizeUploads normalizes the data of uploads, fetching imports from
+// their source URL first, at most maxParallelImageUploads at a time.
+func (s *imageService) normalizeUploads(ctx context.Context, uploads []models.ImageUpload) ([]*utils.ImageRenditions, []error) {
+	renditions := make([]*utils.ImageRenditions, len(uploads))
+	errs := make([]error, len(uploads))
+	slots := make(chan struct{}, maxParallelImageUploads)
+	var wg sync.WaitGroup
+	for i, upload := range uploads {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, upload models.ImageUpload) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			data := upload.Data
+			if upload.SourceURL != "" {
+				var err error
+				if data, err = s.fetcher.Fetch(ctx, upload.SourceURL); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+			renditions[i], errs[i] = utils.NormalizeImage(data)
+		}(i, upload)
+	}
+	wg.Wait()
+	return renditions, errs
+}
+
+// SweepUnreferencedImages deletes a batch of the images unreferenced for
+// longer than the grace period from the image service. Images found on a
+// product after all are marked referenced instead. An image that fails to
+// delete is tried again after a backoff, and comes after the images not tried
+// yet, so that images that keep failing don't hold up the rest. It is run
+// periodically by RunImageSweeper.
+func (s *imageService) SweepUnreferencedImages(ctx context.Context) error {
+	now := time.Now()
+	images, err := s.repo.GetUnreferencedImages(now.Add(-imageSweepGracePeriod), now, imageSweepBatchSize)
+	if err != nil || len(images) == 0 {
+		return err
+	}
+	urls := make([]string, len(images))
+	for i, image := range images {
+		urls[i] = image.URL
+	}
+	referenced, err := s.repo.GetReferencedImageURLs(urls)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ReferenceImages(referenced); err != nil {
+		return err
+	}
+
+	isReferenced := map[string]bool{}
+	for _, url := range referenced {
+		isReferenced[url] = true
+	}
+	for _, image := range images {
+		if isReferenced[image.URL] {
+			continue
+		}
+		deleteCtx, cancel := context.WithTimeout(ctx, imageDeleteTimeout)
+		_, err := s.imageClient.DeleteImage(deleteCtx, &pb.DeleteImageRequest{Url: image.URL})
+		cancel()
+		if status.Code(err) == codes.Unimplemented {
+			// nothing can be deleted until the image service can delete images
+			return fmt.Errorf("image service can't delete images: %w", err)
+		}
+		if status.Code(err) == codes.NotFound {
+			// already gone from the image service
+			err = s.repo.UntrackImages([]string{image.URL})
+		}
+		if err == nil {
+			continue
+		}
+
+		log.Printf("failed to delete unreferenced image %s: %v", image.URL, err)
+		image.DeleteAttempts++
+		next := now.Add(imageDeleteBackoff(image.DeleteAttempts))
+		image.NextDeleteAt = &next
+		if err := s.repo.DeferImageDelete(&image); err != nil {
+			log.Printf("failed to defer the delete of image %s: %v", image.URL, err)
+		}
+	}
+	return nil
+}
+
+// ImportPendingImages fetches and uploads a batch of the images imported from
+// remote URLs that were not attempted yet. It is run periodically by
+// RunImageImporter; imports that fail are left to the retrier.
+func (s *imageService) ImportPendingImages(ctx context.Context) error {
+	uploads, err := s.repo.GetPendingImports(imageRetryBatchSize)
+	if err != nil {
+		return err
+	}
+	return s.retry(ctx, uploads)
+}
+
+// RunImageUploadRetrier retries failed image uploads every interval. It never
+// returns.
+func RunImageUploadRetrier(s ImageService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.RetryFailedUploads(context.Background()); err != nil {
+			log.Printf("failed to retry image uploads: %v", err)
+		}
+	}
+}
+
+// RunImageSweeper deletes unreferenced images every interval. It never
+// returns.
+func RunImageSweeper(s ImageService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.SweepUnreferencedImages(context.Background()); err != nil {
+			log.Printf("failed to sweep unreferenced images: %v", err)
+		}
+	}
+}
+
+// RunImageImporter imports the images added by URL every interval. It never
+// returns.
+func RunImageImporter(s ImageService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.ImportPendingImages(context.Background()); err != nil {
+			log.Printf("failed to import images: %v", err)
+		}
+	}
+}
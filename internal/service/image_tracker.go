@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"google.golang.org/grpc"
+)
+
+// trackingImageClient is an image service client that tracks the images
+// uploaded through it, so that the sweeper can delete them once no product
+// refers to them.
+type trackingImageClient struct {
+	pb.ImageServiceClient
+	repo repository.ImageRepository
+}
+
+// NewTrackingImageClient wraps an image service client to track every image
+// uploaded through it, and to stop tracking the images deleted through it.
+func NewTrackingImageClient(client pb.ImageServiceClient, repo repository.ImageRepository) pb.ImageServiceClient {
+	return &trackingImageClient{ImageServiceClient: client, repo: repo}
+}
+
+// UploadImage uploads an image and tracks it as unreferenced until it is seen
+// on a product. An image that can't be tracked is deleted again, as nothing
+// would ever delete it otherwise.
+func (c *trackingImageClient) UploadImage(ctx context.Context, in *pb.ImageMessage, opts ...grpc.CallOption) (*pb.ImageURL, error) {
+	imageURL, err := c.ImageServiceClient.UploadImage(ctx, in, opts...)
+	if err != nil || imageURL.GetUrl() == "" {
+		return imageURL, err
+	}
+	if err := c.repo.TrackImages([]string{imageURL.GetUrl()}, time.Now()); err != nil {
+		deleteImages(context.Background(), c.ImageServiceClient, []string{imageURL.GetUrl()})
+		return nil, fmt.Errorf("failed to track image: %w", err)
+	}
+	return imageURL, nil
+}
+
+// DeleteImage deletes an image and stops tracking it.
+func (c *trackingImageClient) DeleteImage(ctx context.Context, in *pb.DeleteImageRequest, opts ...grpc.CallOption) (*pb.DeleteImageResponse, error) {
+	response, err := c.ImageServiceClient.DeleteImage(ctx, in, opts...)
+	if err != nil {
+		return response, err
+	}
+	if err := c.repo.UntrackImages([]string{in.GetUrl()}); err != nil {
+		log.Printf("failed to untrack image %s: %v", in.GetUrl(), err)
+	}
+	return response, nil
+}
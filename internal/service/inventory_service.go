@@ -1,7 +1,7 @@
 package service
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tanush-128/openzo_backend/product/internal/models"
@@ -11,44 +11,116 @@ import (
 // InventoryService defines the interface for the inventory service.
 type InventoryService interface {
 	CreateTransaction(ctx *gin.Context, transaction *models.InventoryTransaction) (*models.InventoryTransaction, error)
-	GetTransactionByID(ctx *gin.Context,id string) (*models.InventoryTransaction, error)
-	UpdateTransaction(ctx *gin.Context,transaction *models.InventoryTransaction) (*models.InventoryTransaction, error)
-	DeleteTransaction(ctx *gin.Context,id string) error
-	GetAllTransactionsByProductID(ctx *gin.Context,productID string) ([]models.InventoryTransaction, error)
+	GetTransactionByID(ctx *gin.Context, id string) (*models.InventoryTransaction, error)
+	UpdateTransaction(ctx *gin.Context, transaction *models.InventoryTransaction) (*models.InventoryTransaction, error)
+	DeleteTransaction(ctx *gin.Context, id string) error
+	GetAllTransactionsByProductID(ctx *gin.Context, productID string) ([]models.InventoryTransaction, error)
 }
 
 type inventoryService struct {
-	repo repository.InventoryTransactionRepository
+	repo              repository.InventoryTransactionRepository
+	productRepository repository.ProductRepository
 }
 
 // NewInventoryService creates a new instance of InventoryService.
-func NewInventoryService(repo repository.InventoryTransactionRepository) InventoryService {
-	return &inventoryService{repo: repo}
+func NewInventoryService(repo repository.InventoryTransactionRepository, productRepository repository.ProductRepository) InventoryService {
+	return &inventoryService{repo: repo, productRepository: productRepository}
 }
 
-// CreateTransaction creates a new inventory transaction.
-func (s *inventoryService) CreateTransaction(ctx *gin.Context,transaction *models.InventoryTransaction) (*models.InventoryTransaction, error) {
+// CreateTransaction creates a new inventory transaction. A transaction
+// recorded in another unit than its product, such as grams of a product
+// stocked in kilograms, is converted to the product's unit first.
+func (s *inventoryService) CreateTransaction(ctx *gin.Context, transaction *models.InventoryTransaction) (*models.InventoryTransaction, error) {
+	product, err := s.productRepository.GetProductByID(transaction.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.Unit != "" {
+		unit, err := models.ParseUnit(string(transaction.Unit))
+		if err != nil {
+			return nil, fieldErrorf("unit", "%v", err)
+		}
+		quantity, err := models.ConvertQuantity(transaction.Quantity, unit, product.QuantityUnit)
+		if err != nil {
+			return nil, validationf("%v", err)
+		}
+		transaction.Quantity = quantity
+	}
+	transaction.Unit = product.QuantityUnit
+
+	if product.Type == models.ProductTypeBundle &&
+		(transaction.TransactionType == models.TransactionTypeSale || transaction.TransactionType == models.TransactionTypeReturn) {
+		return s.createBundleTransaction(product, transaction)
+	}
+
+	err = s.repo.Create(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// createBundleTransaction records a sale or return of a bundle together with
+// a transaction of the same type against each component, moving the stock of
+// the components by the bundle quantity times their quantity in the bundle.
+func (s *inventoryService) createBundleTransaction(bundle models.Product, transaction *models.InventoryTransaction) (*models.InventoryTransaction, error) {
+	if len(bundle.ComboItems) == 0 {
+		return nil, validationf("bundle %s has no components", bundle.ID)
+	}
 
-	err := s.repo.Create(transaction)
+	ids := []string{}
+	for _, item := range bundle.ComboItems {
+		ids = append(ids, item.ProductID)
+	}
+	components, err := s.productRepository.GetProductsByIDs(ids)
 	if err != nil {
 		return nil, err
 	}
+	units := map[string]models.Unit{}
+	for _, component := range components {
+		units[component.ID] = component.QuantityUnit
+	}
+
+	transactions := []models.InventoryTransaction{*transaction}
+	for _, item := range bundle.ComboItems {
+		unit, ok := units[item.ProductID]
+		if !ok {
+			return nil, validationf("component %s of bundle %s not found", item.ProductID, bundle.ID)
+		}
+		transactions = append(transactions, models.InventoryTransaction{
+			ProductID:       item.ProductID,
+			VariantType:     item.VariantType,
+			VariantID:       item.VariantID,
+			Quantity:        transaction.Quantity.Times(item.Quantity),
+			Unit:            unit,
+			TransactionType: transaction.TransactionType,
+			Description:     fmt.Sprintf("Component of bundle %s (%s)", bundle.Name, bundle.ID),
+			ReferenceID:     transaction.ReferenceID,
+		})
+	}
+
+	if err := s.repo.CreateBatch(transactions); err != nil {
+		return nil, err
+	}
+	*transaction = transactions[0]
+	transaction.Components = transactions[1:]
 	return transaction, nil
 }
 
 // GetTransactionByID retrieves an inventory transaction by its ID.
-func (s *inventoryService) GetTransactionByID(ctx *gin.Context,id string) (*models.InventoryTransaction, error) {
+func (s *inventoryService) GetTransactionByID(ctx *gin.Context, id string) (*models.InventoryTransaction, error) {
 	return s.repo.GetByID(id)
 }
 
 // UpdateTransaction updates an existing inventory transaction.
-func (s *inventoryService) UpdateTransaction(ctx *gin.Context,transaction *models.InventoryTransaction) (*models.InventoryTransaction, error) {
+func (s *inventoryService) UpdateTransaction(ctx *gin.Context, transaction *models.InventoryTransaction) (*models.InventoryTransaction, error) {
 	transaction, err := s.repo.GetByID(transaction.ID)
 	if err != nil {
 		return nil, err
 	}
 	if transaction == nil {
-		return nil, errors.New("transaction not found")
+		return nil, notFoundf("transaction not found")
 	}
 
 	err = s.repo.Update(transaction)
@@ -59,11 +131,11 @@ func (s *inventoryService) UpdateTransaction(ctx *gin.Context,transaction *model
 }
 
 // DeleteTransaction deletes an inventory transaction by its ID.
-func (s *inventoryService) DeleteTransaction(ctx *gin.Context,id string) error {
+func (s *inventoryService) DeleteTransaction(ctx *gin.Context, id string) error {
 	return s.repo.Delete(id)
 }
 
 // GetAllTransactionsByProductID retrieves all inventory transactions for a given product ID.
-func (s *inventoryService) GetAllTransactionsByProductID(ctx *gin.Context,productID string) ([]models.InventoryTransaction, error) {
+func (s *inventoryService) GetAllTransactionsByProductID(ctx *gin.Context, productID string) ([]models.InventoryTransaction, error) {
 	return s.repo.GetAllByProductID(productID)
 }
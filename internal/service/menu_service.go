@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// MenuSection holds the products of one category of a store menu.
+type MenuSection struct {
+	CategoryID string           `json:"category_id"`
+	Category   string           `json:"category"`
+	Products   []models.Product `json:"products"`
+}
+
+// Menu is the sellable products of a store, grouped by category, with their
+// modifier groups and combo items.
+type Menu struct {
+	StoreID  string        `json:"store_id"`
+	Sections []MenuSection `json:"sections"`
+}
+
+// MenuService defines the interface for the store menu service.
+type MenuService interface {
+	GetMenu(ctx *gin.Context, storeID string) (*Menu, error)
+}
+
+type menuService struct {
+	productRepository    repository.ProductRepository
+	priceResolver        PriceResolver
+	availabilityResolver AvailabilityResolver
+}
+
+// NewMenuService creates a new instance of MenuService.
+func NewMenuService(productRepository repository.ProductRepository, priceResolver PriceResolver, availabilityResolver AvailabilityResolver) MenuService {
+	return &menuService{productRepository: productRepository, priceResolver: priceResolver, availabilityResolver: availabilityResolver}
+}
+
+// GetMenu retrieves the menu of a store. Sections follow the category display
+// order, and posts are left out. Products outside their availability hours
+// stay on the menu, marked unavailable.
+func (s *menuService) GetMenu(ctx *gin.Context, storeID string) (*Menu, error) {
+	products, err := s.productRepository.GetProductsByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.priceResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(products, time.Now()); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, product := range products {
+		names[product.ID] = product.Name
+	}
+
+	menu := &Menu{StoreID: storeID, Sections: []MenuSection{}}
+	sections := map[string]int{}
+	for _, product := range products {
+		if product.Type == "post" {
+			continue
+		}
+		product.InventoryTransactions = nil
+		for i := range product.ComboItems {
+			product.ComboItems[i].ProductName = names[product.ComboItems[i].ProductID]
+		}
+
+		index, ok := sections[product.CategoryID]
+		if !ok {
+			index = len(menu.Sections)
+			sections[product.CategoryID] = index
+			menu.Sections = append(menu.Sections, MenuSection{
+				CategoryID: product.CategoryID,
+				Category:   product.Category,
+				Products:   []models.Product{},
+			})
+		}
+		menu.Sections[index].Products = append(menu.Sections[index].Products, product)
+	}
+	return menu, nil
+}
+
+// validateComboItems checks the components of a combo or bundle. Components
+// must be other products of the same store and cannot be combos themselves.
+// A component without a quantity counts once.
+func validateComboItems(repo repository.ProductRepository, product *models.Product) error {
+	if product.Type == models.ProductTypeBundle && len(product.ComboItems) == 0 {
+		return validationf("a bundle needs at least one component")
+	}
+	for i := range product.ComboItems {
+		item := &product.ComboItems[i]
+		if item.ProductID == "" {
+			return validationf("combo items need a product_id")
+		}
+		if item.ProductID == product.ID {
+			return validationf("a combo cannot contain itself")
+		}
+		if item.Quantity < 0 {
+			return validationf("quantity of combo item %s must not be negative", item.ProductID)
+		}
+		if item.Quantity == 0 {
+			item.Quantity = models.NewQuantity(1)
+		}
+
+		component, err := repo.GetProductByID(item.ProductID)
+		if err != nil {
+			return fmt.Errorf("combo item %s: %w", item.ProductID, err)
+		}
+		if component.StoreID != product.StoreID {
+			return validationf("combo item %s belongs to another store", item.ProductID)
+		}
+		if len(component.ComboItems) > 0 {
+			return validationf("combo item %s is a combo itself", item.ProductID)
+		}
+		if !hasVariant(component, item.VariantType, item.VariantID) {
+			return validationf("combo item %s has no %s variant %d", item.ProductID, item.VariantType, item.VariantID)
+		}
+		item.ID = 0
+		item.ComboProductID = product.ID
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// ModifierService defines the interface for the modifier group service.
+type ModifierService interface {
+	CreateModifierGroup(ctx *gin.Context, group *models.ModifierGroup) (*models.ModifierGroup, error)
+	GetModifierGroupByID(ctx *gin.Context, id string) (*models.ModifierGroup, error)
+	GetModifierGroupsByStoreID(ctx *gin.Context, storeID string) ([]models.ModifierGroup, error)
+	UpdateModifierGroup(ctx *gin.Context, group *models.ModifierGroup) (*models.ModifierGroup, error)
+	DeleteModifierGroup(ctx *gin.Context, id string) error
+	SetProductModifierGroups(ctx *gin.Context, productID string, groupIDs []string) ([]models.ModifierGroup, error)
+}
+
+type modifierService struct {
+	repo              repository.ModifierRepository
+	productRepository repository.ProductRepository
+}
+
+// NewModifierService creates a new instance of ModifierService.
+func NewModifierService(repo repository.ModifierRepository, productRepository repository.ProductRepository) ModifierService {
+	return &modifierService{repo: repo, productRepository: productRepository}
+}
+
+// CreateModifierGroup validates and stores a modifier group with its options.
+func (s *modifierService) CreateModifierGroup(ctx *gin.Context, group *models.ModifierGroup) (*models.ModifierGroup, error) {
+	if group.StoreID == "" {
+		return nil, fieldErrorf("store_id", "store_id is required")
+	}
+	if err := validateModifierGroup(group); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetModifierGroupByID retrieves a modifier group by its ID.
+func (s *modifierService) GetModifierGroupByID(ctx *gin.Context, id string) (*models.ModifierGroup, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetModifierGroupsByStoreID retrieves the modifier groups of a store.
+func (s *modifierService) GetModifierGroupsByStoreID(ctx *gin.Context, storeID string) ([]models.ModifierGroup, error) {
+	return s.repo.GetAllByStoreID(storeID)
+}
+
+// UpdateModifierGroup updates a modifier group and replaces its options. The
+// store of a group cannot be changed.
+func (s *modifierService) UpdateModifierGroup(ctx *gin.Context, group *models.ModifierGroup) (*models.ModifierGroup, error) {
+	existing, err := s.repo.GetByID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group.StoreID = existing.StoreID
+	group.CreatedAt = existing.CreatedAt
+	if err := validateModifierGroup(group); err != nil {
+		return nil, err
+	}
+
+	group.UpdatedAt = time.Now()
+	if err := s.repo.Update(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteModifierGroup deletes a modifier group and removes it from every product.
+func (s *modifierService) DeleteModifierGroup(ctx *gin.Context, id string) error {
+	return s.repo.Delete(id)
+}
+
+// SetProductModifierGroups replaces the modifier groups offered with a
+// product. Every group must belong to the store of the product.
+func (s *modifierService) SetProductModifierGroups(ctx *gin.Context, productID string, groupIDs []string) ([]models.ModifierGroup, error) {
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []models.ModifierGroup{}
+	if len(groupIDs) > 0 {
+		groups, err = s.repo.GetByIDs(groupIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	found := map[string]models.ModifierGroup{}
+	for _, group := range groups {
+		found[group.ID] = group
+	}
+	for _, id := range groupIDs {
+		group, ok := found[id]
+		if !ok {
+			return nil, validationf("modifier group %s not found", id)
+		}
+		if group.StoreID != product.StoreID {
+			return nil, validationf("modifier group %s belongs to another store", id)
+		}
+	}
+
+	if err := s.repo.SetProductGroups(productID, groups); err != nil {
+		return nil, err
+	}
+
+	product, err = s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	return product.ModifierGroups, nil
+}
+
+// validateModifierGroup checks the selection bounds and options of a group.
+// A required group needs at least one selection, and a group needing a
+// selection is required. MaxSelections 0 allows picking every option.
+func validateModifierGroup(group *models.ModifierGroup) error {
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return fieldErrorf("name", "name is required")
+	}
+	if len(group.Options) == 0 {
+		return validationf("a modifier group needs at least one option")
+	}
+	for i := range group.Options {
+		option := &group.Options[i]
+		option.Name = strings.TrimSpace(option.Name)
+		if option.Name == "" {
+			return validationf("option name is required")
+		}
+		if option.Price.Amount < 0 {
+			return validationf("price of option %s must not be negative", option.Name)
+		}
+		option.ModifierGroupID = group.ID
+	}
+
+	if group.MinSelections < 0 || group.MaxSelections < 0 {
+		return validationf("min_selections and max_selections must not be negative")
+	}
+	if group.Required && group.MinSelections == 0 {
+		group.MinSelections = 1
+	}
+	group.Required = group.MinSelections > 0
+	if group.MaxSelections > 0 && group.MinSelections > group.MaxSelections {
+		return validationf("min_selections must not exceed max_selections")
+	}
+	if group.MinSelections > len(group.Options) || group.MaxSelections > len(group.Options) {
+		return validationf("group %s has only %d options", group.Name, len(group.Options))
+	}
+	return nil
+}
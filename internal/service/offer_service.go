@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// Offer is a store selling an item, at the price it sells it for now.
+type Offer struct {
+	ProductID          string       `json:"product_id"`
+	ProductName        string       `json:"product_name"`
+	Barcode            string       `json:"barcode,omitempty"`
+	MasterProductID    string       `json:"master_product_id,omitempty"`
+	Image              string       `json:"image,omitempty"`
+	QuantityUnit       models.Unit  `json:"quantity_unit"`
+	MRP                models.Money `json:"mrp"`
+	EffectivePrice     models.Money `json:"effective_price"`
+	AppliedPriceRuleID string       `json:"applied_price_rule_id,omitempty"`
+	repository.StoreBasicDetails
+}
+
+// OfferService defines the interface for comparing the prices of an item across stores.
+type OfferService interface {
+	GetOffersByBarcode(ctx *gin.Context, barcode string, pincodes []string) ([]Offer, error)
+	GetOffersByMasterProduct(ctx *gin.Context, masterProductID string, pincodes []string) ([]Offer, error)
+}
+
+type offerService struct {
+	productRepository    repository.ProductRepository
+	catalogRepository    repository.CatalogRepository
+	priceResolver        PriceResolver
+	availabilityResolver AvailabilityResolver
+}
+
+// NewOfferService creates a new instance of OfferService.
+func NewOfferService(productRepository repository.ProductRepository, catalogRepository repository.CatalogRepository,
+	priceResolver PriceResolver, availabilityResolver AvailabilityResolver,
+) OfferService {
+	return &offerService{
+		productRepository:    productRepository,
+		catalogRepository:    catalogRepository,
+		priceResolver:        priceResolver,
+		availabilityResolver: availabilityResolver,
+	}
+}
+
+// GetOffersByBarcode retrieves the offers for a barcode from stores in the
+// given pincodes. Products linked to the master entry of the barcode are
+// included even when the store typed the barcode differently.
+func (s *offerService) GetOffersByBarcode(ctx *gin.Context, barcode string, pincodes []string) ([]Offer, error) {
+	gtin, err := models.NormalizeGTIN(barcode)
+	if err != nil {
+		return nil, invalidf("%v", err)
+	}
+	master, err := s.catalogRepository.GetByBarcode(gtin)
+	if err != nil {
+		return nil, err
+	}
+
+	masterProductID := ""
+	if master != nil {
+		masterProductID = master.ID
+	}
+	return s.offers(models.GTINForms(gtin), masterProductID, pincodes)
+}
+
+// GetOffersByMasterProduct retrieves the offers for a master catalog entry
+// from stores in the given pincodes.
+func (s *offerService) GetOffersByMasterProduct(ctx *gin.Context, masterProductID string, pincodes []string) ([]Offer, error) {
+	master, err := s.catalogRepository.GetByID(masterProductID)
+	if err != nil {
+		return nil, fmt.Errorf("master product %s: %w", masterProductID, err)
+	}
+	return s.offers(models.GTINForms(master.Barcode), master.ID, pincodes)
+}
+
+// offers lists the offers with stock that are available now, cheapest first. Offers at the same price
+// are ordered by store rating.
+func (s *offerService) offers(barcodes []string, masterProductID string, pincodes []string) ([]Offer, error) {
+	cleaned := []string{}
+	for _, pincode := range pincodes {
+		if pincode = strings.TrimSpace(pincode); pincode != "" {
+			cleaned = append(cleaned, pincode)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, invalidf("at least one pincode is required")
+	}
+
+	matches, err := s.productRepository.GetOffersByPincodes(barcodes, masterProductID, cleaned)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]models.Product, len(matches))
+	for i := range matches {
+		products[i] = matches[i].Product
+	}
+	now := time.Now()
+	if err := s.priceResolver.Resolve(products, now); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(products, now); err != nil {
+		return nil, err
+	}
+
+	offers := []Offer{}
+	for i, product := range products {
+		if !product.Available {
+			continue
+		}
+		offer := Offer{
+			ProductID:          product.ID,
+			ProductName:        product.Name,
+			Barcode:            product.Barcode,
+			MasterProductID:    product.MasterProductID,
+			QuantityUnit:       product.QuantityUnit,
+			MRP:                product.MRP,
+			EffectivePrice:     product.EffectivePrice,
+			AppliedPriceRuleID: product.AppliedPriceRuleID,
+			StoreBasicDetails:  matches[i].StoreBasicDetails,
+		}
+		offer.StoreId = product.StoreID
+		if len(product.Images) > 0 {
+			offer.Image = product.Images[0].Image
+		}
+		offers = append(offers, offer)
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		if offers[i].EffectivePrice.Amount != offers[j].EffectivePrice.Amount {
+			return offers[i].EffectivePrice.Amount < offers[j].EffectivePrice.Amount
+		}
+		return offers[i].StoreRating > offers[j].StoreRating
+	})
+	return offers, nil
+}
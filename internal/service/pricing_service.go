@@ -0,0 +1,387 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// PriceRuleService defines the interface for managing scheduled price rules.
+type PriceRuleService interface {
+	CreatePriceRule(ctx *gin.Context, rule *models.PriceRule) (*models.PriceRule, error)
+	GetPriceRuleByID(ctx *gin.Context, id string) (*models.PriceRule, error)
+	GetPriceRulesByStoreID(ctx *gin.Context, storeID string) ([]models.PriceRule, error)
+	UpdatePriceRule(ctx *gin.Context, rule *models.PriceRule) (*models.PriceRule, error)
+	DeletePriceRule(ctx *gin.Context, id string) error
+	GetPriceHistoryByProductID(ctx *gin.Context, productID string) ([]models.PriceHistory, error)
+	GetProductPrice(ctx *gin.Context, productID string, variantType string, variantID int, at time.Time) (*ProductPrice, error)
+}
+
+// ProductPrice is the price of a product, or of one of its variants, at a point in time.
+type ProductPrice struct {
+	ProductID          string       `json:"product_id"`
+	VariantType        string       `json:"variant_type,omitempty"`
+	VariantID          int          `json:"variant_id,omitempty"`
+	MRP                models.Money `json:"mrp"`
+	DiscountPrice      models.Money `json:"discount_price"`
+	ListPrice          models.Money `json:"list_price"`
+	EffectivePrice     models.Money `json:"effective_price"`
+	AppliedPriceRuleID string       `json:"applied_price_rule_id,omitempty"`
+	At                 time.Time    `json:"at"`
+}
+
+type priceRuleService struct {
+	repo              repository.PricingRepository
+	productRepository repository.ProductRepository
+	priceResolver     PriceResolver
+}
+
+// NewPriceRuleService creates a new instance of PriceRuleService.
+func NewPriceRuleService(repo repository.PricingRepository, productRepository repository.ProductRepository, priceResolver PriceResolver) PriceRuleService {
+	return &priceRuleService{repo: repo, productRepository: productRepository, priceResolver: priceResolver}
+}
+
+// CreatePriceRule validates and stores a new price rule.
+func (s *priceRuleService) CreatePriceRule(ctx *gin.Context, rule *models.PriceRule) (*models.PriceRule, error) {
+	if err := s.validatePriceRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetPriceRuleByID retrieves a price rule by its ID.
+func (s *priceRuleService) GetPriceRuleByID(ctx *gin.Context, id string) (*models.PriceRule, error) {
+	return s.repo.GetRuleByID(id)
+}
+
+// GetPriceRulesByStoreID retrieves every price rule of a store.
+func (s *priceRuleService) GetPriceRulesByStoreID(ctx *gin.Context, storeID string) ([]models.PriceRule, error) {
+	return s.repo.GetRulesByStoreID(storeID)
+}
+
+// UpdatePriceRule updates an existing price rule. The store of a rule cannot be changed.
+func (s *priceRuleService) UpdatePriceRule(ctx *gin.Context, rule *models.PriceRule) (*models.PriceRule, error) {
+	existing, err := s.repo.GetRuleByID(rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.StoreID = existing.StoreID
+	rule.CreatedAt = existing.CreatedAt
+	if err := s.validatePriceRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeletePriceRule deletes a price rule by its ID.
+func (s *priceRuleService) DeletePriceRule(ctx *gin.Context, id string) error {
+	return s.repo.DeleteRule(id)
+}
+
+// GetPriceHistoryByProductID retrieves the price history of a product.
+func (s *priceRuleService) GetPriceHistoryByProductID(ctx *gin.Context, productID string) ([]models.PriceHistory, error) {
+	return s.repo.GetHistoryByProductID(productID)
+}
+
+// GetProductPrice resolves the price of a product or variant at the given time,
+// which may lie in the future to preview a scheduled promotion.
+func (s *priceRuleService) GetProductPrice(ctx *gin.Context, productID string, variantType string, variantID int, at time.Time) (*ProductPrice, error) {
+	product, err := s.productRepository.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveProductPrice(s.priceResolver, product, variantType, variantID, at)
+}
+
+// ResolveProductPrice applies the price rules in effect at the given time to a
+// product and returns the price of the product or of the requested variant.
+func ResolveProductPrice(resolver PriceResolver, product models.Product, variantType string, variantID int, at time.Time) (*ProductPrice, error) {
+	if err := resolver.ResolveProduct(&product, at); err != nil {
+		return nil, err
+	}
+
+	price := &ProductPrice{
+		ProductID:          product.ID,
+		MRP:                product.MRP,
+		DiscountPrice:      product.DiscountPrice,
+		ListPrice:          listPrice(product),
+		EffectivePrice:     product.EffectivePrice,
+		AppliedPriceRuleID: product.AppliedPriceRuleID,
+		At:                 at,
+	}
+
+	switch variantType {
+	case "":
+		return price, nil
+	case models.VariantTypeSize:
+		for _, variant := range product.SizeVariants {
+			if variant.ID == variantID {
+				price.VariantType, price.VariantID = variantType, variantID
+				if variant.Price.Amount > 0 {
+					price.ListPrice = variant.Price
+				}
+				price.EffectivePrice, price.AppliedPriceRuleID = variant.EffectivePrice, variant.AppliedPriceRuleID
+				return price, nil
+			}
+		}
+	case models.VariantTypeColor:
+		for _, variant := range product.ColorVariants {
+			if variant.ID == variantID {
+				price.VariantType, price.VariantID = variantType, variantID
+				if variant.Price.Amount > 0 {
+					price.ListPrice = variant.Price
+				}
+				price.EffectivePrice, price.AppliedPriceRuleID = variant.EffectivePrice, variant.AppliedPriceRuleID
+				return price, nil
+			}
+		}
+	}
+
+	return nil, validationf("product %s has no %s variant %d", product.ID, variantType, variantID)
+}
+
+func (s *priceRuleService) validatePriceRule(rule *models.PriceRule) error {
+	if rule.StoreID == "" {
+		return fieldErrorf("store_id", "store_id is required")
+	}
+
+	switch rule.DiscountType {
+	case models.DiscountTypePercentage:
+		if rule.Value <= 0 || rule.Value > 100 {
+			return fieldErrorf("percentage", "percentage must be between 1 and 100")
+		}
+	case models.DiscountTypeFixed:
+		if rule.Value <= 0 {
+			return validationf("fixed discount must be positive")
+		}
+	default:
+		return fieldErrorf("discount_type", "discount_type must be %s or %s", models.DiscountTypePercentage, models.DiscountTypeFixed)
+	}
+
+	if rule.StartsAt.IsZero() {
+		rule.StartsAt = time.Now()
+	}
+	if rule.EndsAt != nil && !rule.EndsAt.After(rule.StartsAt) {
+		return fieldErrorf("ends_at", "ends_at must be after starts_at")
+	}
+
+	switch rule.Scope {
+	case models.PriceRuleScopeStore:
+		rule.ProductID, rule.VariantType, rule.VariantID, rule.Category = "", "", 0, ""
+	case models.PriceRuleScopeCategory:
+		if strings.TrimSpace(rule.Category) == "" {
+			return fieldErrorf("category", "category is required for CATEGORY rules")
+		}
+		rule.ProductID, rule.VariantType, rule.VariantID = "", "", 0
+	case models.PriceRuleScopeProduct, models.PriceRuleScopeVariant:
+		product, err := s.productRepository.GetProductByID(rule.ProductID)
+		if err != nil {
+			return err
+		}
+		if product.StoreID != rule.StoreID {
+			return validationf("product does not belong to this store")
+		}
+		rule.Category = ""
+		if rule.Scope == models.PriceRuleScopeProduct {
+			rule.VariantType, rule.VariantID = "", 0
+		} else if rule.VariantType == "" || !hasVariant(product, rule.VariantType, rule.VariantID) {
+			return validationf("product %s has no %s variant %d", rule.ProductID, rule.VariantType, rule.VariantID)
+		}
+	default:
+		return fieldErrorf("scope", "scope must be one of %s, %s, %s or %s",
+			models.PriceRuleScopeProduct, models.PriceRuleScopeVariant, models.PriceRuleScopeCategory, models.PriceRuleScopeStore)
+	}
+
+	return nil
+}
+
+// PriceResolver fills the effective prices of products from the price rules in
+// effect at a given time. Every read path goes through it, so a scheduled
+// promotion shows up without anyone editing the product.
+type PriceResolver interface {
+	Resolve(products []models.Product, at time.Time) error
+	ResolveProduct(product *models.Product, at time.Time) error
+}
+
+type priceResolver struct {
+	repo repository.PricingRepository
+}
+
+// NewPriceResolver creates a new instance of PriceResolver.
+func NewPriceResolver(repo repository.PricingRepository) PriceResolver {
+	return &priceResolver{repo: repo}
+}
+
+// Resolve sets EffectivePrice on the given products and their variants in place.
+func (r *priceResolver) Resolve(products []models.Product, at time.Time) error {
+	storeIDs := []string{}
+	seen := map[string]bool{}
+	for _, product := range products {
+		if !seen[product.StoreID] {
+			seen[product.StoreID] = true
+			storeIDs = append(storeIDs, product.StoreID)
+		}
+	}
+
+	rules, err := r.repo.GetActiveRulesByStoreIDs(storeIDs, at)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		applyPriceRules(&products[i], rules)
+	}
+	return nil
+}
+
+// ResolveProduct sets EffectivePrice on a single product and its variants.
+func (r *priceResolver) ResolveProduct(product *models.Product, at time.Time) error {
+	products := []models.Product{*product}
+	if err := r.Resolve(products, at); err != nil {
+		return err
+	}
+	*product = products[0]
+	return nil
+}
+
+// listPrice is the price of a product before price rules: the discount price
+// when one is set below the MRP, the MRP otherwise.
+func listPrice(product models.Product) models.Money {
+	if product.DiscountPrice.Amount > 0 && product.DiscountPrice.Amount < product.MRP.Amount {
+		return product.DiscountPrice
+	}
+	return product.MRP
+}
+
+func applyPriceRules(product *models.Product, rules []models.PriceRule) {
+	base := listPrice(*product)
+	product.EffectivePrice, product.AppliedPriceRuleID = bestPrice(base, *product, "", 0, rules)
+
+	for i, variant := range product.SizeVariants {
+		variantBase := variant.Price
+		if variantBase.Amount == 0 {
+			variantBase = base
+		}
+		product.SizeVariants[i].EffectivePrice, product.SizeVariants[i].AppliedPriceRuleID =
+			bestPrice(variantBase, *product, models.VariantTypeSize, variant.ID, rules)
+	}
+	for i, variant := range product.ColorVariants {
+		variantBase := variant.Price
+		if variantBase.Amount == 0 {
+			variantBase = base
+		}
+		product.ColorVariants[i].EffectivePrice, product.ColorVariants[i].AppliedPriceRuleID =
+			bestPrice(variantBase, *product, models.VariantTypeColor, variant.ID, rules)
+	}
+}
+
+// bestPrice returns the lowest price any applicable rule gives for the
+// product, or the variant when variantType is set, and the ID of that rule.
+func bestPrice(base models.Money, product models.Product, variantType string, variantID int, rules []models.PriceRule) (models.Money, string) {
+	price, ruleID := base, ""
+	for _, rule := range rules {
+		if !priceRuleApplies(rule, product, variantType, variantID) {
+			continue
+		}
+		if discounted := discountedPrice(base, rule); discounted.Amount < price.Amount {
+			price, ruleID = discounted, rule.ID
+		}
+	}
+	return price, ruleID
+}
+
+func priceRuleApplies(rule models.PriceRule, product models.Product, variantType string, variantID int) bool {
+	if rule.StoreID != product.StoreID {
+		return false
+	}
+
+	switch rule.Scope {
+	case models.PriceRuleScopeStore:
+		return true
+	case models.PriceRuleScopeCategory:
+		return strings.EqualFold(strings.TrimSpace(rule.Category), strings.TrimSpace(product.Category))
+	case models.PriceRuleScopeProduct:
+		return rule.ProductID == product.ID
+	case models.PriceRuleScopeVariant:
+		return rule.ProductID == product.ID && rule.VariantType == variantType && rule.VariantID == variantID
+	}
+	return false
+}
+
+func discountedPrice(base models.Money, rule models.PriceRule) models.Money {
+	var price models.Money
+	switch rule.DiscountType {
+	case models.DiscountTypePercentage:
+		price = base.Sub(base.Percent(rule.Value * 100))
+	case models.DiscountTypeFixed:
+		price = base.Sub(models.NewMoney(rule.Value, base.Currency))
+	default:
+		return base
+	}
+	if price.Amount < 0 {
+		price.Amount = 0
+	}
+	return price
+}
+
+// priceHistoryEntries lists the prices of after that differ from before. A
+// nil before records every price, as for a newly created product.
+func priceHistoryEntries(before *models.Product, after models.Product, at time.Time) []models.PriceHistory {
+	entries := []models.PriceHistory{}
+
+	if before == nil || before.MRP != after.MRP || before.DiscountPrice != after.DiscountPrice {
+		entries = append(entries, models.PriceHistory{
+			ProductID:     after.ID,
+			StoreID:       after.StoreID,
+			MRP:           after.MRP,
+			DiscountPrice: after.DiscountPrice,
+			ChangedAt:     at,
+		})
+	}
+
+	previousPrices := map[string]models.Money{}
+	if before != nil {
+		for _, variant := range before.SizeVariants {
+			previousPrices[fmt.Sprintf("%s/%s", models.VariantTypeSize, variant.Size)] = variant.Price
+		}
+		for _, variant := range before.ColorVariants {
+			previousPrices[fmt.Sprintf("%s/%s", models.VariantTypeColor, variant.Color)] = variant.Price
+		}
+	}
+
+	// variants are matched by label because UpdateProduct replaces the variant rows
+	addVariant := func(variantType string, variantID int, label string, price models.Money) {
+		if previous, ok := previousPrices[fmt.Sprintf("%s/%s", variantType, label)]; ok && previous == price {
+			return
+		}
+		entries = append(entries, models.PriceHistory{
+			ProductID:   after.ID,
+			StoreID:     after.StoreID,
+			VariantType: variantType,
+			VariantID:   variantID,
+			Price:       price,
+			ChangedAt:   at,
+		})
+	}
+	for _, variant := range after.SizeVariants {
+		addVariant(models.VariantTypeSize, variant.ID, variant.Size, variant.Price)
+	}
+	for _, variant := range after.ColorVariants {
+		addVariant(models.VariantTypeColor, variant.ID, variant.Color, variant.Price)
+	}
+
+	return entries
+}
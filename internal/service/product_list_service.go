@@ -0,0 +1,313 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// Sort keys of a store product listing. Any of them can be prefixed with "-"
+// to reverse the order. Price is the effective price, after the price rules
+// in effect when the page is read.
+const (
+	ProductSortDisplayOrder = repository.ProductSortDisplayOrder
+	ProductSortName         = repository.ProductSortName
+	ProductSortPrice        = "price"
+	ProductSortNewest       = repository.ProductSortNewest
+	ProductSortStock        = repository.ProductSortStock
+)
+
+// Stock states a store product listing can be narrowed to.
+const (
+	StockStateInStock    = repository.StockStateInStock
+	StockStateLowStock   = repository.StockStateLowStock
+	StockStateOutOfStock = repository.StockStateOutOfStock
+)
+
+// Relations a store product listing loads on request. Products are listed
+// without them by default.
+const (
+	ProductIncludeVariants       = repository.ProductIncludeVariants
+	ProductIncludeImages         = repository.ProductIncludeImages
+	ProductIncludeTransactions   = repository.ProductIncludeTransactions
+	ProductIncludeAttributes     = repository.ProductIncludeAttributes
+	ProductIncludeModifierGroups = repository.ProductIncludeModifierGroups
+)
+
+// ProductIncludes lists every relation a store product listing can include.
+var ProductIncludes = []string{
+	ProductIncludeVariants,
+	ProductIncludeImages,
+	ProductIncludeTransactions,
+	ProductIncludeAttributes,
+	ProductIncludeModifierGroups,
+}
+
+const (
+	defaultProductListLimit = 50
+
+	// MaxProductListLimit is the largest page of a store product listing.
+	MaxProductListLimit = 200
+)
+
+// ProductListQuery asks for a page of the products of a store. CategoryID
+// includes the products of its subcategories. A cursor from a previous page
+// carries the sort and filters of its listing, which take precedence over the
+// ones of the query.
+type ProductListQuery struct {
+	StoreID    string
+	Limit      int
+	Cursor     string
+	Sort       string
+	CategoryID string
+	Stock      string
+	Attributes []AttributeFilter
+	Include    []string
+}
+
+// ProductPage is a page of a store product listing. NextCursor is empty on
+// the last page.
+type ProductPage struct {
+	Products   []models.Product `json:"products"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// productCursor is the position in a store product listing. The listing is
+// read as of At, so that products created while paging do not shift the
+// pages that follow.
+type productCursor struct {
+	Sort       string            `json:"sort"`
+	CategoryID string            `json:"category_id,omitempty"`
+	Stock      string            `json:"stock,omitempty"`
+	Attributes []AttributeFilter `json:"attributes,omitempty"`
+	At         time.Time         `json:"at"`
+	Offset     int               `json:"offset"`
+}
+
+// ListStoreProducts retrieves a page of the products of a store with their
+// prices and availability resolved.
+func (s *productService) ListStoreProducts(ctx *gin.Context, query ProductListQuery) (*ProductPage, error) {
+	if strings.TrimSpace(query.StoreID) == "" {
+		return nil, invalidf("store id is required")
+	}
+	cursor, err := newProductCursor(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultProductListLimit
+	}
+	if limit > MaxProductListLimit {
+		limit = MaxProductListLimit
+	}
+	include, err := normalizeProductIncludes(query.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	var categoryIDs []string
+	if cursor.CategoryID != "" {
+		categories, err := s.CategoryRepository.GetByStoreID(query.StoreID)
+		if err != nil {
+			return nil, err
+		}
+		categoryIDs = descendantCategoryIDs(categories, cursor.CategoryID)
+		if len(categoryIDs) == 0 {
+			return nil, validationf("category %s is not available to store %s", cursor.CategoryID, query.StoreID)
+		}
+	}
+
+	sortKey := strings.TrimPrefix(cursor.Sort, "-")
+	listQuery := repository.ProductListQuery{
+		StoreID:       query.StoreID,
+		CategoryIDs:   categoryIDs,
+		Stock:         cursor.Stock,
+		Attributes:    cursor.Attributes,
+		Sort:          sortKey,
+		Descending:    sortKey != cursor.Sort,
+		CreatedBefore: cursor.At,
+		Offset:        cursor.Offset,
+		// one more product than asked tells whether there is a next page
+		Limit:   limit + 1,
+		Include: include,
+	}
+	var products []models.Product
+	if sortKey == ProductSortPrice {
+		products, err = s.listStoreProductsByPrice(listQuery, time.Now())
+	} else {
+		products, err = s.ProductRepository.ListStoreProducts(listQuery)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		next := *cursor
+		next.Offset += limit
+		page.NextCursor = next.encode()
+	}
+
+	if err := s.priceResolver.Resolve(page.Products, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.availabilityResolver.Resolve(page.Products, time.Now()); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// listStoreProductsByPrice retrieves the window of a listing ordered by the
+// effective prices of its products at now, products tied on price by id. The
+// prices of the whole listing are resolved to find the window, and only the
+// products in it are loaded.
+func (s *productService) listStoreProductsByPrice(query repository.ProductListQuery, now time.Time) ([]models.Product, error) {
+	prices, err := s.ProductRepository.ListStoreProductPrices(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.priceResolver.Resolve(prices, now); err != nil {
+		return nil, err
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		if query.Descending {
+			a, b = b, a
+		}
+		if a.EffectivePrice.Amount != b.EffectivePrice.Amount {
+			return a.EffectivePrice.Amount < b.EffectivePrice.Amount
+		}
+		return a.ID < b.ID
+	})
+
+	if query.Offset >= len(prices) {
+		return []models.Product{}, nil
+	}
+	window := prices[query.Offset:]
+	if len(window) > query.Limit {
+		window = window[:query.Limit]
+	}
+	position := make(map[string]int, len(window))
+	ids := make([]string, len(window))
+	for i, product := range window {
+		position[product.ID] = i
+		ids[i] = product.ID
+	}
+
+	query.IDs, query.Offset = ids, 0
+	products, err := s.ProductRepository.ListStoreProducts(query)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return position[products[i].ID] < position[products[j].ID]
+	})
+	return products, nil
+}
+
+// newProductCursor returns the cursor of a query, or the start of a new
+// listing read at now when the query has no cursor.
+func newProductCursor(query ProductListQuery, now time.Time) (*productCursor, error) {
+	if query.Cursor != "" {
+		return decodeProductCursor(query.Cursor)
+	}
+
+	sort := strings.ToLower(strings.TrimSpace(query.Sort))
+	if sort == "" {
+		sort = ProductSortDisplayOrder
+	}
+	if !validProductSort(sort) {
+		return nil, invalidf("unknown sort %q", query.Sort)
+	}
+	stock := strings.ToLower(strings.TrimSpace(query.Stock))
+	if !validStockState(stock) {
+		return nil, invalidf("unknown stock state %q", query.Stock)
+	}
+
+	attributes := make([]AttributeFilter, len(query.Attributes))
+	for i, filter := range query.Attributes {
+		filter.Key = normalizeAttributeKey(filter.Key)
+		filter.Value = strings.TrimSpace(filter.Value)
+		attributes[i] = filter
+	}
+
+	return &productCursor{
+		Sort:       sort,
+		CategoryID: strings.TrimSpace(query.CategoryID),
+		Stock:      stock,
+		Attributes: attributes,
+		At:         now.UTC(),
+	}, nil
+}
+
+func decodeProductCursor(value string) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalidf("invalid product cursor")
+	}
+	var cursor productCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalidf("invalid product cursor")
+	}
+	if !validProductSort(cursor.Sort) || !validStockState(cursor.Stock) || cursor.Offset < 0 || cursor.At.IsZero() {
+		return nil, invalidf("invalid product cursor")
+	}
+	return &cursor, nil
+}
+
+func (c productCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func validProductSort(sort string) bool {
+	switch strings.TrimPrefix(sort, "-") {
+	case ProductSortDisplayOrder, ProductSortName, ProductSortPrice, ProductSortNewest, ProductSortStock:
+		return true
+	}
+	return false
+}
+
+// validStockState tells whether state is a known stock state, or empty for
+// products in any state.
+func validStockState(state string) bool {
+	switch state {
+	case "", StockStateInStock, StockStateLowStock, StockStateOutOfStock:
+		return true
+	}
+	return false
+}
+
+// normalizeProductIncludes checks the relations asked of a listing and drops
+// duplicates.
+func normalizeProductIncludes(include []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, name := range include {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		known := false
+		for _, relation := range ProductIncludes {
+			if relation == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, invalidf("unknown include %q", name)
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// GoodsReceiptLine is the quantity received against a single purchase order line.
+// Cost overrides the line cost when the supplier invoiced a different price.
+type GoodsReceiptLine struct {
+	LineID   int             `json:"line_id"`
+	Quantity models.Quantity `json:"quantity"`
+	Cost     models.Money    `json:"cost"`
+}
+
+// GoodsReceipt records goods received against a purchase order. A receipt
+// without lines receives everything still outstanding on the order.
+type GoodsReceipt struct {
+	Lines       []GoodsReceiptLine `json:"lines"`
+	Description string             `json:"description"`
+}
+
+// PurchaseOrderService defines the interface for the purchase order service.
+type PurchaseOrderService interface {
+	CreatePurchaseOrder(ctx *gin.Context, order *models.PurchaseOrder) (*models.PurchaseOrder, error)
+	CreatePurchaseOrderFromReplenishment(ctx *gin.Context, storeID string, supplierID string, windowDays int) (*models.PurchaseOrder, error)
+	GetPurchaseOrderByID(ctx *gin.Context, id string) (*models.PurchaseOrder, error)
+	GetPurchaseOrdersByStoreID(ctx *gin.Context, storeID string, status string) ([]models.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx *gin.Context, order *models.PurchaseOrder) (*models.PurchaseOrder, error)
+	UpdatePurchaseOrderStatus(ctx *gin.Context, id string, status string) (*models.PurchaseOrder, error)
+	ReceiveGoods(ctx *gin.Context, id string, receipt GoodsReceipt) (*models.PurchaseOrder, error)
+	DeletePurchaseOrder(ctx *gin.Context, id string) error
+}
+
+type purchaseOrderService struct {
+	repo                 repository.PurchaseOrderRepository
+	supplierRepository   repository.SupplierRepository
+	productRepository    repository.ProductRepository
+	replenishmentService ReplenishmentService
+}
+
+// NewPurchaseOrderService creates a new instance of PurchaseOrderService.
+func NewPurchaseOrderService(repo repository.PurchaseOrderRepository, supplierRepository repository.SupplierRepository,
+	productRepository repository.ProductRepository, replenishmentService ReplenishmentService,
+) PurchaseOrderService {
+	return &purchaseOrderService{
+		repo:                 repo,
+		supplierRepository:   supplierRepository,
+		productRepository:    productRepository,
+		replenishmentService: replenishmentService,
+	}
+}
+
+// CreatePurchaseOrder creates a new purchase order in DRAFT status.
+func (s *purchaseOrderService) CreatePurchaseOrder(ctx *gin.Context, order *models.PurchaseOrder) (*models.PurchaseOrder, error) {
+	if err := s.validatePurchaseOrder(order); err != nil {
+		return nil, err
+	}
+
+	order.Status = models.PurchaseOrderStatusDraft
+	order.OrderedAt = nil
+	order.ReceivedAt = nil
+	for i := range order.Lines {
+		order.Lines[i].ID = 0
+		order.Lines[i].ReceivedQuantity = 0
+	}
+
+	if err := s.repo.Create(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CreatePurchaseOrderFromReplenishment creates a DRAFT purchase order holding
+// the current reorder suggestions of a store.
+func (s *purchaseOrderService) CreatePurchaseOrderFromReplenishment(ctx *gin.Context, storeID string, supplierID string, windowDays int) (*models.PurchaseOrder, error) {
+	draft, err := s.replenishmentService.GetDraftPurchaseOrder(ctx, storeID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	if len(draft.Lines) == 0 {
+		return nil, validationf("no products need to be reordered")
+	}
+
+	order := &models.PurchaseOrder{
+		StoreID:    storeID,
+		SupplierID: supplierID,
+		Notes:      "Generated from reorder suggestions",
+	}
+	for _, line := range draft.Lines {
+		order.Lines = append(order.Lines, models.PurchaseOrderLine{
+			ProductID:   line.ProductID,
+			VariantType: line.VariantType,
+			VariantID:   line.VariantID,
+			Quantity:    line.Quantity,
+			Cost:        line.UnitCost,
+		})
+	}
+
+	return s.CreatePurchaseOrder(ctx, order)
+}
+
+// GetPurchaseOrderByID retrieves a purchase order by its ID.
+func (s *purchaseOrderService) GetPurchaseOrderByID(ctx *gin.Context, id string) (*models.PurchaseOrder, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetPurchaseOrdersByStoreID retrieves the purchase orders of a store.
+func (s *purchaseOrderService) GetPurchaseOrdersByStoreID(ctx *gin.Context, storeID string, status string) ([]models.PurchaseOrder, error) {
+	return s.repo.GetAllByStoreID(storeID, status)
+}
+
+// UpdatePurchaseOrder replaces the supplier, notes and lines of a purchase
+// order. Only DRAFT purchase orders can be edited.
+func (s *purchaseOrderService) UpdatePurchaseOrder(ctx *gin.Context, order *models.PurchaseOrder) (*models.PurchaseOrder, error) {
+	existing, err := s.repo.GetByID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != models.PurchaseOrderStatusDraft {
+		return nil, conflictf("purchase order is %s, only DRAFT purchase orders can be edited", existing.Status)
+	}
+
+	order.StoreID = existing.StoreID
+	if err := s.validatePurchaseOrder(order); err != nil {
+		return nil, err
+	}
+
+	order.Status = existing.Status
+	order.CreatedAt = existing.CreatedAt
+	for i := range order.Lines {
+		order.Lines[i].ID = 0
+		order.Lines[i].PurchaseOrderID = order.ID
+		order.Lines[i].ReceivedQuantity = 0
+	}
+
+	if err := s.repo.Update(order); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(order.ID)
+}
+
+// UpdatePurchaseOrderStatus moves a purchase order to ORDERED or CANCELLED.
+// RECEIVED and PARTIALLY_RECEIVED are only reached by receiving goods.
+func (s *purchaseOrderService) UpdatePurchaseOrderStatus(ctx *gin.Context, id string, status string) (*models.PurchaseOrder, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch status {
+	case models.PurchaseOrderStatusOrdered:
+		if order.Status != models.PurchaseOrderStatusDraft {
+			return nil, conflictf("cannot order a purchase order that is %s", order.Status)
+		}
+		order.OrderedAt = &now
+	case models.PurchaseOrderStatusCancelled:
+		if order.Status == models.PurchaseOrderStatusReceived || order.Status == models.PurchaseOrderStatusCancelled {
+			return nil, conflictf("cannot cancel a purchase order that is %s", order.Status)
+		}
+	default:
+		return nil, fieldErrorf("status", "status must be %s or %s", models.PurchaseOrderStatusOrdered, models.PurchaseOrderStatusCancelled)
+	}
+
+	order.Status = status
+	order.UpdatedAt = now
+	if err := s.repo.UpdateStatus(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ReceiveGoods books goods received against an ORDERED or PARTIALLY_RECEIVED
+// purchase order. Every received line writes a PURCHASE inventory transaction
+// at the received cost, and the order becomes RECEIVED once nothing is outstanding.
+func (s *purchaseOrderService) ReceiveGoods(ctx *gin.Context, id string, receipt GoodsReceipt) (*models.PurchaseOrder, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.PurchaseOrderStatusOrdered && order.Status != models.PurchaseOrderStatusPartiallyReceived {
+		return nil, conflictf("cannot receive goods against a purchase order that is %s", order.Status)
+	}
+
+	receiptLines := receipt.Lines
+	if len(receiptLines) == 0 {
+		for _, line := range order.Lines {
+			if outstanding := line.Quantity - line.ReceivedQuantity; outstanding > 0 {
+				receiptLines = append(receiptLines, GoodsReceiptLine{LineID: line.ID, Quantity: outstanding})
+			}
+		}
+	}
+	if len(receiptLines) == 0 {
+		return nil, conflictf("nothing left to receive on this purchase order")
+	}
+
+	description := receipt.Description
+	if description == "" {
+		description = fmt.Sprintf("Received against purchase order %s", order.ID)
+	}
+
+	receipts := []repository.LineReceipt{}
+	transactions := []models.InventoryTransaction{}
+	for _, receiptLine := range receiptLines {
+		index := -1
+		for i, line := range order.Lines {
+			if line.ID == receiptLine.LineID {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, validationf("line %d does not belong to purchase order %s", receiptLine.LineID, order.ID)
+		}
+
+		line := &order.Lines[index]
+		if receiptLine.Quantity <= 0 {
+			return nil, validationf("received quantity of line %d must be positive", line.ID)
+		}
+		if line.ReceivedQuantity+receiptLine.Quantity > line.Quantity {
+			return nil, validationf("line %d has only %s units outstanding", line.ID, line.Quantity-line.ReceivedQuantity)
+		}
+
+		cost := line.Cost
+		if receiptLine.Cost.Amount > 0 {
+			cost = receiptLine.Cost
+		}
+
+		receipts = append(receipts, repository.LineReceipt{LineID: line.ID, Quantity: receiptLine.Quantity})
+		transactions = append(transactions, models.InventoryTransaction{
+			ProductID:       line.ProductID,
+			VariantType:     line.VariantType,
+			VariantID:       line.VariantID,
+			Quantity:        receiptLine.Quantity,
+			Price:           cost,
+			TransactionType: models.TransactionTypePurchase,
+			Description:     description,
+			ReferenceID:     order.ID,
+		})
+	}
+
+	// the checks above ran on a copy read outside the receipt; the
+	// repository checks the outstanding quantities again as it books them
+	var overReceived *repository.OverReceivedLineError
+	err = s.repo.Receive(order, receipts, transactions, time.Now())
+	switch {
+	case errors.As(err, &overReceived):
+		return nil, validationf("line %d has only %s units outstanding", overReceived.LineID, overReceived.Outstanding)
+	case errors.Is(err, repository.ErrPurchaseOrderNotReceivable):
+		return nil, conflictf("purchase order %s is no longer open for receipts", order.ID)
+	case err != nil:
+		return nil, err
+	}
+	return order, nil
+}
+
+// DeletePurchaseOrder deletes a DRAFT purchase order. Orders that were sent to
+// the supplier are cancelled instead so that their history is kept.
+func (s *purchaseOrderService) DeletePurchaseOrder(ctx *gin.Context, id string) error {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.PurchaseOrderStatusDraft {
+		return conflictf("purchase order is %s, only DRAFT purchase orders can be deleted", order.Status)
+	}
+	return s.repo.Delete(id)
+}
+
+// validatePurchaseOrder checks the supplier and lines of a purchase order
+// against the store it is raised for.
+func (s *purchaseOrderService) validatePurchaseOrder(order *models.PurchaseOrder) error {
+	if order.StoreID == "" {
+		return fieldErrorf("store_id", "store_id is required")
+	}
+
+	if order.SupplierID != "" {
+		supplier, err := s.supplierRepository.GetByID(order.SupplierID)
+		if err != nil {
+			return err
+		}
+		if supplier.StoreID != order.StoreID {
+			return validationf("supplier does not belong to this store")
+		}
+	}
+
+	if len(order.Lines) == 0 {
+		return validationf("purchase order needs at least one line")
+	}
+	for _, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return validationf("line quantity must be positive")
+		}
+		if line.Cost.Amount < 0 {
+			return validationf("line cost must not be negative")
+		}
+
+		product, err := s.productRepository.GetProductByID(line.ProductID)
+		if err != nil {
+			return err
+		}
+		if product.StoreID != order.StoreID {
+			return validationf("product %s does not belong to this store", line.ProductID)
+		}
+		if !hasVariant(product, line.VariantType, line.VariantID) {
+			return validationf("product %s has no %s variant %d", line.ProductID, line.VariantType, line.VariantID)
+		}
+	}
+
+	return nil
+}
+
+// hasVariant reports whether the product has the given variant. An empty
+// variant type refers to the product itself.
+func hasVariant(product models.Product, variantType string, variantID int) bool {
+	switch variantType {
+	case "":
+		return true
+	case models.VariantTypeSize:
+		for _, variant := range product.SizeVariants {
+			if variant.ID == variantID {
+				return true
+			}
+		}
+	case models.VariantTypeColor:
+		for _, variant := range product.ColorVariants {
+			if variant.ID == variantID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// Defaults applied to products that have no replenishment setting of their own.
+const (
+	DefaultSalesWindowDays  = 30
+	DefaultLeadTimeDays     = 7
+	DefaultSafetyStockDays  = 3
+	DefaultReviewPeriodDays = 7
+)
+
+// ReorderSuggestion is the replenishment proposal for a single product or variant.
+type ReorderSuggestion struct {
+	ProductID         string          `json:"product_id"`
+	ProductName       string          `json:"product_name"`
+	VariantType       string          `json:"variant_type,omitempty"`
+	VariantID         int             `json:"variant_id,omitempty"`
+	VariantLabel      string          `json:"variant_label,omitempty"`
+	QuantityUnit      models.Unit     `json:"quantity_unit"`
+	CurrentStock      models.Quantity `json:"current_stock"`
+	CriticalQuantity  models.Quantity `json:"critical_quantity"`
+	UnitsSold         models.Quantity `json:"units_sold"`
+	AverageDailySales float64         `json:"average_daily_sales"`
+	LeadTimeDays      int             `json:"lead_time_days"`
+	SafetyStockDays   int             `json:"safety_stock_days"`
+	ReviewPeriodDays  int             `json:"review_period_days"`
+	ReorderPoint      models.Quantity `json:"reorder_point"`
+	SuggestedQuantity models.Quantity `json:"suggested_quantity"`
+	UnitCost          models.Money    `json:"unit_cost"`
+}
+
+// DraftPurchaseOrderLine is a line of a DraftPurchaseOrder.
+type DraftPurchaseOrderLine struct {
+	ProductID    string          `json:"product_id"`
+	ProductName  string          `json:"product_name"`
+	VariantType  string          `json:"variant_type,omitempty"`
+	VariantID    int             `json:"variant_id,omitempty"`
+	VariantLabel string          `json:"variant_label,omitempty"`
+	QuantityUnit models.Unit     `json:"quantity_unit"`
+	Quantity     models.Quantity `json:"quantity"`
+	UnitCost     models.Money    `json:"unit_cost"`
+	LineTotal    models.Money    `json:"line_total"`
+}
+
+// DraftPurchaseOrder groups the reorder suggestions of a store into a purchase order draft.
+type DraftPurchaseOrder struct {
+	StoreID     string                   `json:"store_id"`
+	GeneratedAt time.Time                `json:"generated_at"`
+	Lines       []DraftPurchaseOrderLine `json:"lines"`
+	TotalCost   models.Money             `json:"total_cost"`
+}
+
+// ReplenishmentService defines the interface for the replenishment service.
+type ReplenishmentService interface {
+	UpsertSetting(ctx *gin.Context, setting *models.ReplenishmentSetting) (*models.ReplenishmentSetting, error)
+	GetSettingsByStoreID(ctx *gin.Context, storeID string) ([]models.ReplenishmentSetting, error)
+	GetReorderSuggestions(ctx *gin.Context, storeID string, windowDays int, includeAll bool) ([]ReorderSuggestion, error)
+	GetDraftPurchaseOrder(ctx *gin.Context, storeID string, windowDays int) (*DraftPurchaseOrder, error)
+}
+
+type replenishmentService struct {
+	repo              repository.ReplenishmentRepository
+	productRepository repository.ProductRepository
+}
+
+// NewReplenishmentService creates a new instance of ReplenishmentService.
+func NewReplenishmentService(repo repository.ReplenishmentRepository, productRepository repository.ProductRepository) ReplenishmentService {
+	return &replenishmentService{repo: repo, productRepository: productRepository}
+}
+
+type replenishmentKey struct {
+	productID   string
+	variantType string
+	variantID   int
+}
+
+// UpsertSetting validates and stores the replenishment parameters of a product or variant.
+func (s *replenishmentService) UpsertSetting(ctx *gin.Context, setting *models.ReplenishmentSetting) (*models.ReplenishmentSetting, error) {
+	if setting.LeadTimeDays < 0 || setting.SafetyStockDays < 0 || setting.ReviewPeriodDays < 0 || setting.MinOrderQuantity < 0 {
+		return nil, validationf("replenishment parameters must not be negative")
+	}
+
+	product, err := s.productRepository.GetProductByID(setting.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVariant(product, setting.VariantType, setting.VariantID) {
+		return nil, validationf("product %s has no %s variant %d", setting.ProductID, setting.VariantType, setting.VariantID)
+	}
+	setting.StoreID = product.StoreID
+	setting.UpdatedAt = time.Now()
+
+	if err := s.repo.UpsertSetting(setting); err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+// GetSettingsByStoreID retrieves the replenishment settings of a store.
+func (s *replenishmentService) GetSettingsByStoreID(ctx *gin.Context, storeID string) ([]models.ReplenishmentSetting, error) {
+	return s.repo.GetSettingsByStoreID(storeID)
+}
+
+// GetReorderSuggestions computes the average daily sales of every product and
+// variant of a store over the last windowDays and proposes a reorder quantity
+// for those whose stock covers less than the lead time plus safety stock.
+// When includeAll is set, items that need no reorder are returned as well.
+func (s *replenishmentService) GetReorderSuggestions(ctx *gin.Context, storeID string, windowDays int, includeAll bool) ([]ReorderSuggestion, error) {
+	if windowDays <= 0 {
+		windowDays = DefaultSalesWindowDays
+	}
+
+	// the stock of products and of each of their variants is summed from
+	// the inventory ledger, so received goods lower the suggestions at once
+	products, err := s.productRepository.GetProductsByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	settingList, err := s.repo.GetSettingsByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[replenishmentKey]models.ReplenishmentSetting{}
+	for _, setting := range settingList {
+		settings[replenishmentKey{setting.ProductID, setting.VariantType, setting.VariantID}] = setting
+	}
+
+	salesList, err := s.repo.GetSalesByStoreID(storeID, time.Now().AddDate(0, 0, -windowDays))
+	if err != nil {
+		return nil, err
+	}
+	sales := map[replenishmentKey]models.Quantity{}
+	for _, sale := range salesList {
+		sales[replenishmentKey{sale.ProductID, sale.VariantType, sale.VariantID}] += sale.Quantity
+		if sale.VariantType != "" {
+			// variant sales also count towards the product as a whole
+			sales[replenishmentKey{sale.ProductID, "", 0}] += sale.Quantity
+		}
+	}
+
+	costList, err := s.repo.GetLastPurchaseCostsByStoreID(storeID)
+	if err != nil {
+		return nil, err
+	}
+	costs := map[replenishmentKey]models.Money{}
+	for _, cost := range costList {
+		costs[replenishmentKey{cost.ProductID, cost.VariantType, cost.VariantID}] = cost.Price
+	}
+
+	suggestions := []ReorderSuggestion{}
+	for _, product := range products {
+		// bundles are restocked through their components
+		if product.Type == "post" || product.Type == models.ProductTypeBundle {
+			continue
+		}
+
+		base := ReorderSuggestion{
+			ProductID:        product.ID,
+			ProductName:      product.Name,
+			QuantityUnit:     product.QuantityUnit,
+			CriticalQuantity: product.CriticalQuantity,
+		}
+
+		targets := []ReorderSuggestion{}
+		for _, variant := range product.SizeVariants {
+			target := base
+			target.VariantType, target.VariantID, target.VariantLabel = models.VariantTypeSize, variant.ID, variant.Size
+			target.CurrentStock = variant.Quantity
+			targets = append(targets, target)
+		}
+		for _, variant := range product.ColorVariants {
+			target := base
+			target.VariantType, target.VariantID, target.VariantLabel = models.VariantTypeColor, variant.ID, variant.Color
+			target.CurrentStock = variant.Quantity
+			targets = append(targets, target)
+		}
+		if len(targets) == 0 {
+			base.CurrentStock = product.Quantity
+			targets = append(targets, base)
+		}
+
+		for _, target := range targets {
+			key := replenishmentKey{target.ProductID, target.VariantType, target.VariantID}
+			setting, ok := settings[key]
+			if !ok {
+				// variants fall back to the setting of their product
+				setting, ok = settings[replenishmentKey{target.ProductID, "", 0}]
+			}
+			if !ok {
+				setting = models.ReplenishmentSetting{
+					LeadTimeDays:     DefaultLeadTimeDays,
+					SafetyStockDays:  DefaultSafetyStockDays,
+					ReviewPeriodDays: DefaultReviewPeriodDays,
+				}
+			}
+
+			unitCost, ok := costs[key]
+			if !ok {
+				unitCost = costs[replenishmentKey{target.ProductID, "", 0}]
+			}
+
+			suggestion := computeReorderSuggestion(target, setting, sales[key], windowDays)
+			suggestion.UnitCost = unitCost
+			if includeAll || suggestion.SuggestedQuantity > 0 {
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+	}
+
+	return suggestions, nil
+}
+
+// computeReorderSuggestion fills the sales velocity and reorder figures of a suggestion.
+//
+// The reorder point covers the demand expected during the lead time plus the
+// safety stock days, but never falls below the product's CriticalQuantity. Once
+// stock is at or under the reorder point, the suggestion tops stock up to cover
+// one more review period on top of that, rounded up to MinOrderQuantity.
+func computeReorderSuggestion(suggestion ReorderSuggestion, setting models.ReplenishmentSetting, unitsSold models.Quantity, windowDays int) ReorderSuggestion {
+	if unitsSold < 0 {
+		unitsSold = 0
+	}
+	average := unitsSold.Float64() / float64(windowDays)
+
+	suggestion.UnitsSold = unitsSold
+	suggestion.AverageDailySales = math.Round(average*100) / 100
+	suggestion.LeadTimeDays = setting.LeadTimeDays
+	suggestion.SafetyStockDays = setting.SafetyStockDays
+	suggestion.ReviewPeriodDays = setting.ReviewPeriodDays
+
+	reorderPoint := models.QuantityFromFloat(average * float64(setting.LeadTimeDays+setting.SafetyStockDays)).Ceil()
+	if reorderPoint < suggestion.CriticalQuantity {
+		reorderPoint = suggestion.CriticalQuantity
+	}
+	suggestion.ReorderPoint = reorderPoint
+
+	if suggestion.CurrentStock > reorderPoint {
+		return suggestion
+	}
+
+	target := reorderPoint + models.QuantityFromFloat(average*float64(setting.ReviewPeriodDays)).Ceil()
+	quantity := target - suggestion.CurrentStock
+	if quantity <= 0 {
+		return suggestion
+	}
+	if setting.MinOrderQuantity > 0 && quantity < setting.MinOrderQuantity {
+		quantity = setting.MinOrderQuantity
+	}
+	suggestion.SuggestedQuantity = quantity
+
+	return suggestion
+}
+
+// GetDraftPurchaseOrder turns the current reorder suggestions of a store into a draft purchase order.
+func (s *replenishmentService) GetDraftPurchaseOrder(ctx *gin.Context, storeID string, windowDays int) (*DraftPurchaseOrder, error) {
+	suggestions, err := s.GetReorderSuggestions(ctx, storeID, windowDays, false)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &DraftPurchaseOrder{
+		StoreID:     storeID,
+		GeneratedAt: time.Now(),
+		Lines:       []DraftPurchaseOrderLine{},
+	}
+	for _, suggestion := range suggestions {
+		line := DraftPurchaseOrderLine{
+			ProductID:    suggestion.ProductID,
+			ProductName:  suggestion.ProductName,
+			VariantType:  suggestion.VariantType,
+			VariantID:    suggestion.VariantID,
+			VariantLabel: suggestion.VariantLabel,
+			QuantityUnit: suggestion.QuantityUnit,
+			Quantity:     suggestion.SuggestedQuantity,
+			UnitCost:     suggestion.UnitCost,
+			LineTotal:    suggestion.UnitCost.Times(suggestion.SuggestedQuantity),
+		}
+		draft.Lines = append(draft.Lines, line)
+		draft.TotalCost = draft.TotalCost.Add(line.LineTotal)
+	}
+
+	return draft, nil
+}
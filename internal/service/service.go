@@ -2,14 +2,16 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gin-gonic/gin"
 	"github.com/tanush-128/openzo_backend/product/internal/models"
 	"github.com/tanush-128/openzo_backend/product/internal/pb"
 	"github.com/tanush-128/openzo_backend/product/internal/repository"
-	"github.com/tanush-128/openzo_backend/product/internal/utils"
 )
 
 type ProductService interface {
@@ -17,25 +19,44 @@ type ProductService interface {
 	//CRUD
 	CreateProduct(ctx *gin.Context, req models.Product) (models.Product, error)
 	GetProductByID(ctx *gin.Context, id string) (models.Product, error)
-	GetProductsByStoreID(ctx *gin.Context, storeID string) ([]models.Product, error)
-	GetPostByPincode(ctx *gin.Context, pincode string) ([]repository.ProductWithStore, error)
-	ChangeProductQuantity(ctx *gin.Context, id string, quantity int) error
+	ListStoreProducts(ctx *gin.Context, query ProductListQuery) (*ProductPage, error)
+	GetFeed(ctx *gin.Context, query FeedQuery) (*FeedPage, error)
+	ChangeProductQuantity(ctx *gin.Context, id string, quantity models.Quantity, version int64) error
 	UpdateProduct(ctx *gin.Context, req models.Product) (models.Product, error)
-	UpdateDisplayOrder(ctx *gin.Context, id string, displayOrder int) error
-	BatchUpdateDisplayOrder(ctx *gin.Context, updates []models.Product) error
-	DeleteProduct(ctx *gin.Context, id string) error
+	UpdateDisplayOrder(ctx *gin.Context, id string, displayOrder int, version int64) error
+	BatchUpdateDisplayOrder(ctx *gin.Context, storeID string, updates []models.Product) error
+	DeleteProduct(ctx *gin.Context, id string, version int64) error
 }
 
 type productService struct {
-	ProductRepository repository.ProductRepository
-	imageClient       pb.ImageServiceClient
-	kafkaProducer     *kafka.Producer
+	ProductRepository    repository.ProductRepository
+	PricingRepository    repository.PricingRepository
+	CategoryRepository   repository.CategoryRepository
+	AttributeRepository  repository.AttributeRepository
+	CatalogRepository    repository.CatalogRepository
+	ImageRepository      repository.ImageRepository
+	priceResolver        PriceResolver
+	availabilityResolver AvailabilityResolver
+	imageClient          pb.ImageServiceClient
+	kafkaProducer        *kafka.Producer
 }
 
-func NewProductService(ProductRepository repository.ProductRepository,
-	imageClient pb.ImageServiceClient, kafkaProducer *kafka.Producer,
+func NewProductService(ProductRepository repository.ProductRepository, PricingRepository repository.PricingRepository,
+	CategoryRepository repository.CategoryRepository, AttributeRepository repository.AttributeRepository, CatalogRepository repository.CatalogRepository,
+	ImageRepository repository.ImageRepository, priceResolver PriceResolver, availabilityResolver AvailabilityResolver, imageClient pb.ImageServiceClient, kafkaProducer *kafka.Producer,
 ) ProductService {
-	return &productService{ProductRepository: ProductRepository, imageClient: imageClient, kafkaProducer: kafkaProducer}
+	return &productService{
+		ProductRepository:    ProductRepository,
+		PricingRepository:    PricingRepository,
+		CategoryRepository:   CategoryRepository,
+		AttributeRepository:  AttributeRepository,
+		CatalogRepository:    CatalogRepository,
+		ImageRepository:      ImageRepository,
+		priceResolver:        priceResolver,
+		availabilityResolver: availabilityResolver,
+		imageClient:          imageClient,
+		kafkaProducer:        kafkaProducer,
+	}
 }
 
 func (s *productService) GetProductByID(ctx *gin.Context, id string) (models.Product, error) {
@@ -44,68 +65,102 @@ func (s *productService) GetProductByID(ctx *gin.Context, id string) (models.Pro
 		return models.Product{}, err
 	}
 
+	if err := s.priceResolver.ResolveProduct(&Product, time.Now()); err != nil {
+		return models.Product{}, err
+	}
+	if err := s.availabilityResolver.ResolveProduct(&Product, time.Now()); err != nil {
+		return models.Product{}, err
+	}
+
 	return Product, nil
 }
 
-func (s *productService) GetProductsByStoreID(ctx *gin.Context, storeID string) ([]models.Product, error) {
-	Products, err := s.ProductRepository.GetProductsByStoreID(storeID)
+func (s *productService) CreateProduct(ctx *gin.Context, req models.Product) (models.Product, error) {
+	master, err := linkMasterProduct(s.CatalogRepository, &req)
 	if err != nil {
-		return []models.Product{}, err
+		return models.Product{}, err
+	}
+	if master != nil {
+		fillFromMasterProduct(&req, master)
 	}
 
-	return Products, nil
-}
-
-func (s *productService) GetPostByPincode(ctx *gin.Context, pincode string) ([]repository.ProductWithStore, error) {
-	Products, err := s.ProductRepository.GetPostByPincode(pincode)
+	if err := ValidateTaxClass(req.TaxClass); err != nil {
+		return models.Product{}, err
+	}
+	unit, err := normalizeQuantityUnit(req.QuantityUnit)
 	if err != nil {
-		return []repository.ProductWithStore{}, err
+		return models.Product{}, err
+	}
+	req.QuantityUnit = unit
+	if err := assignProductCategory(s.CategoryRepository, &req); err != nil {
+		return models.Product{}, err
+	}
+	if err := applyProductAttributes(s.AttributeRepository, s.CategoryRepository, &req); err != nil {
+		return models.Product{}, err
+	}
+	if err := validateComboItems(s.ProductRepository, &req); err != nil {
+		return models.Product{}, err
 	}
 
-	return Products, nil
-}
-
-func (s *productService) CreateProduct(ctx *gin.Context, req models.Product) (models.Product, error) {
 	form, err := ctx.MultipartForm()
 	if err != nil {
 
 		return models.Product{}, err
 	}
-	req.Images = []models.ProductImage{}
-	for _, file := range form.File["images"] {
-		log.Println(file.Filename)
-
-		imageBytes, err := utils.FileHeaderToBytes(file)
-
-		if err != nil {
-			return models.Product{}, err
-		}
-		imageURL, err := s.imageClient.UploadImage(ctx, &pb.ImageMessage{
-			ImageData: imageBytes,
-		})
-		if err != nil {
-			return models.Product{}, err
+	images, failedUploads, err := uploadFormImages(ctx, s.imageClient, form.File["images"], 1)
+	if err != nil {
+		return models.Product{}, err
+	}
+	imports, err := importImages(form.Value["image_urls"], len(images)+1)
+	if err != nil {
+		deleteImages(ctx, s.imageClient, uploadedImageURLs(images))
+		return models.Product{}, err
+	}
+	req.Images = append(images, imports...)
+	if len(req.Images) == 0 && master != nil {
+		for i, image := range master.Images {
+			req.Images = append(req.Images, models.ProductImage{Image: image, Status: models.ImageStatusReady, DisplayOrder: i + 1})
 		}
-
-		req.Images = append(req.Images, models.ProductImage{
-			Image: imageURL.Url,
-		})
-
 	}
 
 	createdProduct, err := s.ProductRepository.CreateProduct(req)
 	if err != nil {
+		deleteImages(ctx, s.imageClient, uploadedImageURLs(images))
 		return models.Product{}, err // Propagate error
 	}
+	keepFailedUploads(s.ImageRepository, createdProduct.ID, createdProduct.Images[:len(images)], failedUploads)
+	keepImports(s.ImageRepository, createdProduct.ID, createdProduct.Images[len(images):len(images)+len(imports)])
+	s.recordPriceHistory(nil, createdProduct)
+
+	if err := s.priceResolver.ResolveProduct(&createdProduct, time.Now()); err != nil {
+		return models.Product{}, err
+	}
+	if err := s.availabilityResolver.ResolveProduct(&createdProduct, time.Now()); err != nil {
+		return models.Product{}, err
+	}
 	go writeProductToKafka(s.kafkaProducer, createdProduct)
 	return createdProduct, nil
 }
 
+// recordPriceHistory appends the prices of after that changed since before to
+// the price history. The product is already saved at this point, so a failure
+// is logged rather than returned.
+func (s *productService) recordPriceHistory(before *models.Product, after models.Product) {
+	entries := priceHistoryEntries(before, after, time.Now())
+	if err := s.PricingRepository.CreateHistory(entries); err != nil {
+		log.Printf("failed to record price history of product %s: %v", after.ID, err)
+	}
+}
+
 func writeProductToKafka(p *kafka.Producer, product models.Product) {
 	// Produce messages to topic (asynchronously)
 	topic := "products"
 
+	// consumers of the topic read prices as numbers
 	value, _ := json.Marshal(product)
+	if legacy, err := models.EncodeLegacyMoney(value); err == nil {
+		value = legacy
+	}
 
 	p.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
@@ -115,6 +170,23 @@ func writeProductToKafka(p *kafka.Producer, product models.Product) {
 
 }
 
+// keepImageStatus carries the upload status of the existing images of a
+// product over to the images submitted with an update, as the service manages
+// it. Submitted images new to the product are ready.
+func keepImageStatus(images []models.ProductImage, existing []models.ProductImage) {
+	byID := map[int]models.ProductImage{}
+	for _, image := range existing {
+		byID[image.ID] = image
+	}
+	for i := range images {
+		if current, ok := byID[images[i].ID]; ok && images[i].ID != 0 {
+			images[i].Status, images[i].Error = current.Status, current.Error
+			continue
+		}
+		images[i].Status, images[i].Error = models.ImageStatusReady, ""
+	}
+}
+
 func (s *productService) UpdateProduct(ctx *gin.Context, req models.Product) (models.Product, error) {
 
 	// product, err := s.ProductRepository.GetProductByID(req.ID)
@@ -123,76 +195,181 @@ func (s *productService) UpdateProduct(ctx *gin.Context, req models.Product) (mo
 	// }
 	log.Printf("Product Images: %+v", req.Images)
 
-	form, err := ctx.MultipartForm()
-	if err != nil {
-
+	if _, err := linkMasterProduct(s.CatalogRepository, &req); err != nil {
 		return models.Product{}, err
 	}
 
-	for _, file := range form.File["images"] {
-		log.Println(file.Filename)
-
-		imageBytes, err := utils.FileHeaderToBytes(file)
+	if err := ValidateTaxClass(req.TaxClass); err != nil {
+		return models.Product{}, err
+	}
+	unit, err := normalizeQuantityUnit(req.QuantityUnit)
+	if err != nil {
+		return models.Product{}, err
+	}
+	req.QuantityUnit = unit
+	if err := assignProductCategory(s.CategoryRepository, &req); err != nil {
+		return models.Product{}, err
+	}
+	if err := applyProductAttributes(s.AttributeRepository, s.CategoryRepository, &req); err != nil {
+		return models.Product{}, err
+	}
+	if err := validateComboItems(s.ProductRepository, &req); err != nil {
+		return models.Product{}, err
+	}
 
-		if err != nil {
-			return models.Product{}, err
-		}
-		imageURL, err := s.imageClient.UploadImage(ctx, &pb.ImageMessage{
-			ImageData: imageBytes,
-		})
-		if err != nil {
-			return models.Product{}, err
+	var before *models.Product
+	if existing, err := s.ProductRepository.GetProductByID(req.ID); err == nil {
+		// refuse a stale update before uploading its images
+		if req.Version != 0 && req.Version != existing.Version {
+			return models.Product{}, s.staleProduct(ctx, &repository.StaleProductError{ProductID: existing.ID, Version: existing.Version})
 		}
+		before = &existing
+		keepImageStatus(req.Images, existing.Images)
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
 
-		req.Images = append(req.Images, models.ProductImage{
-			Image: imageURL.Url,
-		})
+		return models.Product{}, err
+	}
 
+	offset, displayOrder := len(req.Images), 1
+	for _, image := range req.Images {
+		displayOrder = max(displayOrder, image.DisplayOrder+1)
+	}
+	images, failedUploads, err := uploadFormImages(ctx, s.imageClient, form.File["images"], displayOrder)
+	if err != nil {
+		return models.Product{}, err
+	}
+	imports, err := importImages(form.Value["image_urls"], displayOrder+len(images))
+	if err != nil {
+		deleteImages(ctx, s.imageClient, uploadedImageURLs(images))
+		return models.Product{}, err
 	}
+	req.Images = append(req.Images, images...)
+	req.Images = append(req.Images, imports...)
 
 	updatedProduct, err := s.ProductRepository.UpdateProduct(req)
 	if err != nil {
-		return models.Product{}, err
+		deleteImages(ctx, s.imageClient, uploadedImageURLs(images))
+		return models.Product{}, s.staleProduct(ctx, err)
+	}
+	keepFailedUploads(s.ImageRepository, updatedProduct.ID, updatedProduct.Images[offset:offset+len(images)], failedUploads)
+	keepImports(s.ImageRepository, updatedProduct.ID, updatedProduct.Images[offset+len(images):])
+	if before != nil {
+		detached := detachedImageURLs(before.Images, updatedProduct.Images)
+		if err := s.ImageRepository.UnreferenceImages(detached, time.Now()); err != nil {
+			log.Printf("failed to unreference images of product %s: %v", updatedProduct.ID, err)
+		}
 	}
 	// updatedProduct.Images = req.Images
+	s.recordPriceHistory(before, updatedProduct)
+
+	if err := s.priceResolver.ResolveProduct(&updatedProduct, time.Now()); err != nil {
+		return models.Product{}, err
+	}
+	if err := s.availabilityResolver.ResolveProduct(&updatedProduct, time.Now()); err != nil {
+		return models.Product{}, err
+	}
 
 	go writeProductToKafka(s.kafkaProducer, updatedProduct)
 	return updatedProduct, nil
 }
 
-func (s *productService) UpdateDisplayOrder(ctx *gin.Context, id string, displayOrder int) error {
-	err := s.ProductRepository.UpdateDisplayOrder(id, displayOrder)
+func (s *productService) UpdateDisplayOrder(ctx *gin.Context, id string, displayOrder int, version int64) error {
+	err := s.ProductRepository.UpdateDisplayOrder(id, displayOrder, version)
 	if err != nil {
-		return err
+		return s.staleProduct(ctx, err)
 	}
 
 	// go writeProductToKafka(s.kafkaProducer, updatedProduct)
 	return nil
 }
 
-func (s *productService) BatchUpdateDisplayOrder(ctx *gin.Context, updates []models.Product) error {
+// BatchUpdateDisplayOrder sets the display order of several products at once.
+// With a storeID, every product must belong to that store.
+func (s *productService) BatchUpdateDisplayOrder(ctx *gin.Context, storeID string, updates []models.Product) error {
+	if storeID != "" {
+		ids := make([]string, len(updates))
+		for i, update := range updates {
+			ids[i] = update.ID
+		}
+		products, err := s.ProductRepository.GetProductsByIDs(ids)
+		if err != nil {
+			return err
+		}
+		inStore := map[string]bool{}
+		for _, product := range products {
+			inStore[product.ID] = product.StoreID == storeID
+		}
+		for _, id := range ids {
+			if !inStore[id] {
+				return notFoundf("product %s not found in store %s", id, storeID)
+			}
+		}
+	}
+
 	err := s.ProductRepository.BatchUpdateDisplayOrder(updates)
 	if err != nil {
-		return err
+		return s.staleProduct(ctx, err)
 	}
 
 	return nil
 }
 
-func (s *productService) ChangeProductQuantity(ctx *gin.Context, id string, quantity int) error {
-	err := s.ProductRepository.ChangeProductQuantity(id, quantity)
+// normalizeQuantityUnit maps a unit alias to the catalog, defaulting to pieces.
+func normalizeQuantityUnit(unit models.Unit) (models.Unit, error) {
+	if unit == "" {
+		return models.UnitPiece, nil
+	}
+	parsed, err := models.ParseUnit(string(unit))
 	if err != nil {
-		return err
+		return "", fieldErrorf("quantity_unit", "%v", err)
+	}
+	return parsed, nil
+}
+
+func (s *productService) ChangeProductQuantity(ctx *gin.Context, id string, quantity models.Quantity, version int64) error {
+	_, err := s.ProductRepository.ChangeProductQuantity(id, quantity, version)
+	if err != nil {
+		return s.staleProduct(ctx, err)
 	}
 
 	return nil
 }
 
-func (s *productService) DeleteProduct(ctx *gin.Context, id string) error {
-	err := s.ProductRepository.DeleteProduct(id)
+func (s *productService) DeleteProduct(ctx *gin.Context, id string, version int64) error {
+	images, err := s.ImageRepository.GetImagesByProductID(id)
 	if err != nil {
 		return err
 	}
+	err = s.ProductRepository.DeleteProduct(id, version)
+	if err != nil {
+		return s.staleProduct(ctx, err)
+	}
 
+	if err := s.ImageRepository.UnreferenceImages(uploadedImageURLs(images), time.Now()); err != nil {
+		log.Printf("failed to unreference images of product %s: %v", id, err)
+	}
 	return nil
 }
+
+// staleProduct turns a write refused for a stale version into a conflict
+// carrying the current product, for the client to merge its changes into.
+// Other errors are returned as they are.
+func (s *productService) staleProduct(ctx *gin.Context, err error) error {
+	var stale *repository.StaleProductError
+	if !errors.As(err, &stale) {
+		return err
+	}
+
+	current, getErr := s.GetProductByID(ctx, stale.ProductID)
+	if getErr != nil {
+		return getErr
+	}
+	return &Error{
+		Kind:    ErrorKindConflict,
+		Message: fmt.Sprintf("product %s was changed by someone else and is now at version %d", current.ID, current.Version),
+		Current: current,
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StoreEventsTopic is the topic the store service publishes its stores to.
+const StoreEventsTopic = "stores"
+
+// StoreEvent is a store as published by the store service. A store is
+// deleted by a tombstone, a message with its ID as key and no value, or by
+// an event with Deleted set.
+type StoreEvent struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Image       string   `json:"image"`
+	Address     string   `json:"address"`
+	Category    string   `json:"category"`
+	SubCategory string   `json:"sub_category"`
+	Description string   `json:"description"`
+	Rating      float64  `json:"rating"`
+	ReviewCount int      `json:"review_count"`
+	Pincode     string   `json:"pincode"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	Deleted     bool     `json:"deleted,omitempty"`
+}
+
+// ErrInvalidStoreEvent is wrapped by the errors of store events that can
+// never be applied.
+var ErrInvalidStoreEvent = errors.New("invalid store event")
+
+const (
+	// storeEventRetryInterval is the wait before a store event that failed
+	// to apply is tried again.
+	storeEventRetryInterval = 5 * time.Second
+	// storeBackfillBatch bounds the stores fetched by one backfill.
+	storeBackfillBatch = 500
+)
+
+// ConsumeStoreEvents keeps the local replica of store details up to date with
+// the stores topic. Stores that publish their coordinates also get their
+// location for distance searches. The consumer must not commit offsets
+// itself: the offset of an event is committed once the event is applied, and
+// an event that fails to apply is tried again until it is, so that no store
+// is skipped. Invalid events are skipped. It runs until the consumer is
+// closed.
+func ConsumeStoreEvents(consumer *kafka.Consumer, storeRepository repository.StoreRepository, geoRepository repository.GeoRepository) {
+	if err := consumer.SubscribeTopics([]string{StoreEventsTopic}, nil); err != nil {
+		log.Printf("failed to subscribe to %s: %v", StoreEventsTopic, err)
+		return
+	}
+
+	for {
+		msg, err := consumer.ReadMessage(-1)
+		if err != nil {
+			if consumer.IsClosed() {
+				return
+			}
+			log.Printf("store events consumer: %v", err)
+			continue
+		}
+		for {
+			err := ApplyStoreEvent(storeRepository, geoRepository, msg.Key, msg.Value)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrInvalidStoreEvent) {
+				log.Printf("skipping store event %s: %v", string(msg.Key), err)
+				break
+			}
+			log.Printf("failed to apply store event %s, retrying: %v", string(msg.Key), err)
+			time.Sleep(storeEventRetryInterval)
+			if consumer.IsClosed() {
+				return
+			}
+		}
+		if _, err := consumer.CommitMessage(msg); err != nil {
+			log.Printf("failed to commit store event %s: %v", string(msg.Key), err)
+		}
+	}
+}
+
+// BackfillStoreDetails fetches from the store service the details of the
+// stores that have products but are missing from the replica, such as on a
+// cold start of the replica before the stores topic has been read. A store
+// that the store service does not know, or that fails to fetch, is recorded
+// as a miss and left out of the backfills until its retry is due, so that it
+// does not hold up the stores after it.
+func BackfillStoreDetails(ctx context.Context, client pb.StoreServiceClient, storeRepository repository.StoreRepository, geoRepository repository.GeoRepository) error {
+	now := time.Now()
+	ids, err := storeRepository.GetUnreplicatedStoreIDs(now, storeBackfillBatch)
+	if err != nil {
+		return err
+	}
+
+	retryAt := func(attempts int) time.Time {
+		return now.Add(storeBackfillBackoff(attempts))
+	}
+	for _, id := range ids {
+		store, err := client.GetStore(ctx, &pb.StoreId{Id: id})
+		if err != nil {
+			if status.Code(err) != codes.NotFound {
+				log.Printf("failed to backfill store %s: %v", id, err)
+			}
+			if err := storeRepository.RecordBackfillMiss(id, err, retryAt); err != nil {
+				log.Printf("failed to record the backfill miss of store %s: %v", id, err)
+			}
+			continue
+		}
+
+		event := StoreEvent{
+			ID:          id,
+			Name:        store.GetName(),
+			Image:       store.GetImage(),
+			Address:     store.GetAddress(),
+			Category:    store.GetCategory(),
+			SubCategory: store.GetSubCategory(),
+			Description: store.GetDescription(),
+			Rating:      store.GetRating(),
+			ReviewCount: int(store.GetReviewCount()),
+			Pincode:     store.GetPincode(),
+		}
+		if location := store.GetLocation(); location != nil {
+			latitude, longitude := location.GetLatitude(), location.GetLongitude()
+			event.Latitude, event.Longitude = &latitude, &longitude
+		}
+		if err := applyStore(storeRepository, geoRepository, event); err != nil {
+			log.Printf("failed to backfill store %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// storeBackfillBackoff is the time to wait before the next attempt to
+// backfill a store that failed the given number of times.
+func storeBackfillBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		attempts = 8
+	}
+	return 10 * time.Minute << (attempts - 1)
+}
+
+// RunStoreBackfill backfills the replica of store details now and then every
+// interval. It never returns.
+func RunStoreBackfill(client pb.StoreServiceClient, storeRepository repository.StoreRepository, geoRepository repository.GeoRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := BackfillStoreDetails(context.Background(), client, storeRepository, geoRepository); err != nil {
+			log.Printf("failed to backfill store details: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// ApplyStoreEvent applies a message of the stores topic to the local replica.
+func ApplyStoreEvent(storeRepository repository.StoreRepository, geoRepository repository.GeoRepository, key, value []byte) error {
+	if len(value) == 0 {
+		if len(key) == 0 {
+			return fmt.Errorf("%w: tombstone without a store ID", ErrInvalidStoreEvent)
+		}
+		return deleteStore(storeRepository, geoRepository, string(key))
+	}
+
+	var event StoreEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStoreEvent, err)
+	}
+	if event.ID == "" {
+		event.ID = string(key)
+	}
+	if event.ID == "" {
+		return fmt.Errorf("%w: no store ID", ErrInvalidStoreEvent)
+	}
+	if event.Deleted {
+		return deleteStore(storeRepository, geoRepository, event.ID)
+	}
+	return applyStore(storeRepository, geoRepository, event)
+}
+
+// applyStore creates or replaces the details of a store in the replica, and
+// its location when it has coordinates.
+func applyStore(storeRepository repository.StoreRepository, geoRepository repository.GeoRepository, event StoreEvent) error {
+	now := time.Now()
+	err := storeRepository.UpsertStore(&models.StoreDetails{
+		ID:          event.ID,
+		Name:        event.Name,
+		Image:       event.Image,
+		Address:     event.Address,
+		Category:    event.Category,
+		SubCategory: event.SubCategory,
+		Description: event.Description,
+		Rating:      event.Rating,
+		ReviewCount: event.ReviewCount,
+		Pincode:     event.Pincode,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if event.Latitude == nil || event.Longitude == nil {
+		return nil
+	}
+	if err := validateCoordinates(*event.Latitude, *event.Longitude); err != nil {
+		return fmt.Errorf("%w: store %s: %v", ErrInvalidStoreEvent, event.ID, err)
+	}
+	return geoRepository.UpsertStoreLocation(&models.StoreLocation{
+		StoreID:   event.ID,
+		Latitude:  *event.Latitude,
+		Longitude: *event.Longitude,
+		UpdatedAt: now,
+	})
+}
+
+func deleteStore(storeRepository repository.StoreRepository, geoRepository repository.GeoRepository, id string) error {
+	if err := storeRepository.DeleteStore(id); err != nil {
+		return err
+	}
+	return geoRepository.DeleteStoreLocation(id)
+}
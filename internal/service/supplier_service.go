@@ -0,0 +1,70 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// SupplierService defines the interface for the supplier service.
+type SupplierService interface {
+	CreateSupplier(ctx *gin.Context, supplier *models.Supplier) (*models.Supplier, error)
+	GetSupplierByID(ctx *gin.Context, id string) (*models.Supplier, error)
+	GetSuppliersByStoreID(ctx *gin.Context, storeID string) ([]models.Supplier, error)
+	UpdateSupplier(ctx *gin.Context, supplier *models.Supplier) (*models.Supplier, error)
+	DeleteSupplier(ctx *gin.Context, id string) error
+}
+
+type supplierService struct {
+	repo repository.SupplierRepository
+}
+
+// NewSupplierService creates a new instance of SupplierService.
+func NewSupplierService(repo repository.SupplierRepository) SupplierService {
+	return &supplierService{repo: repo}
+}
+
+// CreateSupplier creates a new supplier for a store.
+func (s *supplierService) CreateSupplier(ctx *gin.Context, supplier *models.Supplier) (*models.Supplier, error) {
+	if supplier.StoreID == "" || supplier.Name == "" {
+		return nil, validationf("store_id and name are required")
+	}
+
+	if err := s.repo.Create(supplier); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+// GetSupplierByID retrieves a supplier by its ID.
+func (s *supplierService) GetSupplierByID(ctx *gin.Context, id string) (*models.Supplier, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetSuppliersByStoreID retrieves all suppliers of a store.
+func (s *supplierService) GetSuppliersByStoreID(ctx *gin.Context, storeID string) ([]models.Supplier, error) {
+	return s.repo.GetAllByStoreID(storeID)
+}
+
+// UpdateSupplier updates an existing supplier. The store of a supplier cannot be changed.
+func (s *supplierService) UpdateSupplier(ctx *gin.Context, supplier *models.Supplier) (*models.Supplier, error) {
+	existing, err := s.repo.GetByID(supplier.ID)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.Name == "" {
+		return nil, fieldErrorf("name", "name is required")
+	}
+
+	supplier.StoreID = existing.StoreID
+	supplier.CreatedAt = existing.CreatedAt
+	if err := s.repo.Update(supplier); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+// DeleteSupplier deletes a supplier by its ID.
+func (s *supplierService) DeleteSupplier(ctx *gin.Context, id string) error {
+	return s.repo.Delete(id)
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+)
+
+// taxRates maps a tax class to its GST rate in basis points.
+var taxRates = map[string]int{
+	models.TaxClassExempt: 0,
+	models.TaxClassGST0:   0,
+	models.TaxClassGST3:   300,
+	models.TaxClassGST5:   500,
+	models.TaxClassGST12:  1200,
+	models.TaxClassGST18:  1800,
+	models.TaxClassGST28:  2800,
+}
+
+// TaxRequest identifies the product, quantity and destination a tax breakdown is computed for.
+type TaxRequest struct {
+	ProductID        string
+	VariantType      string
+	VariantID        int
+	Quantity         models.Quantity
+	OriginState      string
+	DestinationState string
+}
+
+// TaxBreakdown is the GST due on a quantity of a product shipped to a state.
+// Amounts are in the currency of the product price, rounded to minor units.
+type TaxBreakdown struct {
+	ProductID        string          `json:"product_id"`
+	VariantType      string          `json:"variant_type,omitempty"`
+	VariantID        int             `json:"variant_id,omitempty"`
+	HSNCode          string          `json:"hsn_code"`
+	TaxClass         string          `json:"tax_class"`
+	RatePercent      float64         `json:"rate_percent"`
+	TaxInclusive     bool            `json:"tax_inclusive"`
+	OriginState      string          `json:"origin_state"`
+	DestinationState string          `json:"destination_state"`
+	InterState       bool            `json:"inter_state"`
+	Quantity         models.Quantity `json:"quantity"`
+	QuantityUnit     models.Unit     `json:"quantity_unit"`
+	UnitPrice        models.Money    `json:"unit_price"`
+	TaxableValue     models.Money    `json:"taxable_value"`
+	CGST             models.Money    `json:"cgst"`
+	SGST             models.Money    `json:"sgst"`
+	IGST             models.Money    `json:"igst"`
+	TotalTax         models.Money    `json:"total_tax"`
+	TotalAmount      models.Money    `json:"total_amount"`
+}
+
+// TaxCalculator computes tax breakdowns. It is shared by the HTTP and gRPC APIs.
+type TaxCalculator interface {
+	Calculate(req TaxRequest) (*TaxBreakdown, error)
+}
+
+type taxCalculator struct {
+	repo              repository.TaxRepository
+	productRepository repository.ProductRepository
+	priceResolver     PriceResolver
+}
+
+// NewTaxCalculator creates a new instance of TaxCalculator.
+func NewTaxCalculator(repo repository.TaxRepository, productRepository repository.ProductRepository, priceResolver PriceResolver) TaxCalculator {
+	return &taxCalculator{repo: repo, productRepository: productRepository, priceResolver: priceResolver}
+}
+
+// Calculate computes the GST on the effective price of a product. The HSN
+// code, tax class and inclusiveness come from the product, falling back to
+// its category default. Supplies within the store's state are split into
+// CGST and SGST, supplies to another state carry IGST.
+func (c *taxCalculator) Calculate(req TaxRequest) (*TaxBreakdown, error) {
+	if req.DestinationState == "" {
+		return nil, invalidf("destination state is required")
+	}
+	if req.Quantity <= 0 {
+		req.Quantity = models.NewQuantity(1)
+	}
+
+	product, err := c.productRepository.GetProductByID(req.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	originState := req.OriginState
+	if originState == "" {
+		profile, err := c.repo.GetStoreProfile(product.StoreID)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			return nil, validationf("store %s has no tax profile, origin state is unknown", product.StoreID)
+		}
+		originState = profile.StateCode
+	}
+
+	hsnCode, taxClass, inclusive := product.HSNCode, product.TaxClass, product.TaxInclusive
+	if hsnCode == "" || taxClass == "" || inclusive == nil {
+		taxDefault, err := c.repo.GetCategoryDefault(product.StoreID, product.Category)
+		if err != nil {
+			return nil, err
+		}
+		if taxDefault != nil {
+			if hsnCode == "" {
+				hsnCode = taxDefault.HSNCode
+			}
+			if taxClass == "" {
+				taxClass = taxDefault.TaxClass
+			}
+			if inclusive == nil {
+				inclusive = taxDefault.TaxInclusive
+			}
+		}
+	}
+	if taxClass == "" {
+		return nil, validationf("product %s has no tax class and its category has no default", product.ID)
+	}
+	rate, ok := taxRates[taxClass]
+	if !ok {
+		return nil, validationf("unknown tax class %s", taxClass)
+	}
+	taxInclusive := inclusive == nil || *inclusive
+
+	price, err := ResolveProductPrice(c.priceResolver, product, req.VariantType, req.VariantID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &TaxBreakdown{
+		ProductID:        product.ID,
+		VariantType:      price.VariantType,
+		VariantID:        price.VariantID,
+		HSNCode:          hsnCode,
+		TaxClass:         taxClass,
+		RatePercent:      float64(rate) / 100,
+		TaxInclusive:     taxInclusive,
+		OriginState:      strings.ToUpper(originState),
+		DestinationState: strings.ToUpper(req.DestinationState),
+		Quantity:         req.Quantity,
+		QuantityUnit:     product.QuantityUnit,
+		UnitPrice:        price.EffectivePrice,
+	}
+	breakdown.InterState = breakdown.OriginState != breakdown.DestinationState
+
+	gross := price.EffectivePrice.Times(req.Quantity)
+	currency := gross.Currency
+	var taxable, tax int64
+	if taxInclusive {
+		taxable = roundDiv(gross.Amount*10000, int64(10000+rate))
+		tax = gross.Amount - taxable
+	} else {
+		taxable = gross.Amount
+		tax = roundDiv(gross.Amount*int64(rate), 10000)
+	}
+
+	breakdown.TaxableValue = models.NewMoney(taxable, currency)
+	breakdown.TotalTax = models.NewMoney(tax, currency)
+	breakdown.TotalAmount = models.NewMoney(taxable+tax, currency)
+	breakdown.CGST = models.NewMoney(0, currency)
+	breakdown.SGST = models.NewMoney(0, currency)
+	breakdown.IGST = models.NewMoney(0, currency)
+	if breakdown.InterState {
+		breakdown.IGST.Amount = tax
+	} else {
+		cgst := roundDiv(tax, 2)
+		breakdown.CGST.Amount = cgst
+		breakdown.SGST.Amount = tax - cgst
+	}
+
+	return breakdown, nil
+}
+
+func roundDiv(numerator int64, denominator int64) int64 {
+	return int64(math.Round(float64(numerator) / float64(denominator)))
+}
+
+// TaxService defines the interface for the tax configuration service.
+type TaxService interface {
+	GetProductTax(ctx *gin.Context, req TaxRequest) (*TaxBreakdown, error)
+	UpsertCategoryTaxDefault(ctx *gin.Context, taxDefault *models.CategoryTaxDefault) (*models.CategoryTaxDefault, error)
+	GetCategoryTaxDefaults(ctx *gin.Context, storeID string) ([]models.CategoryTaxDefault, error)
+	DeleteCategoryTaxDefault(ctx *gin.Context, id int) error
+	UpsertStoreTaxProfile(ctx *gin.Context, profile *models.StoreTaxProfile) (*models.StoreTaxProfile, error)
+	GetStoreTaxProfile(ctx *gin.Context, storeID string) (*models.StoreTaxProfile, error)
+}
+
+type taxService struct {
+	repo       repository.TaxRepository
+	calculator TaxCalculator
+}
+
+// NewTaxService creates a new instance of TaxService.
+func NewTaxService(repo repository.TaxRepository, calculator TaxCalculator) TaxService {
+	return &taxService{repo: repo, calculator: calculator}
+}
+
+// GetProductTax computes the tax breakdown of a product.
+func (s *taxService) GetProductTax(ctx *gin.Context, req TaxRequest) (*TaxBreakdown, error) {
+	return s.calculator.Calculate(req)
+}
+
+// UpsertCategoryTaxDefault validates and stores the tax default of a category.
+func (s *taxService) UpsertCategoryTaxDefault(ctx *gin.Context, taxDefault *models.CategoryTaxDefault) (*models.CategoryTaxDefault, error) {
+	if strings.TrimSpace(taxDefault.Category) == "" {
+		return nil, fieldErrorf("category", "category is required")
+	}
+	if err := ValidateTaxClass(taxDefault.TaxClass); err != nil {
+		return nil, err
+	}
+
+	taxDefault.UpdatedAt = time.Now()
+	if err := s.repo.UpsertCategoryDefault(taxDefault); err != nil {
+		return nil, err
+	}
+	return taxDefault, nil
+}
+
+// GetCategoryTaxDefaults retrieves the category tax defaults visible to a store.
+func (s *taxService) GetCategoryTaxDefaults(ctx *gin.Context, storeID string) ([]models.CategoryTaxDefault, error) {
+	return s.repo.GetCategoryDefaults(storeID)
+}
+
+// DeleteCategoryTaxDefault deletes a category tax default by its ID.
+func (s *taxService) DeleteCategoryTaxDefault(ctx *gin.Context, id int) error {
+	return s.repo.DeleteCategoryDefault(id)
+}
+
+// UpsertStoreTaxProfile stores the GST registration of a store.
+func (s *taxService) UpsertStoreTaxProfile(ctx *gin.Context, profile *models.StoreTaxProfile) (*models.StoreTaxProfile, error) {
+	if profile.StoreID == "" || profile.StateCode == "" {
+		return nil, validationf("store_id and state_code are required")
+	}
+
+	profile.StateCode = strings.ToUpper(strings.TrimSpace(profile.StateCode))
+	profile.UpdatedAt = time.Now()
+	if err := s.repo.UpsertStoreProfile(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetStoreTaxProfile retrieves the GST registration of a store.
+func (s *taxService) GetStoreTaxProfile(ctx *gin.Context, storeID string) (*models.StoreTaxProfile, error) {
+	profile, err := s.repo.GetStoreProfile(storeID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, notFoundf("store %s has no tax profile", storeID)
+	}
+	return profile, nil
+}
+
+// ValidateTaxClass accepts an empty tax class, which defers to the category
+// default, or one of the known GST slabs.
+func ValidateTaxClass(taxClass string) error {
+	if taxClass == "" {
+		return nil
+	}
+	if _, ok := taxRates[taxClass]; !ok {
+		return validationf("unknown tax class %s", taxClass)
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"mime/multipart"
 	"strconv"
 	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
 )
 
 func StringToInt(s string) int {
@@ -16,6 +18,37 @@ func StringToInt(s string) int {
 	return i
 }
 
+// StringToBoolPointer returns nil for an empty or unparsable string, so that
+// an omitted form field can be told apart from "false".
+func StringToBoolPointer(s string) *bool {
+	b, err := strconv.ParseBool(s)
+
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// StringToMoney parses an amount in major units, such as "12.50", returning
+// zero for an empty or unparsable string.
+func StringToMoney(s string, currency string) models.Money {
+	m, err := models.ParseMoney(s, currency)
+
+	if err != nil {
+		return models.NewMoney(0, currency)
+	}
+	return m
+}
+
+func StringToQuantity(s string) models.Quantity {
+	q, err := models.ParseQuantity(s)
+
+	if err != nil {
+		return 0
+	}
+	return q
+}
+
 func StringToTime(s string) time.Time {
 	t, err := time.Parse(time.DateTime, s)
 
@@ -25,6 +58,17 @@ func StringToTime(s string) time.Time {
 	return t
 }
 
+// StringToTimePointer parses an RFC 3339 or "2006-01-02 15:04:05" time,
+// returning nil for an empty or unparsable string.
+func StringToTimePointer(s string) *time.Time {
+	for _, layout := range []string{time.RFC3339, time.DateTime} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
 func FileHeaderToBytes(fileHeader *multipart.FileHeader) ([]byte, error) {
 	// Open the file from the multipart form
 	file, err := fileHeader.Open()
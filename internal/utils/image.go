@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"mime/multipart"
+	"net/http"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxImageBytes is the largest image file accepted for upload.
+	MaxImageBytes = 10 << 20
+	// MaxImagePixels bounds the decoded size of an image, so that a small
+	// file can't expand into a huge bitmap.
+	MaxImagePixels = 40_000_000
+
+	// Longest side, in pixels, of each rendition of an image.
+	FullImageSize      = 2048
+	MediumImageSize    = 800
+	ThumbnailImageSize = 200
+
+	imageJPEGQuality = 85
+)
+
+// imageTypes are the sniffed content types accepted for product images.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// ImageRenditions are the JPEG renditions of a normalized image. Width and
+// Height are the dimensions of Full.
+type ImageRenditions struct {
+	Full      []byte
+	Medium    []byte
+	Thumbnail []byte
+	Width     int
+	Height    int
+}
+
+// ValidateImageFile checks the size of an uploaded image file before it is read.
+func ValidateImageFile(fileHeader *multipart.FileHeader) error {
+	if fileHeader.Size > MaxImageBytes {
+		return fmt.Errorf("image %s is larger than %d MB", fileHeader.Filename, MaxImageBytes>>20)
+	}
+	return nil
+}
+
+// NormalizeImage validates an image by its content and re-encodes it as JPEG
+// in full, medium and thumbnail sizes. The image is turned upright according
+// to its EXIF orientation, and the EXIF data itself is dropped. Transparent
+// areas become white.
+//
+// WebP uploads are accepted, but no WebP renditions are made: there is no
+// WebP encoder in Go without cgo and libwebp, and JPEG renditions display
+// everywhere. WebP is deliberately left out rather than pending.
+func NormalizeImage(data []byte) (*ImageRenditions, error) {
+	if len(data) > MaxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d MB", MaxImageBytes>>20)
+	}
+	if isHEIF(data) {
+		return nil, errors.New("HEIC images are not supported, upload a JPEG, PNG or WebP image")
+	}
+	contentType := http.DetectContentType(data)
+	if !imageTypes[contentType] {
+		return nil, fmt.Errorf("unsupported image type %s", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	// scaled down first, so that flattening and orienting never run on the
+	// original resolution
+	full := orient(flatten(fitImage(decoded, FullImageSize)), orientation)
+
+	renditions := &ImageRenditions{Width: full.Bounds().Dx(), Height: full.Bounds().Dy()}
+	if renditions.Full, err = encodeJPEG(full); err != nil {
+		return nil, err
+	}
+	if renditions.Medium, err = encodeJPEG(fitImage(full, MediumImageSize)); err != nil {
+		return nil, err
+	}
+	if renditions.Thumbnail, err = encodeJPEG(fitImage(full, ThumbnailImageSize)); err != nil {
+		return nil, err
+	}
+	return renditions, nil
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isHEIF tells whether data is a HEIC or HEIF file, which can't be decoded here.
+func isHEIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	switch string(data[8:12]) {
+	case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+		return true
+	}
+	return false
+}
+
+// flatten draws an image onto a white background.
+func flatten(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// fitImage scales an image down to fit within size pixels on its longest
+// side. Smaller images are returned as they are.
+func fitImage(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+	if width >= height {
+		width, height = size, max(1, height*size/width)
+	} else {
+		width, height = max(1, width*size/height), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+// orient turns an image upright according to its EXIF orientation, from 1
+// (already upright) to 8.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = width-1-x, y
+			case 3: // rotated 180°
+				sx, sy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, height-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90° counterclockwise
+				sx, sy = y, height-1-x
+			case 7: // transversed
+				sx, sy = width-1-y, height-1-x
+			case 8: // rotated 90° clockwise
+				sx, sy = width-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG file, or 1 when it
+// has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// the image data starts, so there is no EXIF segment
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of EXIF TIFF data.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
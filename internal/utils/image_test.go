@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+// halves is a width by height image, red on the left half and blue on the
+// right.
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// jpegWithOrientation encodes img as a JPEG whose EXIF data gives the
+// orientation, in little or big endian TIFF.
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16, order binary.ByteOrder) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II*\x00")
+	} else {
+		tiff.WriteString("MM\x00*")
+	}
+	binary.Write(&tiff, order, uint32(8)) // first IFD
+	binary.Write(&tiff, order, uint16(1)) // entries
+	binary.Write(&tiff, order, uint16(0x0112))
+	binary.Write(&tiff, order, uint16(3)) // SHORT
+	binary.Write(&tiff, order, uint32(1))
+	binary.Write(&tiff, order, orientation)
+	binary.Write(&tiff, order, uint16(0))
+	binary.Write(&tiff, order, uint32(0)) // no next IFD
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	data := append([]byte{}, encoded.Bytes()[:2]...) // SOI
+	data = append(data, app1...)
+	data = append(data, segment...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// pngHeader is a PNG of one pixel whose header claims width by height, which
+// is as far as a file too large to decode is read.
+func pngHeader(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// the IHDR chunk follows the 8 byte signature: length, type, data, CRC
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestValidateImageFile(t *testing.T) {
+	for _, tt := range []struct {
+		size    int64
+		wantErr bool
+	}{
+		{1 << 20, false},
+		{MaxImageBytes, false},
+		{MaxImageBytes + 1, true},
+		{12 << 20, true},
+	} {
+		err := ValidateImageFile(&multipart.FileHeader{Filename: "photo.jpg", Size: tt.size})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("size %d: %v", tt.size, err)
+		}
+	}
+}
+
+func TestNormalizeImageRejects(t *testing.T) {
+	oversized := append(pngHeader(t, 1, 1), make([]byte, MaxImageBytes)...)
+	heif := func(brand string) []byte {
+		return append([]byte("\x00\x00\x00\x18ftyp"+brand+"\x00\x00\x00\x00mif1heic"), make([]byte, 64)...)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"over 10 MB", oversized, "larger than 10 MB"},
+		{"over 40 megapixels", pngHeader(t, 8000, 5001), "8000x5001 pixels is too large"},
+		{"over 40 megapixels on one side", pngHeader(t, 400_000_001, 1), "too large"},
+		{"HEIC", heif("heic"), "HEIC images are not supported"},
+		{"HEIF", heif("mif1"), "HEIC images are not supported"},
+		{"PDF", []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n"), "unsupported image type application/pdf"},
+		{"text", []byte("not an image"), "unsupported image type"},
+		{"truncated PNG", pngHeader(t, 10, 10)[:20], "invalid image"},
+	} {
+		_, err := NormalizeImage(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeImageRenditions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4000, 1000))); err != nil {
+		t.Fatal(err)
+	}
+	renditions, err := NormalizeImage(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renditions.Width != FullImageSize || renditions.Height != FullImageSize/4 {
+		t.Errorf("full rendition of %dx%d", renditions.Width, renditions.Height)
+	}
+	for _, tt := range []struct {
+		name          string
+		data          []byte
+		width, height int
+	}{
+		{"full", renditions.Full, FullImageSize, FullImageSize / 4},
+		{"medium", renditions.Medium, MediumImageSize, MediumImageSize / 4},
+		{"thumbnail", renditions.Thumbnail, ThumbnailImageSize, ThumbnailImageSize / 4},
+	} {
+		config, format, err := image.DecodeConfig(bytes.NewReader(tt.data))
+		if err != nil || format != "jpeg" || config.Width != tt.width || config.Height != tt.height {
+			t.Errorf("%s rendition: %s of %dx%d, %v", tt.name, format, config.Width, config.Height, err)
+		}
+	}
+}
+
+func TestNormalizeImageOrientation(t *testing.T) {
+	red := func(c color.Color) bool {
+		r, g, b, _ := c.RGBA()
+		return r>>8 > 200 && g>>8 < 60 && b>>8 < 60
+	}
+	blue := func(c color.Color) bool {
+		r, g, b, _ := c.RGBA()
+		return r>>8 < 60 && g>>8 < 60 && b>>8 > 200
+	}
+
+	// the stored image is 40x20, red on the left and blue on the right
+	for _, tt := range []struct {
+		orientation   uint16
+		order         binary.ByteOrder
+		width, height int
+		// where the red half ends up
+		redFirst func(img image.Image) bool
+	}{
+		{1, binary.LittleEndian, 40, 20, func(img image.Image) bool { return red(img.At(2, 10)) && blue(img.At(37, 10)) }},
+		{3, binary.BigEndian, 40, 20, func(img image.Image) bool { return blue(img.At(2, 10)) && red(img.At(37, 10)) }},
+		{6, binary.LittleEndian, 20, 40, func(img image.Image) bool { return red(img.At(10, 2)) && blue(img.At(10, 37)) }},
+		{8, binary.BigEndian, 20, 40, func(img image.Image) bool { return blue(img.At(10, 2)) && red(img.At(10, 37)) }},
+	} {
+		data := jpegWithOrientation(t, halves(40, 20), tt.orientation, tt.order)
+		if got := jpegOrientation(data); got != int(tt.orientation) {
+			t.Fatalf("orientation %d read as %d", tt.orientation, got)
+		}
+
+		renditions, err := NormalizeImage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		full, err := jpeg.Decode(bytes.NewReader(renditions.Full))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full.Bounds().Dx() != tt.width || full.Bounds().Dy() != tt.height || !tt.redFirst(full) {
+			t.Errorf("orientation %d: %dx%d, not turned upright", tt.orientation, full.Bounds().Dx(), full.Bounds().Dy())
+		}
+		if bytes.Contains(renditions.Full, []byte("Exif\x00\x00")) || jpegOrientation(renditions.Full) != 1 {
+			t.Errorf("orientation %d: EXIF data kept", tt.orientation)
+		}
+	}
+}
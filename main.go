@@ -3,13 +3,12 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/gin-gonic/gin"
 	"github.com/tanush-128/openzo_backend/product/config"
-	handlers "github.com/tanush-128/openzo_backend/product/internal/api"
 	"github.com/tanush-128/openzo_backend/product/internal/pb"
-	"github.com/tanush-128/openzo_backend/product/internal/repository"
 	"github.com/tanush-128/openzo_backend/product/internal/service"
 	"google.golang.org/grpc"
 )
@@ -69,49 +68,38 @@ func main() {
 	defer imageConn.Close()
 	imageClient := pb.NewImageServiceClient(imageConn)
 
-	productRepository := repository.NewProductRepository(db)
-	productService := service.NewProductService(productRepository, imageClient, p)
-
+	a := newApp(db, imageClient, p)
 	go service.GrpcServer(cfg, &service.Server{
-		ProductRepository: productRepository,
-	})
-
-	// Initialize Inventory Repository and Service
-	inventoryTransactionRepository := repository.NewInventoryTransactionRepository(db)
-	inventoryService := service.NewInventoryService(inventoryTransactionRepository)
-	inventoryHandler := handlers.NewInventoryHandler(&inventoryService)
-
-	// Initialize HTTP server with Gin
-	router := gin.Default()
-	handler := handlers.NewHandler(&productService)
-
-	router.GET("ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"message": "pong",
-		})
+		ProductRepository: a.productRepository,
+		PriceResolver:     a.priceResolver,
+		TaxCalculator:     a.taxCalculator,
 	})
-
-	// router.Use(middlewares.JwtMiddleware(c))
-	router.POST("/", handler.CreateProduct)
-	router.GET("/store/:id", handler.GetProductsByStoreID)
-	router.GET("post/pincode/:pincode", handler.GetPostByPincode)
-	router.GET("/:id", handler.GetProductByID)
-	// router.Use(middlewares.NewMiddleware(c).JwtMiddleware)
-	router.PUT("/:id", handler.ChangeProductQuantity)
-	router.PUT("/", handler.UpdateProduct)
-	router.PUT("/display_order/:id", handler.UpdateDisplayOrder)
-	router.PUT("/display_order/batch", handler.BatchUpdateDisplayOrder)
-	router.DELETE("/:id", handler.DeleteProduct)
-
-	// Inventory routes
-	router.POST("/inventory", inventoryHandler.CreateInventoryTransaction)
-	router.GET("/inventory/:id", inventoryHandler.GetInventoryTransactionByID)
-	router.PUT("/inventory/:id", inventoryHandler.UpdateInventoryTransaction)
-	router.DELETE("/inventory/:id", inventoryHandler.DeleteInventoryTransaction)
-	router.GET("/inventory/product/:product_id", inventoryHandler.GetAllTransactionsByProductID)
+	go service.RunImageUploadRetrier(a.imageService, time.Minute)
+	go service.RunImageSweeper(a.imageService, time.Hour)
+	go service.RunImageImporter(a.imageService, 5*time.Second)
+
+	// store events keep the replica of store details up to date
+	storeConsumerConf := ReadConfig()
+	storeConsumerConf["group.id"] = "product-service-stores"
+	storeConsumerConf["auto.offset.reset"] = "earliest"
+	storeConsumerConf["enable.auto.commit"] = false
+	storeConsumer, err := kafka.NewConsumer(&storeConsumerConf)
+	if err != nil {
+		log.Printf("failed to create store events consumer: %v", err)
+	} else {
+		defer storeConsumer.Close()
+		go service.ConsumeStoreEvents(storeConsumer, a.storeRepository, a.geoRepository)
+	}
+	// stores the topic has not brought yet are fetched from the store service
+	storeConn, err := grpc.Dial(cfg.StoreGrpc, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer storeConn.Close()
+	go service.RunStoreBackfill(pb.NewStoreServiceClient(storeConn), a.storeRepository, a.geoRepository, 10*time.Minute)
 
 	// router.Use(middlewares.JwtMiddleware)
 
-	router.Run(fmt.Sprintf(":%s", cfg.HTTPPort))
+	a.router.Run(fmt.Sprintf(":%s", cfg.HTTPPort))
 
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/gin-gonic/gin"
+	handlers "github.com/tanush-128/openzo_backend/product/internal/api"
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/pb"
+	"google.golang.org/grpc"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// uploadedImages is an image service that hands out a URL per upload.
+type uploadedImages struct {
+	pb.ImageServiceClient
+	mu      sync.Mutex
+	uploads int
+}
+
+func (c *uploadedImages) UploadImage(ctx context.Context, in *pb.ImageMessage, opts ...grpc.CallOption) (*pb.ImageURL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads++
+	return &pb.ImageURL{Url: fmt.Sprintf("https://images.example.com/%d.jpg", c.uploads)}, nil
+}
+
+func (c *uploadedImages) DeleteImage(ctx context.Context, in *pb.DeleteImageRequest, opts ...grpc.CallOption) (*pb.DeleteImageResponse, error) {
+	return &pb.DeleteImageResponse{}, nil
+}
+
+// document is a decoded OpenAPI document.
+type document map[string]interface{}
+
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// resolve follows the references of schema to the schema they name.
+func (d document) resolve(schema map[string]interface{}) map[string]interface{} {
+	for schema["$ref"] != nil {
+		name := strings.TrimPrefix(schema["$ref"].(string), "#/components/schemas/")
+		schema = object(object(object(d["components"])["schemas"])[name])
+	}
+	return schema
+}
+
+// validate lists where v, found at at, breaks schema.
+func (d document) validate(schema map[string]interface{}, v interface{}, at string) []string {
+	schema = d.resolve(schema)
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": is null"}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		for _, sub := range oneOf {
+			if len(d.validate(object(sub), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches none of oneOf"}
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		var errs []string
+		for _, sub := range allOf {
+			errs = append(errs, d.validate(object(sub), v, at)...)
+		}
+		return errs
+	}
+	switch schema["type"] {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: is %T, not an object", at, v)}
+		}
+		var errs []string
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := m[name.(string)]; !ok {
+				errs = append(errs, at+"."+name.(string)+": is missing")
+			}
+		}
+		for name, value := range m {
+			if additional := object(schema["additionalProperties"]); additional != nil {
+				errs = append(errs, d.validate(additional, value, at+"."+name)...)
+				continue
+			}
+			property := object(object(schema["properties"])[name])
+			if property == nil {
+				errs = append(errs, at+"."+name+": is undocumented")
+				continue
+			}
+			errs = append(errs, d.validate(property, value, at+"."+name)...)
+		}
+		return errs
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: is %T, not an array", at, v)}
+		}
+		var errs []string
+		for i, item := range a {
+			errs = append(errs, d.validate(object(schema["items"]), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+		return errs
+	case "string":
+		if _, ok := v.(string); !ok {
+			return []string{fmt.Sprintf("%s: %v is not a string", at, v)}
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			return []string{fmt.Sprintf("%s: %v is not an integer", at, v)}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{fmt.Sprintf("%s: %v is not a number", at, v)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: %v is not a boolean", at, v)}
+		}
+	}
+	return nil
+}
+
+// testApp serves the routes of the service from the in-memory database
+// name, which holds the store s1.
+func testApp(t *testing.T, name string) (*gorm.DB, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.ReleaseMode)
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(`INSERT INTO store_details (id, name, image, address, category, sub_category, description, rating, review_count, pincode)
+		VALUES ('s1', 'Corner Store', '', '', '', '', '', 4, 0, '560001')`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(producer.Close)
+	return db, newApp(db, &uploadedImages{}, producer).router
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRoutesMatchOpenAPI calls the routes of the service and checks their
+// responses against the document served at /openapi.json.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	_, router := testApp(t, "app")
+
+	for _, route := range handlers.UndocumentedRoutes(router.Routes()) {
+		t.Errorf("%s has no OpenAPI operation", route)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	paths := object(doc["paths"])
+
+	for _, path := range []string{"/docs", "/docs/swagger-ui.css", "/docs/swagger-ui-bundle.js"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || w.Body.Len() == 0 {
+			t.Errorf("GET %s: %d", path, w.Code)
+		}
+	}
+
+	// operationFor finds the operation documenting the route that serves
+	// method and path.
+	operationFor := func(method, path string) map[string]interface{} {
+		path = strings.Split(path, "?")[0]
+		for _, route := range router.Routes() {
+			pattern := regexp.MustCompile("^" + pathParam.ReplaceAllString(route.Path, `[^/]+`) + "$")
+			if route.Method == method && pattern.MatchString(path) {
+				template := pathParam.ReplaceAllString(route.Path, "{$1}")
+				return object(object(paths[template])[strings.ToLower(method)])
+			}
+		}
+		return nil
+	}
+	call := func(method, path, contentType string, body []byte) map[string]interface{} {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(w, req)
+
+		operation := operationFor(method, path)
+		if operation == nil {
+			t.Errorf("%s %s: no operation", method, path)
+			return nil
+		}
+		responses := object(operation["responses"])
+		response := object(responses[fmt.Sprint(w.Code)])
+		if response == nil {
+			response = object(responses["default"])
+		}
+		if response == nil {
+			t.Errorf("%s %s: status %d is undocumented", method, path, w.Code)
+			return nil
+		}
+		var v interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Errorf("%s %s: %v: %s", method, path, err, w.Body.String())
+			return nil
+		}
+		schema := object(object(object(response["content"])["application/json"])["schema"])
+		for _, e := range doc.validate(schema, v, fmt.Sprintf("%s %s %d", method, path, w.Code)) {
+			t.Error(e)
+		}
+		return object(v)
+	}
+
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 8, 6))); err != nil {
+		t.Fatal(err)
+	}
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fields := map[string]string{"name": "Rice", "mrp": "50", "quantity": "3", "type": "post", "tax_class": "GST_5",
+		"size_variants": `[{"size":"1kg","mrp":{"amount":5000,"currency":"INR"}}]`}
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	file, _ := mw.CreateFormFile("images", "rice.png")
+	file.Write(picture.Bytes())
+	mw.Close()
+	product := call("POST", "/v1/stores/s1/products", mw.FormDataContentType(), form.Bytes())
+	id, _ := product["id"].(string)
+	if id == "" {
+		t.Fatalf("created %v", product)
+	}
+
+	call("GET", "/v1/products/"+id, "", nil)
+	call("GET", "/"+id, "", nil)
+	call("GET", "/v1/stores/s1/products", "", nil)
+	call("GET", "/v1/stores/s1/products?sort=price&in_stock=true", "", nil)
+	call("GET", "/v1/pincodes/560001/feed", "", nil)
+	call("GET", "/v1/pincodes/560001/posts", "", nil)
+	call("GET", "/v1/units", "", nil)
+	call("GET", "/v1/products/"+id+"/images", "", nil)
+	call("POST", "/v1/inventory/transactions", "application/json",
+		[]byte(`{"product_id":"`+id+`","quantity":2,"price":{"amount":100,"currency":"INR"},"transaction_type":"PURCHASE"}`))
+	call("GET", "/v1/inventory/products/"+id+"/transactions", "", nil)
+	call("GET", "/v1/products/"+id+"/price", "", nil)
+	call("PUT", "/v1/products/"+id+"/quantity?quantity=4", "", nil)
+	call("POST", "/v1/suppliers", "application/json", []byte(`{"store_id":"s1","name":"Wholesale"}`))
+	call("POST", "/v1/categories", "application/json", []byte(`{"store_id":"s1","name":"Grains"}`))
+	call("GET", "/v1/stores/s1/categories", "", nil)
+	call("GET", "/v1/stores/s1/menu", "", nil)
+	call("PUT", "/v1/stores/s1/location", "application/json", []byte(`{"latitude":12.9,"longitude":77.6}`))
+	call("GET", "/v1/geo/products?lat=12.9&lng=77.6", "", nil)
+	call("PUT", "/v1/stores/s1/tax/profile", "application/json", []byte(`{"state_code":"KA"}`))
+	call("GET", "/v1/products/"+id+"/tax?destination_state=KA", "", nil)
+	call("GET", "/v1/stores/s1/replenishment/suggestions?all=true", "", nil)
+	call("PUT", "/display_order/batch", "application/json", []byte(`{"updates":[{"product_id":"`+id+`","display_order":2}]}`))
+
+	// errors
+	call("GET", "/v1/products/missing", "", nil)
+	call("GET", "/missing", "", nil)
+	call("POST", "/v1/suppliers", "application/json", []byte(`{"store_id":5}`))
+	call("PUT", "/v1/products/"+id+"/quantity?quantity=4&version=99", "", nil)
+	call("PUT", "/display_order/"+id+"?display_order=1&version=99", "", nil)
+	call("DELETE", "/v1/products/"+id, "", nil)
+}
+
+// TestStaleVersionIsRefused checks that writes based on a version of a
+// product that is no longer current are refused with the current product
+// and leave it as it was, and that writes based on the current version pass.
+func TestStaleVersionIsRefused(t *testing.T) {
+	db, router := testApp(t, "stale")
+	product := models.Product{ID: "p1", StoreID: "s1", Name: "Rice", ProductPrivate: models.ProductPrivate{Quantity: models.NewQuantity(3)}}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, path string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	stored := func() models.Product {
+		t.Helper()
+		var p models.Product
+		if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	read := serve("GET", "/v1/products/p1", nil)
+	etag := read.Header().Get("ETag")
+	if read.Code != 200 || !strings.HasSuffix(etag, `.1"`) {
+		t.Fatalf("read: %d, ETag %s", read.Code, etag)
+	}
+
+	for _, stale := range []struct {
+		method, path string
+		header       map[string]string
+	}{
+		{"PUT", "/v1/products/p1/quantity?quantity=5&version=2", nil},
+		{"PUT", "/v1/products/p1/quantity?quantity=5", map[string]string{"If-Match": `"2"`}},
+		{"PUT", "/v1/products/p1/display_order?display_order=4&version=2", nil},
+		{"DELETE", "/v1/products/p1?version=2", nil},
+		{"PUT", "/p1?quantity=5&version=2", nil},
+	} {
+		w := serve(stale.method, stale.path, stale.header)
+		// v1 routes carry the current product in the error envelope
+		var body struct {
+			Error   json.RawMessage `json:"error"`
+			Current models.Product  `json:"current"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if strings.HasPrefix(stale.path, "/v1/") {
+			json.Unmarshal(body.Error, &body)
+		}
+		if w.Code != 409 || body.Current.ID != "p1" || body.Current.Version != 1 {
+			t.Errorf("%s %s %v: %d %s", stale.method, stale.path, stale.header, w.Code, w.Body.String())
+		}
+		if p := stored(); p.Version != 1 || p.Quantity != models.NewQuantity(3) || p.DisplayOrder != 0 {
+			t.Errorf("%s %s %v changed the product: version %d, quantity %v, display order %d",
+				stale.method, stale.path, stale.header, p.Version, p.Quantity, p.DisplayOrder)
+		}
+	}
+
+	if w := serve("PUT", "/v1/products/p1/quantity?quantity=5", map[string]string{"If-Match": etag}); w.Code != 200 {
+		t.Fatalf("write given the ETag of the read: %d %s", w.Code, w.Body.String())
+	}
+	if p := stored(); p.Version != 2 || p.Quantity != models.NewQuantity(5) {
+		t.Fatalf("after the write: version %d, quantity %v", p.Version, p.Quantity)
+	}
+
+	// the ETag read before that write is now stale
+	if w := serve("PUT", "/v1/products/p1/quantity?quantity=6", map[string]string{"If-Match": etag}); w.Code != 409 {
+		t.Errorf("write given a stale ETag: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("PUT", "/v1/products/p1/display_order?display_order=4&version=2", nil); w.Code != 200 {
+		t.Errorf("write given the current version: %d %s", w.Code, w.Body.String())
+	}
+	if p := stored(); p.Version != 3 || p.Quantity != models.NewQuantity(5) || p.DisplayOrder != 4 {
+		t.Errorf("finally: version %d, quantity %v, display order %d", p.Version, p.Quantity, p.DisplayOrder)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"github.com/tanush-128/openzo_backend/product/internal/repository"
+	"gorm.io/gorm"
+)
+
+// migration is a data migration that AutoMigrate can't express. Each one runs
+// once, after AutoMigrate, and is recorded in the schema_migrations table.
+type migration struct {
+	ID  string
+	Run func(tx *gorm.DB) error
+}
+
+var migrations = []migration{
+	{ID: "030_money_and_quantity", Run: migrateMoneyAndQuantity},
+	{ID: "031_categories", Run: migrateCategories},
+	{ID: "040_store_details", Run: migrateStoreDetails},
+	{ID: "027_variant_stock", Run: migrateVariantStock},
+}
+
+func runMigrations(db *gorm.DB) error {
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&models.SchemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+		log.Printf("Applied migration %s", m.ID)
+	}
+	return nil
+}
+
+// migrateMoneyAndQuantity moves whole rupee prices into the <column>_amount
+// and <column>_currency columns of Money, in paise, scales quantities to
+// thousandths and maps free-text quantity units to the unit catalog.
+func migrateMoneyAndQuantity(tx *gorm.DB) error {
+	moneyColumns := []struct {
+		model   interface{}
+		table   string
+		columns []string
+	}{
+		{&models.Product{}, "products", []string{"mrp", "discount_price", "msrp"}},
+		{&models.SizeVariant{}, "size_variants", []string{"price"}},
+		{&models.ColorVariant{}, "color_variants", []string{"price"}},
+		{&models.InventoryTransaction{}, "inventory_transactions", []string{"price"}},
+		{&models.PurchaseOrderLine{}, "purchase_order_lines", []string{"cost"}},
+		{&models.PriceHistory{}, "price_histories", []string{"mrp", "discount_price", "price"}},
+	}
+	for _, money := range moneyColumns {
+		for _, column := range money.columns {
+			if !tx.Migrator().HasColumn(money.model, column) {
+				continue
+			}
+			err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s_amount = %s * 100, %s_currency = ?", money.table, column, column, column),
+				models.DefaultCurrency).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(money.model, column); err != nil {
+				return err
+			}
+		}
+	}
+
+	quantityColumns := map[string][]string{
+		"products":               {"quantity", "critical_quantity"},
+		"size_variants":          {"quantity"},
+		"color_variants":         {"quantity"},
+		"inventory_transactions": {"quantity"},
+		"purchase_order_lines":   {"quantity", "received_quantity"},
+		"replenishment_settings": {"min_order_quantity"},
+	}
+	for table, columns := range quantityColumns {
+		for _, column := range columns {
+			err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = %s * ?", table, column, column), models.QuantityScale).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// fixed discounts were whole rupees and are now paise
+	err := tx.Model(&models.PriceRule{}).Where("discount_type = ?", models.DiscountTypeFixed).
+		Update("value", gorm.Expr("value * 100")).Error
+	if err != nil {
+		return err
+	}
+
+	var units []string
+	if err := tx.Model(&models.Product{}).Distinct().Pluck("quantity_unit", &units).Error; err != nil {
+		return err
+	}
+	for _, unit := range units {
+		parsed, err := models.ParseUnit(unit)
+		if err != nil {
+			log.Printf("Unknown quantity unit %q, products using it are migrated to %s", unit, models.UnitPiece)
+			parsed = models.UnitPiece
+		}
+		if string(parsed) == unit {
+			continue
+		}
+		err = tx.Model(&models.Product{}).Where("quantity_unit = ?", unit).Update("quantity_unit", parsed).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateCategories turns the free-text categories of products into custom
+// categories of their stores, one per distinct normalized name, and links the
+// products to them. The first spelling found becomes the category name.
+func migrateCategories(tx *gorm.DB) error {
+	var products []models.Product
+	err := tx.Select("id", "store_id", "category").
+		Where("category <> '' AND (category_id IS NULL OR category_id = '')").
+		Order("created_at ASC").
+		Find(&products).Error
+	if err != nil {
+		return err
+	}
+
+	categories := repository.NewCategoryRepository(tx)
+	created := map[string]*models.Category{}
+	for _, product := range products {
+		name := strings.TrimSpace(product.Category)
+		if name == "" {
+			continue
+		}
+
+		key := product.StoreID + "/" + repository.NormalizeCategory(name)
+		category, ok := created[key]
+		if !ok {
+			category = &models.Category{StoreID: product.StoreID, Name: name}
+			if err := categories.Create(category); err != nil {
+				return err
+			}
+			created[key] = category
+		}
+
+		err := tx.Model(&models.Product{}).Where("id = ?", product.ID).
+			Updates(map[string]interface{}{"category_id": category.ID, "category": category.Name}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateStoreDetails seeds the local store details replica from the stores
+// table of the store service, when both share the database. Deployments with
+// separate databases are seeded by the store events and by the backfill from
+// the store service instead.
+func migrateStoreDetails(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("stores") {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO store_details (id, name, image, address, category, sub_category, description, rating, review_count, pincode, updated_at)
+		SELECT id, name, image, address, category, sub_category, description, COALESCE(rating, 0), COALESCE(review_count, 0), pincode, CURRENT_TIMESTAMP
+		FROM stores WHERE id NOT IN (SELECT id FROM store_details)`).Error
+}
+
+// migrateVariantStock records the stock held on size and color variants as
+// an opening INVENTORY_ADJUSTMENT, as the stock of variants is summed from
+// their inventory transactions like the stock of products. Variants that
+// already have transactions are left alone.
+func migrateVariantStock(tx *gorm.DB) error {
+	transactions := repository.NewInventoryTransactionRepository(tx)
+	for _, variants := range []struct {
+		table       string
+		variantType string
+	}{
+		{"size_variants", models.VariantTypeSize},
+		{"color_variants", models.VariantTypeColor},
+	} {
+		var rows []struct {
+			ID        int
+			ProductID string
+			Quantity  models.Quantity
+		}
+		err := tx.Table(variants.table).Select("id", "product_id", "quantity").Where("quantity <> 0").Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			var count int64
+			err := tx.Model(&models.InventoryTransaction{}).
+				Where("product_id = ? AND variant_type = ? AND variant_id = ?", row.ProductID, variants.variantType, row.ID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			err = transactions.Create(&models.InventoryTransaction{
+				ProductID:       row.ProductID,
+				VariantType:     variants.variantType,
+				VariantID:       row.ID,
+				Quantity:        row.Quantity,
+				TransactionType: models.TransactionTypeInventoryAdjustment,
+				Description:     "Opening stock of the variant",
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/tanush-128/openzo_backend/product/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMigrateMoneyAndQuantity migrates rows written before prices carried a
+// currency and quantities a scale, and checks that a second start leaves
+// them alone.
+func TestMigrateMoneyAndQuantity(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:migrations?mode=memory"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the schema as AutoMigrate leaves it on the first start after 030, with
+	// the whole rupee columns of before still holding the prices
+	if err := db.AutoMigrate(&models.Product{}, &models.SizeVariant{}, &models.InventoryTransaction{}, &models.PriceRule{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`ALTER TABLE products ADD COLUMN "mrp" integer`,
+		`ALTER TABLE products ADD COLUMN "discount_price" integer`,
+		`ALTER TABLE size_variants ADD COLUMN "price" integer`,
+		`ALTER TABLE inventory_transactions ADD COLUMN "price" integer`,
+		`INSERT INTO products (id, store_id, name, category, mrp, discount_price, quantity, critical_quantity, quantity_unit, created_at)
+			VALUES ('p1', 's1', 'Rice', '', 60, 55, 4, 2, 'KGS', '2024-01-01 00:00:00')`,
+		`INSERT INTO products (id, store_id, name, category, mrp, discount_price, quantity, critical_quantity, quantity_unit, created_at)
+			VALUES ('p2', 's1', 'Eggs', '', 7, 0, 30, 0, 'box', '2024-01-01 00:00:00')`,
+		`INSERT INTO size_variants (product_id, size, price, quantity) VALUES ('p1', '5kg', 280, 3)`,
+		`INSERT INTO inventory_transactions (id, product_id, quantity, price, transaction_type, created_at) VALUES ('t1', 'p1', 4, 50, 'PURCHASE', '2024-01-01 00:00:00')`,
+		`INSERT INTO price_rules (id, store_id, scope, discount_type, value) VALUES ('r1', 's1', 'STORE', 'FIXED', 5)`,
+		`INSERT INTO price_rules (id, store_id, scope, discount_type, value) VALUES ('r2', 's1', 'STORE', 'PERCENTAGE', 10)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for start := 0; start < 2; start++ {
+		if err := migrate(db); err != nil {
+			t.Fatalf("start %d: %v", start, err)
+		}
+
+		var rice, eggs models.Product
+		db.First(&rice, "id = ?", "p1")
+		db.First(&eggs, "id = ?", "p2")
+		if rice.MRP != (models.Money{Amount: 6000, Currency: "INR"}) || rice.DiscountPrice != (models.Money{Amount: 5500, Currency: "INR"}) ||
+			rice.CriticalQuantity != models.NewQuantity(2) || rice.QuantityUnit != models.UnitKilogram {
+			t.Fatalf("start %d: rice %+v %+v %v %s", start, rice.MRP, rice.DiscountPrice, rice.CriticalQuantity, rice.QuantityUnit)
+		}
+		if eggs.MRP.Amount != 700 || eggs.QuantityUnit != models.UnitPiece {
+			t.Fatalf("start %d: eggs %+v %s", start, eggs.MRP, eggs.QuantityUnit)
+		}
+
+		var variant models.SizeVariant
+		db.First(&variant, "product_id = ?", "p1")
+		if variant.Price.Amount != 28000 || variant.Quantity != models.NewQuantity(3) {
+			t.Fatalf("start %d: variant %+v %v", start, variant.Price, variant.Quantity)
+		}
+		var purchase models.InventoryTransaction
+		db.First(&purchase, "id = ?", "t1")
+		if purchase.Price.Amount != 5000 || purchase.Quantity != models.NewQuantity(4) {
+			t.Fatalf("start %d: transaction %+v %v", start, purchase.Price, purchase.Quantity)
+		}
+		var fixed, percentage models.PriceRule
+		db.First(&fixed, "id = ?", "r1")
+		db.First(&percentage, "id = ?", "r2")
+		if fixed.Value != 500 || percentage.Value != 10 {
+			t.Fatalf("start %d: rules %d %d", start, fixed.Value, percentage.Value)
+		}
+
+		var applied int64
+		db.Model(&models.SchemaMigration{}).Where("id = ?", "030_money_and_quantity").Count(&applied)
+		if applied != 1 {
+			t.Fatalf("start %d: 030 recorded %d times", start, applied)
+		}
+	}
+
+	if db.Migrator().HasColumn(&models.Product{}, "mrp") || db.Migrator().HasColumn(&models.SizeVariant{}, "price") {
+		t.Fatal("whole rupee columns left behind")
+	}
+}